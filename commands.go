@@ -0,0 +1,1353 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	upbitTool "github.com/jekeun/upbit-go/tool"
+	"github.com/jekeun/upbit-go/types"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"raindrop/main/backtest"
+	"raindrop/main/exchange"
+	"raindrop/main/exchange/upbitapi"
+	"raindrop/main/fee"
+	"raindrop/main/marketdata"
+	"raindrop/main/model"
+	"raindrop/main/money"
+	"raindrop/main/montecarlo"
+	"raindrop/main/optimize"
+	"raindrop/main/quote"
+	"raindrop/main/reconcile"
+	"raindrop/main/recorder"
+	"raindrop/main/report"
+	"raindrop/main/state"
+	"raindrop/main/strategy/lw_basic"
+	"raindrop/main/universe"
+	"raindrop/main/utils/identifier"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+/*
+ * 운영용 CLI 명령
+ */
+
+// CLI 에서 직접 낸 주문의 Identifier 전략명
+const cliStrategyName = "cli"
+
+// flatten 매도 수량 소수점 자리수
+const flattenVolumePlaces = 8
+
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+}
+
+func parseFloat(value string) float64 {
+	f, _ := strconv.ParseFloat(value, 64)
+	return f
+}
+
+/*
+ * 잔고 및 수익률 조회
+ */
+func balanceCommand(args []string) (err error) {
+	flags := flag.NewFlagSet("balance", flag.ExitOnError)
+	flags.Parse(args)
+
+	store, err := loadStore(config)
+	if err != nil {
+		return
+	}
+
+	ex, err := newExchange(config, store)
+	if err != nil {
+		return
+	}
+
+	balances, err := ex.Accounts()
+	if err != nil {
+		return
+	}
+
+	// 보유 코인은 타겟의 기준 통화 마켓으로, 타겟에 없으면 원화 마켓으로 평가한다.
+	targets := config.LarryStrategy.Targets
+
+	markets := make([]string, 0)
+	for _, balance := range balances {
+		if balance.Currency != quote.KRW {
+			markets = append(markets, quote.MarketOf(balance.Currency, targets))
+		}
+	}
+
+	candleMap := exchange.GetDayCandlesByCoins(ex, markets, 1)
+	rates := quote.Rates(ex, quote.Currencies(markets), candleMap)
+	fees := fee.NewProvider(ex, config.Fee)
+
+	table := newTable()
+	fmt.Fprintln(table, "코인\t마켓\t보유\t주문중\t평균매수가\t현재가\t평가금액(KRW)\t수익률(%, 수수료 포함)\t")
+
+	total := 0.0
+	for _, balance := range balances {
+		volume := parseFloat(balance.Balance) + parseFloat(balance.Locked)
+
+		if balance.Currency == quote.KRW {
+			total += volume
+			fmt.Fprintf(table, "%s\t-\t%s\t%s\t-\t-\t%.0f\t-\t\n",
+				balance.Currency, balance.Balance, balance.Locked, volume)
+			continue
+		}
+
+		market := quote.MarketOf(balance.Currency, targets)
+		currentPrice := upbitTool.GetCurrentPriceFromDayCandle(candleMap[market])
+
+		// 평균 매수가를 알려주지 않는 거래소는 봇 주문 체결로 구한다.
+		avgBuyPrice := balance.AvgBuyPrice
+		if len(avgBuyPrice) == 0 {
+			avgBuyPrice = strconv.FormatFloat(store.AvgPrice(market), 'f', -1, 64)
+		}
+
+		value, converted := quote.ToKRW(volume*currentPrice, quote.Of(market), rates)
+		valueStr := "-"
+		if converted {
+			total += value
+			valueStr = fmt.Sprintf("%.0f", value)
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%.8g\t%s\t%.2f\t\n",
+			balance.Currency,
+			market,
+			balance.Balance,
+			balance.Locked,
+			avgBuyPrice,
+			currentPrice,
+			valueStr,
+			fees.For(market).ProfitRate(parseFloat(avgBuyPrice), currentPrice))
+	}
+
+	fmt.Fprintf(table, "합계\t\t\t\t\t\t%.0f\t\t\n", total)
+
+	return table.Flush()
+}
+
+/*
+ * 미체결 주문 조회
+ */
+func ordersCommand(args []string) (err error) {
+	flags := flag.NewFlagSet("orders", flag.ExitOnError)
+	flags.Parse(args)
+
+	store, err := loadStore(config)
+	if err != nil {
+		return
+	}
+
+	ex, err := newExchange(config, store)
+	if err != nil {
+		return
+	}
+
+	ordersMap, err := ex.OrdersMap("", types.ORDERSTATE_WAIT, 1, types.ORDERBY_DESC)
+	if err != nil {
+		return
+	}
+
+	table := newTable()
+	fmt.Fprintln(table, "구분\t코인\t가격\t수량\t주문시각\t주문자\tuuid\t")
+
+	for _, side := range []string{types.ORDERSIDE_BID, types.ORDERSIDE_ASK} {
+		for _, order := range ordersMap[side] {
+			owner := "수동"
+			if strategy, exist := store.StrategyOf(order.Uuid); exist {
+				owner = strategy
+			}
+
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+				order.Side, order.Market, order.Price, order.Volume, order.CreatedAt, owner, order.Uuid)
+		}
+	}
+
+	return table.Flush()
+}
+
+/*
+ * 미체결 주문 취소
+ * 기본으로 봇이 낸 주문만 취소하며, -manual 옵션을 주면 수동 주문도 취소한다.
+ */
+func cancelAllCommand(args []string) (err error) {
+	flags := flag.NewFlagSet("cancel-all", flag.ExitOnError)
+	includeManual := flags.Bool("manual", false, "수동 주문도 취소")
+	flags.Parse(args)
+
+	store, err := loadStore(config)
+	if err != nil {
+		return
+	}
+
+	ex, err := newExchange(config, store)
+	if err != nil {
+		return
+	}
+
+	ordersMap, err := ex.OrdersMap("", types.ORDERSTATE_WAIT, 1, types.ORDERBY_DESC)
+	if err != nil {
+		return
+	}
+
+	for _, orders := range ordersMap {
+		for _, order := range orders {
+			if !*includeManual && !store.IsOwnOrder(order.Uuid) {
+				continue
+			}
+
+			if _, cancelErr := ex.CancelOrder(order.Uuid); cancelErr != nil {
+				fmt.Printf("주문 취소 실패 : %s, %s\n", order.Uuid, cancelErr.Error())
+				err = cancelErr
+			} else {
+				fmt.Printf("주문 취소 : %s %s %s\n", order.Market, order.Side, order.Uuid)
+			}
+		}
+	}
+
+	return
+}
+
+/*
+ * 타겟 코인 보유분을 시장가로 전량 매도한다.
+ * -all : 타겟이 아닌 코인도 매도, -yes 가 없으면 매도 대상만 출력한다.
+ */
+func flattenCommand(args []string) (err error) {
+	flags := flag.NewFlagSet("flatten", flag.ExitOnError)
+	all := flags.Bool("all", false, "타겟이 아닌 코인도 매도")
+	confirm := flags.Bool("yes", false, "실제 매도 실행")
+	flags.Parse(args)
+
+	store, err := loadStore(config)
+	if err != nil {
+		return
+	}
+
+	ex, err := newExchange(config, store)
+	if err != nil {
+		return
+	}
+
+	// 매도는 그 코인을 보유한 전략의 주문으로 기록한다. 전략 보유 수량을 넘는 수량은 cli 주문으로 기록한다.
+	idGenerators := make(map[string]*identifier.Generator)
+	nextID := func(strategy string, market string) string {
+		if _, exist := idGenerators[strategy]; !exist {
+			idGenerators[strategy] = identifier.NewGenerator(strategy)
+		}
+		return idGenerators[strategy].Next(market)
+	}
+
+	isTarget := func(market string) bool {
+		return *all || upbitTool.IsExist(market, config.LarryStrategy.Targets)
+	}
+
+	if *confirm {
+		// 매도 수량이 묶여있지 않도록 해당 코인의 봇 주문을 먼저 취소한다.
+		ordersMap, ordersErr := ex.OrdersMap("", types.ORDERSTATE_WAIT, 1, types.ORDERBY_DESC)
+		if ordersErr != nil {
+			return ordersErr
+		}
+
+		for _, orders := range ordersMap {
+			for _, order := range store.FilterOwnOrders(orders) {
+				if isTarget(order.Market) {
+					ex.CancelOrder(order.Uuid)
+				}
+			}
+		}
+
+		time.Sleep(time.Second)
+	}
+
+	balances, err := ex.Accounts()
+	if err != nil {
+		return
+	}
+
+	for _, balance := range balances {
+		if balance.Currency == quote.KRW || parseFloat(balance.Balance) <= 0 {
+			continue
+		}
+
+		market := quote.MarketOf(balance.Currency, config.LarryStrategy.Targets)
+		if !isTarget(market) {
+			continue
+		}
+
+		volume, parseErr := money.NewFromString(balance.Balance)
+		if parseErr != nil {
+			fmt.Printf("수량 확인 실패 : %s, %s\n", market, parseErr.Error())
+			err = parseErr
+			continue
+		}
+
+		for _, share := range store.SplitVolume(market, config.StrategyNames(), volume, flattenVolumePlaces, cliStrategyName) {
+			if !*confirm {
+				fmt.Printf("매도 대상 : %s, 수량 : %s, 전략 : %s\n", market, share.Volume.String(), share.Strategy)
+				continue
+			}
+
+			fmt.Printf("시장가 매도 : %s, 수량 : %s, 전략 : %s\n", market, share.Volume.String(), share.Strategy)
+
+			id := nextID(share.Strategy, market)
+			order, askErr := ex.AskMarketOrder(id, market, share.Volume.String())
+			if askErr != nil {
+				fmt.Printf("매도 실패 : %s, %s\n", market, askErr.Error())
+				err = askErr
+				continue
+			}
+			store.RecordOrder(id, order)
+		}
+	}
+
+	if !*confirm {
+		fmt.Println("실제 매도하려면 -yes 옵션을 추가하세요.")
+	}
+
+	return
+}
+
+/*
+ * 타겟 코인 매수 신호를 한 번 계산해 출력한다.
+ */
+func signalsCommand(args []string) (err error) {
+	flags := flag.NewFlagSet("signals", flag.ExitOnError)
+	flags.Parse(args)
+
+	store, err := loadStore(config)
+	if err != nil {
+		return
+	}
+
+	ex, err := newExchange(config, store)
+	if err != nil {
+		return
+	}
+
+	runner := new(lw_basic.LarryRunner)
+	runner.Init(config, log.New(ioutil.Discard, "", 0), store, ex)
+
+	table := newTable()
+	fmt.Fprintln(table, "코인\t시가\tRange\tk\t매수조건가\t현재가\t이평스코어\t주문금액\t신호\t")
+
+	for _, signal := range runner.Signals() {
+		triggered := "-"
+		if signal.Triggered {
+			triggered = "매수"
+		}
+
+		fmt.Fprintf(table, "%s\t%.4f\t%.4f\t%.3f\t%.4f\t%.4f\t%.2f\t%.0f\t%s\t\n",
+			signal.Market,
+			signal.OpeningPrice,
+			signal.Range,
+			signal.KValue,
+			signal.TriggerPrice,
+			signal.CurrentPrice,
+			signal.MalScore,
+			signal.OrderAmount,
+			triggered)
+	}
+
+	if err = table.Flush(); err != nil {
+		return
+	}
+
+	regime := runner.Regime()
+	state := "정상"
+	if regime.Bearish {
+		state = fmt.Sprintf("약세 (주문 금액 %.0f%%) : %s", regime.AmountRate*100, strings.Join(regime.Reasons, ", "))
+	}
+	if config.LarryStrategy.Regime.Enable != 1 {
+		state += " - 필터 사용 안함"
+	}
+
+	fmt.Printf("\n시장 국면 %s : 현재가 %.4f, 이평 %.4f, 이평 스코어 %.2f, %s\n",
+		regime.Market, regime.CurrentPrice, regime.MovingAverage, regime.MalScore, state)
+
+	return
+}
+
+/*
+ * 설정된 타겟 코인으로 일봉 백테스트를 수행한다.
+ */
+func backtestCommand(args []string) (err error) {
+	params := backtest.DefaultParams(config.LarryStrategy)
+
+	// 수수료, 슬리피지 기본값은 설정을 따른다.
+	feeModel := fee.FromConfig(config.Fee)
+	params.FeeRate = feeModel.BidRate
+	params.Slippage = feeModel.BidSlippage
+
+	flags := flag.NewFlagSet("backtest", flag.ExitOnError)
+	days := flags.Int("days", upbitapi.MaxCandleCount, "조회할 일봉 개수")
+	flags.Float64Var(&params.InitialCash, "cash", params.InitialCash, "초기 자금 (KRW)")
+	flags.Float64Var(&params.FeeRate, "fee", params.FeeRate, "거래 수수료율")
+	flags.Float64Var(&params.Slippage, "slippage", params.Slippage, "체결 슬리피지 비율 (매수가 상승, 매도가 하락)")
+	flags.IntVar(&params.NoiseLookback, "noise", params.NoiseLookback, "노이즈 k 계산 기간 (0 : k_value 고정)")
+	output := flags.String("out", "", "결과를 저장할 JSON 파일")
+	showTrades := flags.Bool("trades", false, "거래 목록 출력")
+	dataDir := flags.String("data", "", "record 명령으로 저장한 데이터 디렉토리 (지정하면 API 대신 사용)")
+	fillUnit := flags.Int("fill-unit", 0, "분봉 체결 시뮬레이션 단위 (1, 5), -data 의 분봉 사용 (0 : 일봉 가정)")
+	flags.IntVar(&params.PollSecond, "poll", backtest.DefaultPollSecond, "분봉 체결 시뮬레이션의 현재가 조회 주기 (초)")
+	flags.Parse(args)
+
+	if *fillUnit != 0 && *fillUnit != 1 && *fillUnit != 5 {
+		return fmt.Errorf("-fill-unit 은 1 또는 5 : %d", *fillUnit)
+	}
+	if *fillUnit != 0 && len(*dataDir) == 0 {
+		return fmt.Errorf("-fill-unit 은 -data 의 분봉이 필요함")
+	}
+
+	series := make(map[string][]*marketdata.Candle)
+	minutes := make(map[string][]*marketdata.Candle)
+	if len(*dataDir) > 0 {
+		now := time.Now().UTC()
+		storage := marketdata.NewStorage(*dataDir)
+		replay, replayErr := marketdata.NewReplay(storage,
+			marketdata.KindDay, params.Strategy.Targets, now.AddDate(0, 0, -*days), now)
+		if replayErr != nil {
+			return replayErr
+		}
+		series = replay.Series()
+
+		if *fillUnit > 0 {
+			minuteReplay, minuteErr := marketdata.NewReplay(storage,
+				marketdata.MinuteKind(*fillUnit), params.Strategy.Targets, now.AddDate(0, 0, -*days), now)
+			if minuteErr != nil {
+				return minuteErr
+			}
+			minutes = minuteReplay.Series()
+		}
+	} else {
+		api := upbitapi.NewClient("", "")
+
+		for _, market := range params.Strategy.Targets {
+			candles, fetchErr := marketdata.FetchDayCandles(api, market, *days)
+			if fetchErr != nil {
+				fmt.Printf("일봉 조회 실패 : %s, %s\n", market, fetchErr.Error())
+				continue
+			}
+			series[market] = candles
+		}
+	}
+
+	var result *backtest.Result
+	if *fillUnit > 0 {
+		result = backtest.RunIntraday(params, series, minutes)
+	} else {
+		result = backtest.Run(params, series)
+	}
+
+	printBacktestSummary(result.Summary)
+
+	if *fillUnit > 0 && result.DailyFallback > 0 {
+		fmt.Printf("분봉이 없어 일봉 가정으로 체결한 거래 : %d 건\n", result.DailyFallback)
+	}
+
+	if *showTrades {
+		fmt.Println()
+		report.PrintTrades(os.Stdout, result.Trades)
+	}
+
+	if len(*output) > 0 {
+		data, marshalErr := json.MarshalIndent(result, "", "\t")
+		if marshalErr != nil {
+			return marshalErr
+		}
+		err = ioutil.WriteFile(*output, data, 0644)
+	}
+
+	return
+}
+
+func printBacktestSummary(summary backtest.Summary) {
+	table := newTable()
+	fmt.Fprintf(table, "초기 자금\t%.0f\t\n", summary.InitialEquity)
+	fmt.Fprintf(table, "최종 자금\t%.0f\t\n", summary.FinalEquity)
+	fmt.Fprintf(table, "총 수익률(%%)\t%.2f\t\n", summary.TotalReturn)
+	fmt.Fprintf(table, "CAGR(%%)\t%.2f\t\n", summary.CAGR)
+	fmt.Fprintf(table, "MDD(%%)\t%.2f\t\n", summary.MDD)
+	fmt.Fprintf(table, "Sharpe\t%.2f\t\n", summary.Sharpe)
+	fmt.Fprintf(table, "거래수\t%d\t\n", summary.TradeCount)
+	fmt.Fprintf(table, "승률(%%)\t%.1f\t\n", summary.WinRate)
+	fmt.Fprintf(table, "Profit Factor\t%.2f\t\n", summary.ProfitFactor)
+	table.Flush()
+}
+
+/*
+ * optimize, walk-forward 명령이 같이 쓰는 탐색 옵션
+ */
+type optimizeArgs struct {
+	base    backtest.Params
+	space   optimize.Space
+	options optimize.Options
+
+	dataDir string
+	fromStr string
+	toStr   string
+	ranges  map[string]*string
+}
+
+func newOptimizeArgs(flags *flag.FlagSet) (optArgs *optimizeArgs) {
+	optArgs = &optimizeArgs{
+		base:   backtest.DefaultParams(config.LarryStrategy),
+		ranges: make(map[string]*string)}
+
+	feeModel := fee.FromConfig(config.Fee)
+	optArgs.base.FeeRate = feeModel.BidRate
+	optArgs.base.Slippage = feeModel.BidSlippage
+
+	flags.StringVar(&optArgs.dataDir, "data", "", "record, fetch-history 로 저장한 데이터 디렉토리 (없으면 API 일봉 200개)")
+	flags.StringVar(&optArgs.fromStr, "from", "", "거래 시작일 (yyyy-mm-dd)")
+	flags.StringVar(&optArgs.toStr, "to", "", "거래 종료일 (yyyy-mm-dd)")
+	optArgs.ranges["k"] = flags.String("k", "", "k_value 범위 (예 : 0.3:0.7:0.1, -noise 에 0 이 있어야 함)")
+	optArgs.ranges["noise"] = flags.String("noise", "", "노이즈 k 계산 기간 (예 : 0,10,20, 0 : k_value 사용)")
+	optArgs.ranges["money-plan"] = flags.String("money-plan", "", "money_plan 범위")
+	optArgs.ranges["min-rate"] = flags.String("min-rate", "", "min_order_amount_rate 범위")
+	optArgs.ranges["max-coin"] = flags.String("max-coin", "", "max_coin 범위")
+	optArgs.ranges["start-time"] = flags.String("start-time", "", "start_time 범위 (0 외의 값은 -data 의 60분봉 필요)")
+	flags.StringVar(&optArgs.options.Mode, "mode", optimize.ModeGrid, "탐색 방법 (grid, random)")
+	flags.IntVar(&optArgs.options.Samples, "samples", 100, "random 탐색 조합 수")
+	flags.IntVar(&optArgs.options.Workers, "workers", runtime.NumCPU(), "동시 백테스트 수")
+	flags.StringVar(&optArgs.options.Objective, "objective", optimize.ObjectiveCAGRMDD, "순위 기준 (cagr_mdd, sharpe, return)")
+	flags.IntVar(&optArgs.options.MinTrades, "min-trades", 10, "최소 거래 수")
+	flags.Int64Var(&optArgs.options.Seed, "seed", 1, "random 탐색 시드")
+	flags.Float64Var(&optArgs.base.InitialCash, "cash", optArgs.base.InitialCash, "초기 자금 (KRW)")
+	flags.Float64Var(&optArgs.base.FeeRate, "fee", optArgs.base.FeeRate, "거래 수수료율")
+	flags.Float64Var(&optArgs.base.Slippage, "slippage", optArgs.base.Slippage, "체결 슬리피지 비율")
+
+	return
+}
+
+/*
+ * 플래그 값을 검사하고 기간, 탐색 범위를 채운다. (flags.Parse 이후 호출)
+ */
+func (optArgs *optimizeArgs) parse() (err error) {
+	if !optimize.ValidObjective(optArgs.options.Objective) {
+		return fmt.Errorf("지원하지 않는 objective : %s", optArgs.options.Objective)
+	}
+
+	if optArgs.base.From, err = parseDate(optArgs.fromStr); err != nil {
+		return
+	}
+	if optArgs.base.To, err = parseDate(optArgs.toStr); err != nil {
+		return
+	}
+
+	targets := map[string]*[]float64{
+		"k":          &optArgs.space.KValue,
+		"noise":      &optArgs.space.NoiseLookback,
+		"money-plan": &optArgs.space.MoneyPlan,
+		"min-rate":   &optArgs.space.MinOrderAmountRate,
+		"max-coin":   &optArgs.space.MaxCoin,
+		"start-time": &optArgs.space.StartTime,
+	}
+	for name, values := range targets {
+		if *values, err = optimize.ParseRange(*optArgs.ranges[name]); err != nil {
+			return fmt.Errorf("-%s : %s", name, err.Error())
+		}
+	}
+
+	return
+}
+
+func (optArgs *optimizeArgs) seriesFunc() optimize.SeriesFunc {
+	return backtestSeriesFunc(optArgs.dataDir, optArgs.base.Strategy.Targets, optArgs.base.From, optArgs.base.To)
+}
+
+/*
+ * 백테스트 파라미터를 격자/무작위 탐색하고 목적 함수 상위 결과와 최적 설정을 출력한다.
+ */
+func optimizeCommand(args []string) (err error) {
+	flags := flag.NewFlagSet("optimize", flag.ExitOnError)
+	optArgs := newOptimizeArgs(flags)
+	top := flags.Int("top", 10, "출력할 상위 결과 수")
+	flags.Parse(args)
+
+	if err = optArgs.parse(); err != nil {
+		return
+	}
+
+	options := optArgs.options
+
+	results, err := optimize.Run(optArgs.base, optArgs.space, options, optArgs.seriesFunc())
+	if err != nil {
+		return
+	}
+
+	if len(results) == 0 {
+		fmt.Println("조건을 만족하는 결과 없음")
+		return
+	}
+
+	table := newTable()
+	fmt.Fprintln(table, "순위\t파라미터\tCAGR(%)\tMDD(%)\tSharpe\t거래수\t점수\t")
+	for index, result := range results {
+		if index >= *top {
+			break
+		}
+
+		fmt.Fprintf(table, "%d\t%s\t%.2f\t%.2f\t%.2f\t%d\t%.3f\t\n",
+			index+1,
+			optimize.Describe(result.Params),
+			result.Summary.CAGR,
+			result.Summary.MDD,
+			result.Summary.Sharpe,
+			result.Summary.TradeCount,
+			result.Score)
+	}
+	table.Flush()
+
+	best := results[0]
+	block, err := json.MarshalIndent(best.Params.Strategy, "", "  ")
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("\n%d 개 조합 중 최적 (%s)\n\n", len(results), options.Objective)
+	fmt.Printf("\"larry_strategy\" : %s\n", block)
+
+	if best.Params.NoiseLookback != backtest.DefaultNoiseLookback {
+		fmt.Printf("\n* 실매매는 최근 %d일 노이즈 k 를 사용하므로 noise=%d 결과는 그대로 적용되지 않음\n",
+			backtest.DefaultNoiseLookback, best.Params.NoiseLookback)
+	}
+
+	return
+}
+
+/*
+ * 학습 구간 탐색, 다음 검증 구간 적용을 반복해 검증 구간 성과와 파라미터 안정성을 출력한다.
+ */
+func walkForwardCommand(args []string) (err error) {
+	walkOptions := optimize.WalkForwardOptions{}
+
+	flags := flag.NewFlagSet("walk-forward", flag.ExitOnError)
+	optArgs := newOptimizeArgs(flags)
+	flags.IntVar(&walkOptions.TrainDays, "train", 180, "학습 구간 일수")
+	flags.IntVar(&walkOptions.TestDays, "test", 30, "검증 구간 일수")
+	flags.IntVar(&walkOptions.StepDays, "step", 0, "구간 이동 일수 (0 : 검증 구간 일수, 검증 구간 일수 이상)")
+	output := flags.String("out", "", "검증 구간 자금 곡선, 거래를 저장할 JSON 파일")
+	flags.Parse(args)
+
+	if err = optArgs.parse(); err != nil {
+		return
+	}
+
+	walkForward, err := optimize.RunWalkForward(optArgs.base, optArgs.space, optArgs.options, walkOptions, optArgs.seriesFunc())
+	if err != nil {
+		return
+	}
+
+	const dateLayout = "2006-01-02"
+
+	table := newTable()
+	fmt.Fprintln(table, "학습 구간\t검증 구간\t파라미터\t학습 점수\t검증 점수\t검증 수익률(%)\t검증 MDD(%)\t거래수\t")
+	for _, window := range walkForward.Windows {
+		trainRange := window.TrainFrom.Format(dateLayout) + " ~ " + window.TrainTo.Format(dateLayout)
+		testRange := window.TestFrom.Format(dateLayout) + " ~ " + window.TestTo.Format(dateLayout)
+
+		if window.Best == nil {
+			fmt.Fprintf(table, "%s\t%s\t조건을 만족하는 조합 없음\t\t\t\t\t\t\n", trainRange, testRange)
+			continue
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%.3f\t%.3f\t%.2f\t%.2f\t%d\t\n",
+			trainRange,
+			testRange,
+			optimize.Describe(window.Best.Params),
+			window.Best.Score,
+			window.TestScore,
+			window.Test.TotalReturn,
+			window.Test.MDD,
+			window.Test.TradeCount)
+	}
+	table.Flush()
+
+	fmt.Println()
+	table = newTable()
+	fmt.Fprintln(table, "파라미터\t평균\t표준편차\t최소\t최대\t변경 횟수\t구간별 값\t")
+	for _, stat := range walkForward.Stability {
+		fmt.Fprintf(table, "%s\t%.3f\t%.3f\t%g\t%g\t%d\t%v\t\n",
+			stat.Name, stat.Mean, stat.StdDev, stat.Min, stat.Max, stat.Changes, stat.Values)
+	}
+	table.Flush()
+
+	fmt.Println("\n[검증 구간 연결 결과]")
+	printBacktestSummary(walkForward.Summary)
+	fmt.Printf("Walk-forward 효율 (검증 점수 / 학습 점수) : %.2f\n", walkForward.Efficiency)
+
+	if len(*output) > 0 {
+		data, marshalErr := json.MarshalIndent(map[string]interface{}{
+			"summary": walkForward.Summary,
+			"trades":  walkForward.Trades,
+			"equity":  walkForward.Equity}, "", "\t")
+		if marshalErr != nil {
+			return marshalErr
+		}
+		err = ioutil.WriteFile(*output, data, 0644)
+	}
+
+	return
+}
+
+/*
+ * 세션 시작 시각별 백테스트 일봉을 읽는 함수
+ * start_time 0 은 일봉, 그 외는 저장된 60분봉을 세션 봉으로 묶어 사용한다.
+ */
+func backtestSeriesFunc(dataDir string, markets []string, from time.Time, to time.Time) optimize.SeriesFunc {
+	return func(startTime int) (series map[string][]*marketdata.Candle, err error) {
+		series = make(map[string][]*marketdata.Candle)
+
+		if len(dataDir) == 0 {
+			if startTime != 0 {
+				return nil, fmt.Errorf("start_time %d 은 -data 의 60분봉이 필요함", startTime)
+			}
+
+			api := upbitapi.NewClient("", "")
+			for _, market := range markets {
+				candles, fetchErr := marketdata.FetchDayCandles(api, market, upbitapi.MaxCandleCount)
+				if fetchErr != nil {
+					fmt.Printf("일봉 조회 실패 : %s, %s\n", market, fetchErr.Error())
+					continue
+				}
+				series[market] = candles
+			}
+
+			return
+		}
+
+		// 신호 계산에 필요한 이전 캔들을 포함해 읽는다.
+		loadFrom := time.Time{}
+		if !from.IsZero() {
+			loadFrom = from.AddDate(0, 0, -backtestWarmupDays)
+		}
+
+		// 60분봉은 종료일 세션이 다음 날 파티션까지 이어진다.
+		loadTo := to
+		if !to.IsZero() {
+			loadTo = to.AddDate(0, 0, 1)
+		}
+
+		storage := marketdata.NewStorage(dataDir)
+
+		for _, market := range markets {
+			var candles []*marketdata.Candle
+			if startTime == 0 {
+				candles, err = storage.LoadCandles(marketdata.KindDay, market, loadFrom, to)
+			} else {
+				candles, err = storage.LoadCandles(marketdata.MinuteKind(60), market, loadFrom, loadTo)
+				candles = marketdata.Sessions(candles, startTime)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if len(candles) > 0 {
+				series[market] = candles
+			}
+		}
+
+		if len(series) == 0 {
+			return nil, fmt.Errorf("%s 에 start_time %d 백테스트 데이터가 없음", dataDir, startTime)
+		}
+
+		return
+	}
+}
+
+// 거래 시작일 이전에 읽을 캔들 기간 (노이즈 k, 이평 스코어 계산용)
+const backtestWarmupDays = 120
+
+func parseDate(text string) (date time.Time, err error) {
+	if len(text) == 0 {
+		return
+	}
+
+	return time.Parse("2006-01-02", text)
+}
+
+/*
+ * 봇이 낸 주문의 체결 내역을 동기화하고 전략별 성과를 출력한다.
+ */
+func reportCommand(args []string) (err error) {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	strategy := flags.String("strategy", "", "전략명으로 필터")
+	sinceStr := flags.String("since", "", "시작일 (yyyy-mm-dd)")
+	showTrades := flags.Bool("trades", false, "거래 목록 출력")
+	noSync := flags.Bool("no-sync", false, "거래소 체결 조회 없이 저장된 기록만 사용")
+	flags.Parse(args)
+
+	trades, krwTrades, err := loadLiveTrades(*strategy, *sinceStr, !*noSync)
+	if err != nil {
+		return
+	}
+
+	shadowTrades, err := loadShadowTrades(*strategy, *sinceStr)
+	if err != nil {
+		return
+	}
+
+	// shadow 전략은 실매매 전략 아래에 나란히 출력한다.
+	report.PrintSummary(os.Stdout, append(krwTrades, shadowTrades...))
+
+	if *showTrades {
+		fmt.Println()
+		report.PrintTrades(os.Stdout, trades)
+	}
+
+	return
+}
+
+/*
+ * 상태 파일의 봇 주문 기록으로 왕복 거래를 만든다.
+ * krwTrades 는 원화 외 마켓의 손익을 현재 시세로 원화 환산한 거래 (시세를 얻지 못한 마켓은 빠짐)
+ */
+func loadLiveTrades(strategy string, sinceStr string, sync bool) (trades []*model.Trade, krwTrades []*model.Trade, err error) {
+	var since time.Time
+	if len(sinceStr) > 0 {
+		if since, err = time.ParseInLocation("2006-01-02", sinceStr, time.Local); err != nil {
+			return
+		}
+	}
+
+	store, err := state.Load(config.StateFile)
+	if err != nil {
+		return
+	}
+
+	ex, err := newExchange(config, store)
+	if err != nil {
+		return
+	}
+
+	if sync {
+		_, syncErr := store.SyncFills(ex.OrderFill)
+
+		if syncErr != nil {
+			fmt.Printf("체결 내역 동기화 실패 : %s\n", syncErr.Error())
+		}
+	}
+
+	trades = report.FilterTrades(report.RoundTrips(store, fee.NewProvider(ex, config.Fee).For), strategy, since)
+
+	// 원화 외 마켓의 손익은 현재 시세로 원화 환산해 합산한다.
+	markets := make([]string, 0, len(trades))
+	for _, trade := range trades {
+		markets = append(markets, trade.Market)
+	}
+
+	krwTrades, missing := report.ConvertToKRW(trades, quote.Rates(ex, quote.Currencies(markets), nil))
+	if len(missing) > 0 {
+		fmt.Printf("원화 환산 시세 조회 실패로 요약에서 제외 : %v\n", missing)
+	}
+
+	return
+}
+
+/*
+ * shadow 전략의 가상 지갑 기록으로 왕복 거래를 만든다.
+ * 전략명은 "shadow:<이름>/<전략>" 으로 바꿔 실매매 전략과 구분하고, 손익은 원화로 환산한다.
+ * strategy 는 원래 전략명, shadow 이름, 바꾼 전략명 중 하나와 같으면 포함한다.
+ */
+func loadShadowTrades(strategy string, sinceStr string) (krwTrades []*model.Trade, err error) {
+	krwTrades = make([]*model.Trade, 0)
+	if len(config.Shadows) == 0 {
+		return
+	}
+
+	var since time.Time
+	if len(sinceStr) > 0 {
+		if since, err = time.ParseInLocation("2006-01-02", sinceStr, time.Local); err != nil {
+			return
+		}
+	}
+
+	ex, err := newExchange(config, nil)
+	if err != nil {
+		return
+	}
+
+	trades := make([]*model.Trade, 0)
+	for _, shadow := range config.Shadows {
+		shadowConfig, configErr := config.ShadowConfig(shadow)
+		if configErr != nil {
+			return nil, configErr
+		}
+
+		store, loadErr := state.Load(shadowConfig.StateFile)
+		if loadErr != nil {
+			return nil, loadErr
+		}
+
+		paper, paperErr := exchange.NewPaper(ex, shadow.WalletFile, shadow.InitialCash, shadowConfig.Fee)
+		if paperErr != nil {
+			return nil, paperErr
+		}
+
+		if _, syncErr := store.SyncFills(paper.OrderFill); syncErr != nil {
+			fmt.Printf("shadow %s 체결 내역 동기화 실패 : %s\n", shadow.Name, syncErr.Error())
+		}
+
+		for _, trade := range report.FilterTrades(report.RoundTrips(store, fee.NewProvider(paper, shadowConfig.Fee).For), "", since) {
+			renamed := "shadow:" + shadow.Name + "/" + trade.Strategy
+			if len(strategy) > 0 && strategy != trade.Strategy && strategy != shadow.Name && strategy != renamed {
+				continue
+			}
+
+			trade.Strategy = renamed
+			trades = append(trades, trade)
+		}
+	}
+
+	markets := make([]string, 0, len(trades))
+	for _, trade := range trades {
+		markets = append(markets, trade.Market)
+	}
+
+	krwTrades, missing := report.ConvertToKRW(trades, quote.Rates(ex, quote.Currencies(markets), nil))
+	if len(missing) > 0 {
+		fmt.Printf("원화 환산 시세 조회 실패로 shadow 요약에서 제외 : %v\n", missing)
+	}
+
+	return
+}
+
+/*
+ * 백테스트 결과(-in) 또는 실거래 기록의 거래로 몬테카를로 분석을 수행한다.
+ */
+func montecarloCommand(args []string) (err error) {
+	options := montecarlo.Options{}
+
+	flags := flag.NewFlagSet("montecarlo", flag.ExitOnError)
+	input := flags.String("in", "", "backtest, walk-forward -out 결과 파일 (없으면 실거래 기록)")
+	strategy := flags.String("strategy", "", "실거래 기록 전략명으로 필터")
+	sinceStr := flags.String("since", "", "실거래 기록 시작일 (yyyy-mm-dd)")
+	noSync := flags.Bool("no-sync", false, "거래소 체결 조회 없이 저장된 기록만 사용")
+	methodStr := flags.String("method", strings.Join(montecarlo.Methods, ","), "방법 (shuffle, bootstrap, skip, 쉼표 구분)")
+	levelsStr := flags.String("levels", "50,90,95,99", "신뢰수준 (%)")
+	flags.IntVar(&options.Runs, "runs", montecarlo.DefaultRuns, "시뮬레이션 횟수")
+	flags.Float64Var(&options.InitialCash, "cash", 0, "초기 자금 (기본 : 결과 파일의 초기 자금 또는 백테스트 기본값)")
+	flags.Float64Var(&options.OrderAmount, "order-amount", 0, "거래당 금액으로 손익을 다시 계산 (0 : 기록된 손익)")
+	flags.Float64Var(&options.SkipRate, "skip", montecarlo.DefaultSkipRate, "skip 방법의 거래 누락 확률 (0 ~ 1)")
+	flags.Int64Var(&options.Seed, "seed", 1, "난수 시드")
+	flags.Parse(args)
+
+	levels, err := optimize.ParseRange(*levelsStr)
+	if err != nil {
+		return
+	}
+
+	var trades []*model.Trade
+	if len(*input) > 0 {
+		data, readErr := ioutil.ReadFile(*input)
+		if readErr != nil {
+			return readErr
+		}
+
+		result := struct {
+			Params struct {
+				InitialCash float64 `json:"initial_cash"`
+			} `json:"params"`
+			Summary backtest.Summary `json:"summary"`
+			Trades  []*model.Trade   `json:"trades"`
+		}{}
+		if err = json.Unmarshal(data, &result); err != nil {
+			return
+		}
+
+		trades = result.Trades
+		if options.InitialCash <= 0 {
+			options.InitialCash = math.Max(result.Params.InitialCash, result.Summary.InitialEquity)
+		}
+	} else {
+		if _, trades, err = loadLiveTrades(*strategy, *sinceStr, !*noSync); err != nil {
+			return
+		}
+	}
+
+	if options.InitialCash <= 0 {
+		options.InitialCash = backtest.DefaultInitialCash
+	}
+
+	fmt.Printf("거래 %d 건, 초기 자금 %.0f, 시뮬레이션 %d 회\n", len(trades), options.InitialCash, options.Runs)
+
+	for index, method := range strings.Split(*methodStr, ",") {
+		options.Method = strings.TrimSpace(method)
+
+		mcReport, simulateErr := montecarlo.Simulate(trades, options)
+		if simulateErr != nil {
+			return simulateErr
+		}
+
+		if index == 0 {
+			fmt.Printf("실제 순서 : 최종 자금 %.0f, MDD %.2f%% (%.0f), 최장 연속 손실 %d\n",
+				mcReport.Actual.FinalEquity, mcReport.Actual.MaxDrawdown,
+				mcReport.Actual.MaxDrawdownAmount, mcReport.Actual.LongestLosingStreak)
+		}
+
+		fmt.Printf("\n[%s] 파산 비율 %.2f%%\n", mcReport.Method, mcReport.RuinRate)
+
+		table := newTable()
+		fmt.Fprint(table, "신뢰수준(%)\t")
+		for _, level := range levels {
+			fmt.Fprintf(table, "%g\t", level)
+		}
+		fmt.Fprintln(table)
+
+		rows := []struct {
+			name   string
+			format string
+			value  func(level float64) float64
+		}{
+			{"최종 자금 (이상)", "%.0f\t", mcReport.WorstFinalEquity},
+			{"MDD % (이하)", "%.2f\t", mcReport.WorstMaxDrawdown},
+			{"MDD 금액 (이하)", "%.0f\t", mcReport.WorstMaxDrawdownAmount},
+			{"최장 연속 손실 (이하)", "%.0f\t", mcReport.WorstLosingStreak},
+		}
+		for _, row := range rows {
+			fmt.Fprintf(table, "%s\t", row.name)
+			for _, level := range levels {
+				fmt.Fprintf(table, row.format, row.value(level))
+			}
+			fmt.Fprintln(table)
+		}
+		table.Flush()
+	}
+
+	return
+}
+
+/*
+ * 백테스트 결과(-in) 또는 실거래 기록으로 한 파일짜리 HTML 리포트를 만든다.
+ * 타겟 코인과 KRW-BTC 를 같은 기간 단순 보유한 결과와 비교한다.
+ */
+func htmlReportCommand(args []string) (err error) {
+	flags := flag.NewFlagSet("html-report", flag.ExitOnError)
+	input := flags.String("in", "", "backtest, walk-forward -out 결과 파일 (없으면 실거래 기록)")
+	output := flags.String("out", "report.html", "저장할 HTML 파일")
+	strategy := flags.String("strategy", "", "실거래 기록 전략명으로 필터")
+	sinceStr := flags.String("since", "", "실거래 기록 시작일 (yyyy-mm-dd)")
+	noSync := flags.Bool("no-sync", false, "거래소 체결 조회 없이 저장된 기록만 사용")
+	initialCash := flags.Float64("cash", 0, "초기 자금 (기본 : 결과 파일의 초기 자금 또는 백테스트 기본값)")
+	dataDir := flags.String("data", "", "비교용 일봉을 읽을 데이터 디렉토리 (없으면 API 최근 200일)")
+	flags.Parse(args)
+
+	htmlInput := report.HTMLInput{Benchmarks: make(map[string][]*marketdata.Candle)}
+
+	if len(*input) > 0 {
+		data, readErr := ioutil.ReadFile(*input)
+		if readErr != nil {
+			return readErr
+		}
+
+		result := struct {
+			Params struct {
+				InitialCash float64 `json:"initial_cash"`
+			} `json:"params"`
+			Summary backtest.Summary     `json:"summary"`
+			Trades  []*model.Trade       `json:"trades"`
+			Equity  []*model.EquityPoint `json:"equity"`
+		}{}
+		if err = json.Unmarshal(data, &result); err != nil {
+			return
+		}
+
+		htmlInput.Title = "백테스트 리포트 : " + filepath.Base(*input)
+		htmlInput.Trades = result.Trades
+		htmlInput.Equity = result.Equity
+		htmlInput.InitialCash = math.Max(result.Params.InitialCash, result.Summary.InitialEquity)
+	} else {
+		if _, htmlInput.Trades, err = loadLiveTrades(*strategy, *sinceStr, !*noSync); err != nil {
+			return
+		}
+
+		htmlInput.Title = "실거래 리포트"
+		if len(*strategy) > 0 {
+			htmlInput.Title += " : " + *strategy
+		}
+	}
+
+	if *initialCash > 0 {
+		htmlInput.InitialCash = *initialCash
+	}
+	if htmlInput.InitialCash <= 0 {
+		htmlInput.InitialCash = backtest.DefaultInitialCash
+	}
+
+	// 비교 대상 : 타겟 코인 + KRW-BTC
+	benchmarks := append([]string{}, config.LarryStrategy.Targets...)
+	hasBTC := false
+	for _, market := range benchmarks {
+		hasBTC = hasBTC || market == "KRW-BTC"
+	}
+	if !hasBTC {
+		benchmarks = append(benchmarks, "KRW-BTC")
+	}
+
+	var from time.Time
+	for _, trade := range htmlInput.Trades {
+		if from.IsZero() || trade.EntryTime.Before(from) {
+			from = trade.EntryTime
+		}
+	}
+	if len(htmlInput.Equity) > 0 {
+		from = htmlInput.Equity[0].Time
+	}
+
+	if len(*dataDir) > 0 {
+		storage := marketdata.NewStorage(*dataDir)
+		for _, market := range benchmarks {
+			candles, loadErr := storage.LoadCandles(marketdata.KindDay, market, from, time.Time{})
+			if loadErr != nil {
+				return loadErr
+			}
+			if len(candles) > 0 {
+				htmlInput.Benchmarks[market] = candles
+			}
+		}
+	} else {
+		api := upbitapi.NewClient("", "")
+		for _, market := range benchmarks {
+			candles, fetchErr := marketdata.FetchDayCandles(api, market, upbitapi.MaxCandleCount)
+			if fetchErr != nil {
+				fmt.Printf("일봉 조회 실패 : %s, %s\n", market, fetchErr.Error())
+				continue
+			}
+			htmlInput.Benchmarks[market] = candles
+		}
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	if err = report.WriteHTML(file, htmlInput); err != nil {
+		return
+	}
+
+	fmt.Printf("거래 %d 건 리포트 저장 : %s\n", len(htmlInput.Trades), *output)
+
+	return
+}
+
+/*
+ * 거래소 잔고와 봇 보유 수량을 비교해 출력한다.
+ * -apply 를 주면 adopt 정책인 코인의 차이를 봇 수량에 편입한다.
+ */
+func reconcileCommand(args []string) (err error) {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	apply := flags.Bool("apply", false, "adopt 정책인 코인의 차이를 봇 수량에 편입")
+	flags.Parse(args)
+
+	store, err := loadStore(config)
+	if err != nil {
+		return
+	}
+
+	ex, err := newExchange(config, store)
+	if err != nil {
+		return
+	}
+
+	reconciler := reconcile.NewReconciler(config, log.New(ioutil.Discard, "", 0), store, ex)
+
+	diffs, err := reconciler.Compare()
+	if err != nil {
+		return
+	}
+
+	if len(diffs) == 0 {
+		fmt.Println("거래소 잔고와 봇 보유 수량이 일치합니다.")
+		return
+	}
+
+	table := newTable()
+	fmt.Fprintln(table, "마켓\t거래소\t봇\t차이\t구분\t정책\t")
+
+	for _, diff := range diffs {
+		fmt.Fprintf(table, "%s\t%.8f\t%.8f\t%+.8f\t%s\t%s\t\n",
+			diff.Market, diff.Exchange, diff.Bot, diff.Diff, diff.Kind, diff.Policy)
+	}
+	table.Flush()
+
+	if !*apply {
+		return
+	}
+
+	for _, diff := range diffs {
+		if diff.Policy != reconcile.PolicyAdopt || diff.Kind == reconcile.KindPartialFill {
+			continue
+		}
+
+		if adoptErr := reconciler.Adopt(diff); adoptErr != nil {
+			fmt.Printf("편입 실패 : %s, %s\n", diff.Market, adoptErr.Error())
+			err = adoptErr
+			continue
+		}
+		fmt.Printf("편입 : %s, %+.8f\n", diff.Market, diff.Diff)
+	}
+
+	return
+}
+
+/*
+ * 유니버스 선정 결과를 출력한다. (설정의 universe 값 사용, enable 과 관계없이 계산)
+ */
+func universeCommand(args []string) (err error) {
+	universeConfig := config.LarryStrategy.Universe
+
+	flags := flag.NewFlagSet("universe", flag.ExitOnError)
+	flags.IntVar(&universeConfig.TopN, "top", universeConfig.TopN, "선정할 코인 수")
+	showAll := flags.Bool("all", false, "제외된 후보도 출력")
+	flags.Parse(args)
+
+	ex, err := newExchange(config, nil)
+	if err != nil {
+		return
+	}
+
+	selector := universe.NewSelector(ex, universeConfig, log.New(ioutil.Discard, "", 0))
+
+	targets, candidates, err := selector.Select()
+	if err != nil {
+		return
+	}
+
+	table := newTable()
+	fmt.Fprintln(table, "마켓\t거래대금(24h)\t평균변동폭(%)\t노이즈\t점수\t유의\t제외\t")
+
+	for _, candidate := range candidates {
+		if len(candidate.Excluded) > 0 && !*showAll {
+			continue
+		}
+
+		fmt.Fprintf(table, "%s\t%.0f\t%.2f\t%.3f\t%.0f\t%t\t%s\t\n",
+			candidate.Market,
+			candidate.TradeValue,
+			candidate.AverageRange*100,
+			candidate.Noise,
+			candidate.Score,
+			candidate.Warning,
+			candidate.Excluded)
+	}
+	table.Flush()
+
+	fmt.Printf("\n선정 : %v\n", targets)
+
+	return
+}
+
+/*
+ * 시세 기록 모드 : 종료할 때까지 타겟 코인의 캔들, 현재가, 호가를 저장한다.
+ */
+func recordCommand(args []string) (err error) {
+	recorderConfig := rootConfig.Recorder
+
+	flags := flag.NewFlagSet("record", flag.ExitOnError)
+	flags.StringVar(&recorderConfig.Dir, "dir", recorderConfig.Dir, "저장 디렉토리 (기본 ./data)")
+	flags.IntVar(&recorderConfig.MinuteUnit, "minute", recorderConfig.MinuteUnit, "분봉 단위 (기본 1)")
+	flags.Parse(args)
+
+	targets := recorderTargets()
+	if len(targets) == 0 {
+		return fmt.Errorf("기록할 마켓이 없음")
+	}
+
+	logger := newLogger(filepath.Join(logDir, "recorder.log"))
+	logger.SetOutput(io.MultiWriter(logger.Writer(), os.Stdout))
+
+	recorder.NewRecorder(recorderConfig, targets, logger).Run()
+
+	return
+}
+
+/*
+ * 시세를 기록할 마켓 : recorder.targets, 비어 있으면 모든 계정의 targets
+ */
+func recorderTargets() (targets []string) {
+	if len(rootConfig.Recorder.Targets) > 0 {
+		return rootConfig.Recorder.Targets
+	}
+
+	targets = make([]string, 0)
+	for _, accountConfig := range accountConfigs {
+		for _, market := range accountConfig.LarryStrategy.Targets {
+			if !upbitTool.IsExist(market, targets) {
+				targets = append(targets, market)
+			}
+		}
+	}
+
+	return
+}
+
+/*
+ * 과거 캔들을 to 파라미터로 넘겨가며 받아 데이터 디렉토리에 저장한다.
+ * 이미 받은 구간은 건너뛰고, 중단된 다운로드는 이어서 받는다.
+ */
+func fetchHistoryCommand(args []string) (err error) {
+	dir := rootConfig.Recorder.Dir
+	if len(dir) == 0 {
+		dir = "./data"
+	}
+
+	flags := flag.NewFlagSet("fetch-history", flag.ExitOnError)
+	flags.StringVar(&dir, "dir", dir, "저장 디렉토리")
+	kind := flags.String("kind", marketdata.KindDay, "캔들 종류 (day, week, minute)")
+	unit := flags.Int("unit", 1, "분봉 단위 (1, 3, 5, 15, 60, 240 등)")
+	fromStr := flags.String("from", "", "시작일 (yyyy-mm-dd, 기본 1년 전)")
+	marketsStr := flags.String("markets", "", "받을 마켓 (쉼표 구분, 기본 recorder 대상 마켓)")
+	flags.Parse(args)
+
+	now := time.Now().UTC()
+	from := now.AddDate(-1, 0, 0)
+	if len(*fromStr) > 0 {
+		if from, err = time.Parse("2006-01-02", *fromStr); err != nil {
+			return
+		}
+	}
+
+	markets := recorderTargets()
+	if len(*marketsStr) > 0 {
+		markets = strings.Split(*marketsStr, ",")
+	}
+
+	if len(markets) == 0 {
+		return fmt.Errorf("받을 마켓이 없음")
+	}
+
+	fetcher, err := marketdata.NewHistoryFetcher(upbitapi.NewClient("", ""), marketdata.NewStorage(dir), *kind, *unit)
+	if err != nil {
+		return
+	}
+
+	fetcher.OnPage = func(market string, oldest time.Time, saved int) {
+		fmt.Printf("%s %s : %s 까지, %d 개 저장\n", fetcher.Kind(), market, oldest.Format(time.RFC3339), saved)
+	}
+
+	for _, market := range markets {
+		saved, fetchErr := fetcher.Fetch(strings.TrimSpace(market), from, now)
+		if fetchErr != nil {
+			fmt.Printf("%s 다운로드 중단 (다시 실행하면 이어받음) : %s\n", market, fetchErr.Error())
+			continue
+		}
+
+		fmt.Printf("%s %s 완료 : %d 개 저장\n", fetcher.Kind(), market, saved)
+	}
+
+	return
+}
@@ -0,0 +1,77 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+/*
+ * 운영 알림
+ * 로그에 남기고, webhook 주소가 있으면 JSON 으로 전송한다.
+ * (Slack 호환 text 필드를 함께 보낸다)
+ */
+
+type Notifier struct {
+	account    string
+	webhookURL string
+	logger     *log.Logger
+	httpClient *http.Client
+}
+
+type Message struct {
+	Account string    `json:"account"`
+	Title   string    `json:"title"`
+	Body    string    `json:"body"`
+	Text    string    `json:"text"`
+	Time    time.Time `json:"time"`
+}
+
+func NewNotifier(account string, webhookURL string, logger *log.Logger) *Notifier {
+	return &Notifier{
+		account:    account,
+		webhookURL: webhookURL,
+		logger:     logger,
+		httpClient: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (notifier *Notifier) Notify(title string, body string) {
+	notifier.logger.Printf("[알림] %s : %s\n", title, body)
+
+	if len(notifier.webhookURL) == 0 {
+		return
+	}
+
+	if err := notifier.post(title, body); err != nil {
+		notifier.logger.Printf("알림 전송 실패 : %s\n", err.Error())
+	}
+}
+
+func (notifier *Notifier) post(title string, body string) (err error) {
+	message := &Message{
+		Account: notifier.account,
+		Title:   title,
+		Body:    body,
+		Text:    fmt.Sprintf("[raindrop:%s] %s\n%s", notifier.account, title, body),
+		Time:    time.Now()}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+
+	response, err := notifier.httpClient.Post(notifier.webhookURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		err = fmt.Errorf("webhook 응답 %d", response.StatusCode)
+	}
+
+	return
+}
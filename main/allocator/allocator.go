@@ -0,0 +1,312 @@
+package allocator
+
+import (
+	"fmt"
+	"github.com/jekeun/upbit-go/types"
+	"log"
+	"math"
+	"raindrop/main/model"
+	"raindrop/main/quote"
+	"raindrop/main/report"
+	"raindrop/main/state"
+	"sort"
+	"strconv"
+)
+
+/*
+ * 전략별 자금 배분
+ * 한 계정에서 여러 전략이 매매하면 전략마다 전체 주문 가능 잔고를 보고 서로 경쟁하므로,
+ * 전략마다 예산을 정하고 봇 주문 기록으로 전략이 쓰고 있는 자금을 계산해 예산을 넘는 매수 주문을 막는다.
+ *
+ * fixed       : budgets 의 값(원화)을 예산으로 사용
+ * percent     : 평가 금액(원화 + 보유 코인 원화 환산)의 budgets 값 % 를 예산으로 사용
+ * risk_parity : 평가 금액의 total_rate % 를 budgets 에 적은 전략들에게
+ *               최근 거래 수익률 표준편차의 역수 비율로 나눈다.
+ */
+
+const (
+	ModeFixed      = "fixed"
+	ModePercent    = "percent"
+	ModeRiskParity = "risk_parity"
+
+	DefaultTotalRate      = 100.0
+	DefaultLookbackTrades = 20
+
+	// 변동성을 계산할 최소 거래 수, 모자라면 다른 전략의 평균 변동성을 사용한다.
+	minVolatilityTrades = 5
+)
+
+/*
+ * 전략의 예산과 사용 중인 자금 (원화)
+ * InUse : 봇 보유 수량의 매수 원가(수수료 포함) + 미체결 매수 주문 금액
+ */
+type Allocation struct {
+	Strategy  string
+	Budget    float64
+	InUse     float64
+	Available float64
+}
+
+type Allocator struct {
+	config model.AllocatorConfig
+	logger *log.Logger
+}
+
+func New(config model.AllocatorConfig, logger *log.Logger) *Allocator {
+	if config.Mode != ModePercent && config.Mode != ModeRiskParity {
+		config.Mode = ModeFixed
+	}
+
+	if config.TotalRate <= 0 {
+		config.TotalRate = DefaultTotalRate
+	}
+
+	if config.LookbackTrades <= 0 {
+		config.LookbackTrades = DefaultLookbackTrades
+	}
+
+	return &Allocator{
+		config: config,
+		logger: logger}
+}
+
+/*
+ * 전략의 예산과 사용 중인 자금을 구한다.
+ * equity : 평가 금액 (원화), rates : 기준 통화의 원화 환산 비율
+ * feeModelOf : 체결 기록에 수수료가 없을 때 추정할 수수료 모델 (risk_parity 에서 사용, nil 가능)
+ */
+func (allocator *Allocator) Allocate(strategy string,
+	store *state.Store,
+	equity float64,
+	rates map[string]float64,
+	feeModelOf report.FeeModelFunc) (allocation *Allocation) {
+
+	inUse, missing := InUse(store, rates)
+	if len(missing) > 0 {
+		allocator.logger.Printf("[자금 배분] 원화 환산 비율이 없어 기준 통화 금액을 그대로 사용 : %v\n", missing)
+	}
+
+	allocation = &Allocation{
+		Strategy: strategy,
+		Budget:   allocator.budgets(store, equity, feeModelOf)[strategy],
+		InUse:    inUse[strategy]}
+	allocation.Available = math.Max(allocation.Budget-allocation.InUse, 0)
+
+	allocator.logger.Printf("[자금 배분] %s (%s) : 예산 %.0f, 사용 중 %.0f, 남은 예산 %.0f, 평가 금액 %.0f\n",
+		strategy, allocator.config.Mode, allocation.Budget, allocation.InUse, allocation.Available, equity)
+
+	return
+}
+
+/*
+ * 전략별 예산 (원화), budgets 에 없는 전략은 예산이 0 이다.
+ */
+func (allocator *Allocator) budgets(store *state.Store,
+	equity float64,
+	feeModelOf report.FeeModelFunc) (budgets map[string]float64) {
+
+	budgets = make(map[string]float64)
+
+	switch allocator.config.Mode {
+	case ModePercent:
+		for strategy, rate := range allocator.config.Budgets {
+			budgets[strategy] = equity * rate / 100
+		}
+
+	case ModeRiskParity:
+		total := equity * allocator.config.TotalRate / 100
+		for strategy, weight := range RiskParityWeights(allocator.strategies(), report.RoundTrips(store, feeModelOf),
+			allocator.config.LookbackTrades) {
+			budgets[strategy] = total * weight
+		}
+
+	default:
+		for strategy, amount := range allocator.config.Budgets {
+			budgets[strategy] = amount
+		}
+	}
+
+	return
+}
+
+func (allocator *Allocator) strategies() (strategies []string) {
+	for strategy := range allocator.config.Budgets {
+		strategies = append(strategies, strategy)
+	}
+	sort.Strings(strategies)
+
+	return
+}
+
+/*
+ * 주문 금액(원화)이 남은 예산 안에 있는지 확인한다.
+ */
+func (allocation *Allocation) Check(amount float64) error {
+	if amount > allocation.Available {
+		return fmt.Errorf("%s 예산 초과 : 주문 금액 %.0f, 남은 예산 %.0f (예산 %.0f, 사용 중 %.0f)",
+			allocation.Strategy, amount, allocation.Available, allocation.Budget, allocation.InUse)
+	}
+
+	return nil
+}
+
+/*
+ * 같은 주기에 낸 주문 금액을 사용 중인 자금에 더한다.
+ */
+func (allocation *Allocation) Use(amount float64) {
+	allocation.InUse += amount
+	allocation.Available = math.Max(allocation.Budget-allocation.InUse, 0)
+}
+
+/*
+ * 전략별 사용 중인 자금 (원화)
+ * 체결 기록을 전략 + 마켓별로 모아 매수는 원가(평균가 * 체결량 + 수수료)를 더하고,
+ * 매도는 매도 수량 비율만큼 원가를 뺀다. 미체결 매수 주문은 남은 수량 * 주문 가격을 더한다.
+ * 원화 환산 비율이 없는 기준 통화는 missing 으로 돌려주고 금액을 그대로 더한다.
+ */
+func InUse(store *state.Store, rates map[string]float64) (inUse map[string]float64, missing []string) {
+	type holding struct {
+		strategy string
+		market   string
+		volume   float64
+		cost     float64
+	}
+
+	holdings := make(map[string]*holding)
+	holdingOf := func(record *state.OrderRecord) *holding {
+		key := record.Strategy + "|" + record.Market
+		if _, exist := holdings[key]; !exist {
+			holdings[key] = &holding{strategy: record.Strategy, market: record.Market}
+		}
+		return holdings[key]
+	}
+
+	for _, record := range store.FilledOrders() {
+		position := holdingOf(record)
+
+		switch record.Side {
+		case types.ORDERSIDE_BID:
+			position.volume += record.ExecutedVolume
+			position.cost += record.AvgPrice*record.ExecutedVolume + record.PaidFee
+		case types.ORDERSIDE_ASK:
+			if position.volume <= 0 {
+				continue
+			}
+			sold := math.Min(record.ExecutedVolume, position.volume)
+			position.cost -= position.cost * sold / position.volume
+			position.volume -= sold
+		}
+	}
+
+	for _, record := range store.PendingOrders() {
+		if record.Side != types.ORDERSIDE_BID {
+			continue
+		}
+
+		price, priceErr := strconv.ParseFloat(record.Price, 64)
+		volume, volumeErr := strconv.ParseFloat(record.Volume, 64)
+		if priceErr != nil || volumeErr != nil {
+			continue
+		}
+
+		if remaining := volume - record.ExecutedVolume; remaining > 0 {
+			holdingOf(record).cost += price * remaining
+		}
+	}
+
+	inUse = make(map[string]float64)
+	missingMap := make(map[string]bool)
+
+	for _, position := range holdings {
+		if position.cost <= 0 {
+			continue
+		}
+
+		currency := quote.Of(position.market)
+		amount, ok := quote.ToKRW(position.cost, currency, rates)
+		if !ok && !missingMap[currency] {
+			missingMap[currency] = true
+			missing = append(missing, currency)
+		}
+
+		inUse[position.strategy] += amount
+	}
+
+	return
+}
+
+/*
+ * 전략별 리스크 패리티 비중 (합 1)
+ * 전략의 최근 lookback 개 거래 수익률 표준편차의 역수에 비례한다.
+ * 거래가 적거나 표준편차가 0 인 전략은 다른 전략의 평균 표준편차를 쓰고, 모두 없으면 같은 비중으로 나눈다.
+ */
+func RiskParityWeights(strategies []string, trades []*model.Trade, lookback int) (weights map[string]float64) {
+	weights = make(map[string]float64)
+	if len(strategies) == 0 {
+		return
+	}
+
+	rates := make(map[string][]float64)
+	for _, trade := range trades {
+		rates[trade.Strategy] = append(rates[trade.Strategy], trade.ProfitRate)
+	}
+
+	volatilities := make(map[string]float64)
+	sum := 0.0
+	for _, strategy := range strategies {
+		recent := rates[strategy]
+		if len(recent) > lookback {
+			recent = recent[len(recent)-lookback:]
+		}
+
+		if len(recent) < minVolatilityTrades {
+			continue
+		}
+
+		if volatility := stdev(recent); volatility > 0 {
+			volatilities[strategy] = volatility
+			sum += volatility
+		}
+	}
+
+	fallback := 1.0
+	if len(volatilities) > 0 {
+		fallback = sum / float64(len(volatilities))
+	}
+
+	inverseSum := 0.0
+	for _, strategy := range strategies {
+		volatility, exist := volatilities[strategy]
+		if !exist {
+			volatility = fallback
+		}
+
+		weights[strategy] = 1 / volatility
+		inverseSum += weights[strategy]
+	}
+
+	for strategy := range weights {
+		weights[strategy] /= inverseSum
+	}
+
+	return
+}
+
+func stdev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+
+	mean := 0.0
+	for _, value := range values {
+		mean += value
+	}
+	mean /= float64(len(values))
+
+	variance := 0.0
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+
+	return math.Sqrt(variance / float64(len(values)-1))
+}
@@ -0,0 +1,375 @@
+package backtest
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"math"
+	"raindrop/main/fee"
+	"raindrop/main/marketdata"
+	"raindrop/main/model"
+	"raindrop/main/risk"
+	"raindrop/main/strategy/lw_basic"
+	"sort"
+	"time"
+)
+
+/*
+ * Larry Williams 변동성 돌파 전략 일봉 백테스트
+ * 매수 : 당일 고가가 시가 + 전일 Range * k 이상이면 돌파 가격에 체결된 것으로 본다.
+ * 매도 : 당일 종가 (다음 세션 시작 시각) 에 전량 매도
+ * 신호 계산은 실매매와 같은 lw_basic 함수를 사용한다.
+ * RunIntraday 는 분봉으로 돌파 시점, 지정가 체결, 매도 시간대의 가격 조정을 시뮬레이션한다. (intraday.go)
+ */
+
+const (
+	DefaultInitialCash   = 1000000.0
+	DefaultFeeRate       = 0.0005
+	DefaultNoiseLookback = 20
+
+	// 실매매와 같이 최근 20개 일봉으로 이평 스코어를 계산한다.
+	candleWindow = 20
+	strategyName = "lw_basic"
+)
+
+type Params struct {
+	Strategy    model.LarryStrategyConfig `json:"strategy"`
+	InitialCash float64                   `json:"initial_cash"`
+	FeeRate     float64                   `json:"fee_rate"`
+	// 돌파 가격 매수, 종가 매도에 불리하게 적용할 슬리피지 비율
+	Slippage float64 `json:"slippage"`
+
+	// 노이즈 k 를 구할 기간, 0 이면 k_value 고정값을 사용한다.
+	NoiseLookback int `json:"noise_lookback"`
+
+	// 거래 기간 (0 이면 제한 없음), 이전 캔들은 신호 계산에만 사용한다.
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	// 분봉 체결 시뮬레이션의 현재가 조회 주기 (초), 0 이면 실매매와 같은 10초
+	PollSecond int `json:"poll_second,omitempty"`
+}
+
+type Result struct {
+	Params  Params               `json:"params"`
+	Summary Summary              `json:"summary"`
+	Trades  []*model.Trade       `json:"trades"`
+	Equity  []*model.EquityPoint `json:"equity"`
+
+	// 분봉 체결 시뮬레이션에서 분봉이 없어 일봉 가정으로 체결한 거래 수
+	DailyFallback int `json:"daily_fallback,omitempty"`
+}
+
+func DefaultParams(strategy model.LarryStrategyConfig) Params {
+	return Params{
+		Strategy:      strategy,
+		InitialCash:   DefaultInitialCash,
+		FeeRate:       DefaultFeeRate,
+		NoiseLookback: DefaultNoiseLookback}
+}
+
+/*
+ * 백테스트 실행
+ * series : 코인별 일봉 (과거 -> 최신 순)
+ */
+func Run(params Params, series map[string][]*marketdata.Candle) (result *Result) {
+	return run(params, series, nil)
+}
+
+/*
+ * 분봉 체결 시뮬레이션 백테스트
+ * minutes : 코인별 1분봉 또는 5분봉 (과거 -> 최신 순), 분봉이 없는 날은 일봉 가정으로 체결한다.
+ */
+func RunIntraday(params Params, series map[string][]*marketdata.Candle, minutes map[string][]*marketdata.Candle) (result *Result) {
+	return run(params, series, minutes)
+}
+
+func run(params Params, series map[string][]*marketdata.Candle, minutes map[string][]*marketdata.Candle) (result *Result) {
+	result = &Result{
+		Params: params,
+		Trades: make([]*model.Trade, 0),
+		Equity: make([]*model.EquityPoint, 0)}
+
+	indexMap := make(map[string]map[time.Time]int)
+	for market, candles := range series {
+		indexMap[market] = make(map[time.Time]int)
+		for index, candle := range candles {
+			indexMap[market][candle.Time] = index
+		}
+	}
+
+	minIndex := MinHistory(params)
+
+	cash := params.InitialCash
+
+	for _, day := range tradingDays(series) {
+		if (!params.From.IsZero() && day.Before(params.From)) || (!params.To.IsZero() && day.After(params.To)) {
+			continue
+		}
+
+		// 평가 금액 기준 주문 금액 : 전일 포지션은 모두 청산되었으므로 현금이 평가 금액이다.
+		dayParams := params
+		dayParams.Strategy.OrderAmount = lw_basic.BaseOrderAmount(&params.Strategy, cash)
+
+		entries := make([]*entry, 0)
+		for _, market := range params.Strategy.Targets {
+			index, exist := indexMap[market][day]
+			if !exist || index < minIndex {
+				continue
+			}
+
+			if dayEntry := simulateDay(&dayParams, market, series[market], index, minutes[market]); dayEntry != nil {
+				entries = append(entries, dayEntry)
+			}
+		}
+
+		// 먼저 체결된 순서로 최대 보유 코인 수, 자금을 적용한다. (일봉 가정은 모두 같은 시각이므로 타겟 순서)
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].entryTime.Before(entries[j].entryTime)
+		})
+
+		// 상관계수 진입 제한 : 같은 날 먼저 진입한 코인이 보유 코인이다.
+		var returns map[string][]float64
+		if params.Strategy.Correlation.Enable == 1 {
+			returns = dayReturns(&params, series, indexMap, day, entries)
+		}
+
+		dayTrades := make([]*model.Trade, 0)
+		held := make([]string, 0)
+		for _, dayEntry := range entries {
+			if len(dayTrades) >= params.Strategy.MaxCoin || cash <= 0 ||
+				cash < lw_basic.RequiredBalance(&params.Strategy, dayParams.Strategy.OrderAmount) {
+				break
+			}
+
+			if returns != nil {
+				_, amountRate := lw_basic.EvaluateCorrelation(params.Strategy.Correlation, dayEntry.market, held, returns)
+				if amountRate <= 0 {
+					continue
+				}
+				dayEntry.orderAmount *= amountRate
+			}
+			held = append(held, dayEntry.market)
+
+			trade := dayEntry.trade(&params, cash)
+			cash -= trade.EntryPrice*trade.Volume + trade.Fee
+			dayTrades = append(dayTrades, trade)
+
+			if dayEntry.fallback {
+				result.DailyFallback++
+			}
+		}
+
+		// 당일 진입한 포지션은 종가에 모두 청산된다.
+		for _, trade := range dayTrades {
+			cash += trade.EntryPrice*trade.Volume + trade.Fee + trade.Profit
+		}
+		result.Trades = append(result.Trades, dayTrades...)
+
+		result.Equity = append(result.Equity, &model.EquityPoint{Time: day, Equity: cash})
+	}
+
+	result.Summary = Summarize(params.InitialCash, result.Trades, result.Equity)
+
+	return
+}
+
+/*
+ * 진입 후보 코인의 day 이전 일간 수익률 (최신순, 상관계수 계산용)
+ */
+func dayReturns(params *Params,
+	series map[string][]*marketdata.Candle,
+	indexMap map[string]map[time.Time]int,
+	day time.Time,
+	entries []*entry) (returns map[string][]float64) {
+
+	lookback := lw_basic.CorrelationLookbackDays(params.Strategy.Correlation)
+	returns = make(map[string][]float64)
+
+	for _, dayEntry := range entries {
+		index := indexMap[dayEntry.market][day]
+
+		// 당일 봉 + 전일부터 lookback 개 수익률에 필요한 lookback + 1 개 봉
+		start := index - lookback - 1
+		if start < 0 {
+			start = 0
+		}
+
+		returns[dayEntry.market] = risk.Returns(newestFirst(series[dayEntry.market][start:index+1]), lookback)
+	}
+
+	return
+}
+
+/*
+ * 신호 계산에 필요한 이전 일봉 수 (이보다 앞선 날은 거래하지 않는다.)
+ */
+func MinHistory(params Params) int {
+	if params.NoiseLookback > candleWindow-1 {
+		return params.NoiseLookback
+	}
+
+	return candleWindow - 1
+}
+
+/*
+ * 하루 동안의 진입 여부와 체결 가격을 계산한다.
+ * 돌파가 없거나, 주문 금액이 없거나, 지정가 매수가 체결되지 않으면 nil
+ * minutes 가 있으면 분봉으로 체결을 시뮬레이션한다.
+ */
+func simulateDay(params *Params, market string, candles []*marketdata.Candle, index int, minutes []*marketdata.Candle) (dayEntry *entry) {
+	today := candles[index]
+	prev := candles[index-1]
+
+	kValue := params.Strategy.KValue
+	if params.NoiseLookback > 0 {
+		noiseK := lw_basic.KNoiseValue(newestFirst(candles[index-params.NoiseLookback : index]))
+		if !math.IsNaN(noiseK) {
+			kValue = noiseK
+		}
+	}
+
+	triggerPrice := today.Open + (prev.High-prev.Low)*kValue
+	if today.High < triggerPrice {
+		return
+	}
+
+	// 돌파 시점의 당일 봉 : 현재가 = 돌파 가격
+	window := make([]*types.DayCandle, 0, candleWindow)
+	window = append(window, &types.DayCandle{
+		OpeningPrice: today.Open,
+		HighPrice:    math.Max(today.Open, triggerPrice),
+		LowPrice:     today.Open,
+		TradePrice:   triggerPrice})
+	window = append(window, newestFirst(candles[index-candleWindow+1:index])...)
+
+	malScore := lw_basic.MalScore(window)
+	signal := lw_basic.NewSignal(&params.Strategy, market, window, kValue, malScore)
+	if signal == nil || signal.OrderAmount <= 0 {
+		return
+	}
+
+	feeModel := params.feeModel()
+
+	dayEntry = &entry{
+		market:      market,
+		orderAmount: signal.OrderAmount,
+		kValue:      kValue,
+		malScore:    malScore}
+
+	if len(minutes) > 0 {
+		session := newIntradaySession(params, minutes, today.Time)
+		if !session.hasData() {
+			dayEntry.fallback = true
+		} else {
+			price, at, filled := session.entry(market, triggerPrice)
+			if !filled {
+				return nil
+			}
+
+			exitPrice, exitAt, marketOrder := session.exit(market)
+
+			// 지정가 체결은 주문 가격 그대로, 시장가 매도만 슬리피지를 적용한다.
+			if marketOrder {
+				exitPrice = feeModel.ExitPrice(exitPrice)
+			}
+
+			dayEntry.entryPrice, dayEntry.entryTime = price, at
+			dayEntry.exitPrice, dayEntry.exitTime = exitPrice, exitAt
+
+			return
+		}
+	}
+
+	dayEntry.entryPrice = feeModel.EntryPrice(triggerPrice)
+	dayEntry.exitPrice = feeModel.ExitPrice(today.Close)
+	dayEntry.entryTime = today.Time
+	dayEntry.exitTime = today.Time
+
+	return
+}
+
+/*
+ * 체결된 매수 (주문 금액은 진입 순서대로 자금을 적용할 때 정한다.)
+ */
+type entry struct {
+	market      string
+	orderAmount float64
+	kValue      float64
+	malScore    float64
+
+	entryPrice float64
+	exitPrice  float64
+	entryTime  time.Time
+	exitTime   time.Time
+
+	// 분봉이 없어 일봉 가정으로 체결
+	fallback bool
+}
+
+func (dayEntry *entry) trade(params *Params, cash float64) *model.Trade {
+	feeModel := params.feeModel()
+
+	orderAmount := math.Min(dayEntry.orderAmount, cash/(1+params.FeeRate))
+	volume := orderAmount / dayEntry.entryPrice
+
+	entryFee := feeModel.BidFee(orderAmount)
+	proceeds := volume * dayEntry.exitPrice
+	exitFee := feeModel.AskFee(proceeds)
+	profit := proceeds - exitFee - orderAmount - entryFee
+
+	return &model.Trade{
+		Strategy:   strategyName,
+		Market:     dayEntry.market,
+		EntryTime:  dayEntry.entryTime,
+		ExitTime:   dayEntry.exitTime,
+		Volume:     volume,
+		EntryPrice: dayEntry.entryPrice,
+		ExitPrice:  dayEntry.exitPrice,
+		Fee:        entryFee + exitFee,
+		Profit:     profit,
+		ProfitRate: profit / (orderAmount + entryFee) * 100,
+		KValue:     dayEntry.kValue,
+		MalScore:   dayEntry.malScore}
+}
+
+func (params *Params) feeModel() *fee.Model {
+	return &fee.Model{
+		BidRate:     params.FeeRate,
+		AskRate:     params.FeeRate,
+		BidSlippage: params.Slippage,
+		AskSlippage: params.Slippage}
+}
+
+/*
+ * 모든 코인의 일봉 날짜를 합쳐 순서대로 돌려준다.
+ */
+func tradingDays(series map[string][]*marketdata.Candle) (days []time.Time) {
+	daySet := make(map[time.Time]bool)
+	for _, candles := range series {
+		for _, candle := range candles {
+			daySet[candle.Time] = true
+		}
+	}
+
+	days = make([]time.Time, 0, len(daySet))
+	for day := range daySet {
+		days = append(days, day)
+	}
+
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Before(days[j])
+	})
+
+	return
+}
+
+/*
+ * 과거순 캔들을 전략 함수가 사용하는 최신순 일봉으로 변환
+ */
+func newestFirst(candles []*marketdata.Candle) (dayCandles []*types.DayCandle) {
+	dayCandles = make([]*types.DayCandle, 0, len(candles))
+	for index := len(candles) - 1; index >= 0; index-- {
+		dayCandles = append(dayCandles, candles[index].DayCandle())
+	}
+
+	return
+}
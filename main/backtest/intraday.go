@@ -0,0 +1,186 @@
+package backtest
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"raindrop/main/marketdata"
+	"raindrop/main/money"
+	"raindrop/main/rules"
+	"sort"
+	"time"
+)
+
+/*
+ * 분봉 체결 시뮬레이션
+ * 일봉 백테스트는 돌파 가격에 정확히 매수되고 종가에 매도된 것으로 보지만,
+ * 실매매는 10초마다 현재가를 보고 지정가 주문을 내므로 체결되지 않거나 다른 가격에 체결될 수 있다.
+ *
+ * 매수 : 매도 시간대(세션 시작 ~ ask_period_minute 분) 이후 돌파 가격 이상이 처음 나온 분봉에서
+ *       조회 주기(PollSecond)만큼 늦게 돌파 가격(호가 단위 내림) 지정가 매수를 낸다.
+ *       그 봉의 종가가 주문 가격 이하이거나, 주문 이후 시작한 분봉의 저가가 주문 가격 이하이면 체결된다.
+ *       다음 세션 시작까지 체결되지 않으면 실매매와 같이 취소된 것으로 본다.
+ * 매도 : 다음 세션 시작에 현재가(호가 단위 올림) 지정가 매도를 내고, 주문 이후 시작한 분봉의 고가가 주문 가격 이상이면 체결된다.
+ *       ask_order_gap 초가 지난 다음 조회에서 현재가로 다시 주문하고 (forceAskOrder),
+ *       매도 시간대가 끝날 때까지 체결되지 않으면 시장가로 매도한다. (forceAskMarketOrder)
+ * 분봉 안의 가격 순서는 알 수 없으므로 주문 시각 이전에 시작한 분봉은 체결 판단에 쓰지 않는다.
+ */
+
+const (
+	DefaultPollSecond = 10
+
+	// 체결 시뮬레이션의 호가 단위 규칙
+	intradayExchange = "upbit"
+)
+
+type intradaySession struct {
+	candles []*marketdata.Candle
+	unit    time.Duration
+	poll    time.Duration
+
+	// 매도 시간대 길이, 같은 시각 이후 지정가 매도를 다시 내기까지의 시간
+	askWindow    time.Duration
+	repriceDelay time.Duration
+
+	start time.Time
+	next  time.Time
+}
+
+/*
+ * start 에 시작하는 세션의 매수와 다음 세션 매도 시간대의 매도를 분봉으로 시뮬레이션한다.
+ */
+func newIntradaySession(params *Params, minutes []*marketdata.Candle, start time.Time) *intradaySession {
+	pollSecond := params.PollSecond
+	if pollSecond <= 0 {
+		pollSecond = DefaultPollSecond
+	}
+
+	// 실매매는 주문 경과 시간이 ask_order_gap 초를 넘은 첫 조회에서 가격을 조정한다.
+	repriceSecond := (params.Strategy.AskOrderGap/pollSecond + 1) * pollSecond
+
+	return &intradaySession{
+		candles:      minutes,
+		unit:         candleUnit(minutes),
+		poll:         time.Duration(pollSecond) * time.Second,
+		askWindow:    time.Duration(params.Strategy.AskPeriodMinute+1) * time.Minute,
+		repriceDelay: time.Duration(repriceSecond) * time.Second,
+		start:        start,
+		next:         start.Add(24 * time.Hour)}
+}
+
+/*
+ * 세션과 다음 세션 매도 시간대의 분봉이 모두 있는지
+ */
+func (session *intradaySession) hasData() bool {
+	return len(session.between(session.start, session.next)) > 0 &&
+		len(session.between(session.next, session.next.Add(session.askWindow))) > 0
+}
+
+/*
+ * 돌파 가격 지정가 매수의 체결 가격과 시각
+ */
+func (session *intradaySession) entry(market string, triggerPrice float64) (price float64, at time.Time, filled bool) {
+	bidPrice := rules.For(intradayExchange, market).Price(types.ORDERSIDE_BID, money.NewFromFloat(triggerPrice)).Float64()
+
+	candles := session.between(session.start.Add(session.askWindow), session.next)
+	for index, candle := range candles {
+		if candle.High < triggerPrice {
+			continue
+		}
+
+		placedAt := candle.Time.Add(session.poll)
+
+		// 돌파한 봉이 주문 가격 이하에서 끝났으면 주문 이후 가격이 내려온 것이다.
+		if candle.Close <= bidPrice {
+			return bidPrice, placedAt, true
+		}
+
+		for _, later := range candles[index+1:] {
+			if later.Time.Before(placedAt) {
+				continue
+			}
+			if later.Low <= bidPrice {
+				return bidPrice, later.Time, true
+			}
+		}
+
+		return 0, time.Time{}, false
+	}
+
+	return 0, time.Time{}, false
+}
+
+/*
+ * 다음 세션 매도 시간대의 매도 가격과 시각, 시장가 매도 여부
+ */
+func (session *intradaySession) exit(market string) (price float64, at time.Time, marketOrder bool) {
+	askRules := rules.For(intradayExchange, market)
+	windowEnd := session.next.Add(session.askWindow)
+
+	for placedAt := session.next; placedAt.Before(windowEnd); {
+		askPrice := askRules.Price(types.ORDERSIDE_ASK, money.NewFromFloat(session.priceAt(placedAt))).Float64()
+
+		cancelAt := placedAt.Add(session.repriceDelay)
+		if cancelAt.After(windowEnd) {
+			cancelAt = windowEnd
+		}
+
+		for _, candle := range session.between(placedAt, cancelAt) {
+			if candle.High >= askPrice {
+				return askPrice, candle.Time, false
+			}
+		}
+
+		placedAt = cancelAt
+	}
+
+	return session.priceAt(windowEnd), windowEnd, true
+}
+
+/*
+ * from 이후 ~ to 이전에 시작한 분봉
+ */
+func (session *intradaySession) between(from time.Time, to time.Time) []*marketdata.Candle {
+	begin := sort.Search(len(session.candles), func(i int) bool {
+		return !session.candles[i].Time.Before(from)
+	})
+
+	end := begin
+	for end < len(session.candles) && session.candles[end].Time.Before(to) {
+		end++
+	}
+
+	return session.candles[begin:end]
+}
+
+/*
+ * at 시각의 현재가 : at 이전에 끝난 마지막 분봉의 종가, 없으면 처음 분봉의 시가
+ */
+func (session *intradaySession) priceAt(at time.Time) float64 {
+	index := sort.Search(len(session.candles), func(i int) bool {
+		return session.candles[i].Time.Add(session.unit).After(at)
+	})
+
+	if index > 0 {
+		return session.candles[index-1].Close
+	}
+	if len(session.candles) > 0 {
+		return session.candles[0].Open
+	}
+
+	return 0
+}
+
+/*
+ * 분봉 간격 (연속한 두 봉 사이의 가장 짧은 간격)
+ */
+func candleUnit(candles []*marketdata.Candle) (unit time.Duration) {
+	unit = time.Minute
+
+	for index := 1; index < len(candles) && index < 10; index++ {
+		gap := candles[index].Time.Sub(candles[index-1].Time)
+		if gap > 0 && (index == 1 || gap < unit) {
+			unit = gap
+		}
+	}
+
+	return
+}
@@ -0,0 +1,158 @@
+package backtest
+
+import (
+	"raindrop/main/marketdata"
+	"raindrop/main/model"
+	"testing"
+	"time"
+)
+
+var sessionStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// [시작 분, 시가, 고가, 저가, 종가]
+type bar [5]float64
+
+/*
+ * from 에 시작하는 1분봉, bars 에 없는 분은 flat 가격의 봉으로 채운다.
+ */
+func minuteCandles(from time.Time, count int, flat float64, bars ...bar) (candles []*marketdata.Candle) {
+	byMinute := make(map[int]bar)
+	for _, value := range bars {
+		byMinute[int(value[0])] = value
+	}
+
+	for minute := 0; minute < count; minute++ {
+		value, exist := byMinute[minute]
+		if !exist {
+			value = bar{float64(minute), flat, flat, flat, flat}
+		}
+
+		candles = append(candles, &marketdata.Candle{
+			Market: "KRW-XRP",
+			Time:   from.Add(time.Duration(minute) * time.Minute),
+			Open:   value[1],
+			High:   value[2],
+			Low:    value[3],
+			Close:  value[4]})
+	}
+
+	return
+}
+
+func intradayParams() *Params {
+	params := DefaultParams(model.LarryStrategyConfig{AskPeriodMinute: 5, AskOrderGap: 30})
+	params.PollSecond = 10
+
+	return &params
+}
+
+func TestIntradayEntry(t *testing.T) {
+	// 돌파 가격 1000.4 -> 지정가 매수 1000 (호가 단위 1 내림)
+	const trigger = 1000.4
+
+	tests := []struct {
+		name   string
+		bars   []bar
+		filled bool
+		at     time.Duration
+	}{
+		{"breakout closes below order", []bar{{10, 995, 1005, 995, 999}}, true, 10*time.Minute + 10*time.Second},
+		{"later candle dips to order", []bar{{10, 995, 1005, 995, 1003}, {11, 1003, 1004, 1001, 1002}, {12, 1002, 1003, 1000, 1001}},
+			true, 12 * time.Minute},
+		{"never returns to order", []bar{{10, 995, 1005, 995, 1003}, {11, 1003, 1010, 1001, 1008}, {12, 1008, 1009, 1002, 1005}},
+			false, 0},
+		// 매도 시간대(세션 시작 ~ 6분) 안의 돌파는 매수하지 않는다.
+		{"breakout in ask window", []bar{{3, 995, 1005, 990, 990}}, false, 0},
+		{"no breakout", nil, false, 0},
+	}
+
+	for _, test := range tests {
+		// 돌파 전후 나머지 봉은 990, 세션의 처음 13분만 사용한다.
+		candles := minuteCandles(sessionStart, 13, 990, test.bars...)
+
+		session := newIntradaySession(intradayParams(), candles, sessionStart)
+		price, at, filled := session.entry("KRW-XRP", trigger)
+
+		if filled != test.filled {
+			t.Errorf("%s : filled = %t, want %t", test.name, filled, test.filled)
+			continue
+		}
+		if !filled {
+			continue
+		}
+		if price != 1000 || !at.Equal(sessionStart.Add(test.at)) {
+			t.Errorf("%s : price = %v, at = %s, want 1000, %s", test.name, price, at, sessionStart.Add(test.at))
+		}
+	}
+}
+
+func TestIntradayExit(t *testing.T) {
+	next := sessionStart.Add(24 * time.Hour)
+
+	tests := []struct {
+		name        string
+		bars        []bar
+		price       float64
+		at          time.Duration
+		marketOrder bool
+	}{
+		// 직전 봉 종가 1000 에 지정가 매도, 첫 봉에서 체결
+		{"first candle", []bar{{0, 1000, 1001, 999, 1000}}, 1000, 0, false},
+		// 40초(ask_order_gap 30초 이후 첫 조회)마다 직전에 끝난 봉의 종가로 다시 주문한다.
+		{"repriced", []bar{{0, 999, 999, 995, 995}, {1, 995, 999, 994, 996}, {2, 996, 996, 990, 992}}, 996, 2 * time.Minute, false},
+		// 매도 시간대(6분) 동안 체결되지 않으면 마지막 종가에 시장가 매도
+		{"market order", []bar{{0, 990, 990, 980, 985}, {1, 984, 984, 970, 975}, {2, 974, 974, 960, 965},
+			{3, 964, 964, 950, 955}, {4, 954, 954, 940, 945}, {5, 944, 944, 930, 935}}, 935, 6 * time.Minute, true},
+	}
+
+	for _, test := range tests {
+		// 세션 직전 봉의 종가가 1000, 매도 시간대는 bars 외에 900 으로 채운다.
+		before := minuteCandles(next.Add(-time.Minute), 1, 1000)
+		after := minuteCandles(next, 10, 900, test.bars...)
+
+		session := newIntradaySession(intradayParams(), append(before, after...), sessionStart)
+		price, at, marketOrder := session.exit("KRW-XRP")
+
+		if price != test.price || !at.Equal(next.Add(test.at)) || marketOrder != test.marketOrder {
+			t.Errorf("%s : price = %v, at = %s, market = %t, want %v, %s, %t", test.name,
+				price, at, marketOrder, test.price, next.Add(test.at), test.marketOrder)
+		}
+	}
+}
+
+func TestIntradayHasData(t *testing.T) {
+	tests := []struct {
+		name    string
+		candles []*marketdata.Candle
+		want    bool
+	}{
+		{"full", minuteCandles(sessionStart, 24*60+10, 1000), true},
+		{"missing next session", minuteCandles(sessionStart, 24*60, 1000), false},
+		{"missing session", minuteCandles(sessionStart.Add(24*time.Hour), 10, 1000), false},
+	}
+
+	for _, test := range tests {
+		if hasData := newIntradaySession(intradayParams(), test.candles, sessionStart).hasData(); hasData != test.want {
+			t.Errorf("%s : hasData = %t, want %t", test.name, hasData, test.want)
+		}
+	}
+}
+
+func TestCandleUnit(t *testing.T) {
+	tests := []struct {
+		name    string
+		candles []*marketdata.Candle
+		want    time.Duration
+	}{
+		{"minute", minuteCandles(sessionStart, 5, 1000), time.Minute},
+		{"single", minuteCandles(sessionStart, 1, 1000), time.Minute},
+		{"hour", []*marketdata.Candle{{Time: sessionStart}, {Time: sessionStart.Add(time.Hour)}, {Time: sessionStart.Add(3 * time.Hour)}},
+			time.Hour},
+	}
+
+	for _, test := range tests {
+		if unit := candleUnit(test.candles); unit != test.want {
+			t.Errorf("%s : unit = %s, want %s", test.name, unit, test.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package backtest
+
+import (
+	"math"
+	"raindrop/main/model"
+)
+
+type Summary struct {
+	InitialEquity float64 `json:"initial_equity"`
+	FinalEquity   float64 `json:"final_equity"`
+	TotalReturn   float64 `json:"total_return"`
+	CAGR          float64 `json:"cagr"`
+	MDD           float64 `json:"mdd"`
+	Sharpe        float64 `json:"sharpe"`
+	TradeCount    int     `json:"trade_count"`
+	WinRate       float64 `json:"win_rate"`
+	ProfitFactor  float64 `json:"profit_factor"`
+}
+
+/*
+ * 거래 목록과 일별 평가 금액으로 성과 지표를 계산한다.
+ * 수익률, MDD 는 % 단위, Sharpe 는 일간 수익률 기준 연율화 (365일)
+ */
+func Summarize(initialEquity float64, trades []*model.Trade, equity []*model.EquityPoint) (summary Summary) {
+	summary.InitialEquity = initialEquity
+	summary.FinalEquity = initialEquity
+	summary.TradeCount = len(trades)
+
+	if len(equity) > 0 {
+		summary.FinalEquity = equity[len(equity)-1].Equity
+	}
+
+	if initialEquity > 0 {
+		summary.TotalReturn = (summary.FinalEquity/initialEquity - 1) * 100
+	}
+
+	if len(equity) > 1 && initialEquity > 0 && summary.FinalEquity > 0 {
+		years := equity[len(equity)-1].Time.Sub(equity[0].Time).Hours() / 24 / 365
+		if years > 0 {
+			summary.CAGR = (math.Pow(summary.FinalEquity/initialEquity, 1/years) - 1) * 100
+		}
+	}
+
+	summary.MDD = MaxDrawdown(initialEquity, equity)
+	summary.Sharpe = Sharpe(initialEquity, equity)
+
+	winCount := 0
+	grossProfit := 0.0
+	grossLoss := 0.0
+	for _, trade := range trades {
+		if trade.Profit > 0 {
+			winCount++
+			grossProfit += trade.Profit
+		} else {
+			grossLoss -= trade.Profit
+		}
+	}
+
+	if len(trades) > 0 {
+		summary.WinRate = float64(winCount) / float64(len(trades)) * 100
+	}
+
+	if grossLoss > 0 {
+		summary.ProfitFactor = grossProfit / grossLoss
+	}
+
+	return
+}
+
+/*
+ * 최대 낙폭 (%)
+ */
+func MaxDrawdown(initialEquity float64, equity []*model.EquityPoint) (mdd float64) {
+	peak := initialEquity
+
+	for _, point := range equity {
+		if point.Equity > peak {
+			peak = point.Equity
+		}
+
+		if peak > 0 {
+			drawdown := (peak - point.Equity) / peak * 100
+			if drawdown > mdd {
+				mdd = drawdown
+			}
+		}
+	}
+
+	return
+}
+
+/*
+ * 일간 수익률 기준 Sharpe 비율 (무위험 수익률 0)
+ */
+func Sharpe(initialEquity float64, equity []*model.EquityPoint) float64 {
+	returns := DailyReturns(initialEquity, equity)
+	if len(returns) < 2 {
+		return 0
+	}
+
+	mean := 0.0
+	for _, value := range returns {
+		mean += value
+	}
+	mean /= float64(len(returns))
+
+	variance := 0.0
+	for _, value := range returns {
+		variance += (value - mean) * (value - mean)
+	}
+	variance /= float64(len(returns) - 1)
+
+	if variance <= 0 {
+		return 0
+	}
+
+	return mean / math.Sqrt(variance) * math.Sqrt(365)
+}
+
+func DailyReturns(initialEquity float64, equity []*model.EquityPoint) (returns []float64) {
+	returns = make([]float64, 0, len(equity))
+
+	prev := initialEquity
+	for _, point := range equity {
+		if prev > 0 {
+			returns = append(returns, point.Equity/prev-1)
+		}
+		prev = point.Equity
+	}
+
+	return
+}
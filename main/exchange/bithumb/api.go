@@ -0,0 +1,213 @@
+package bithumb
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+)
+
+/*
+ * Bithumb API 응답
+ * 숫자 필드가 문자열/숫자로 섞여 오므로 flexString 으로 받는다.
+ */
+
+type flexString string
+
+func (value *flexString) UnmarshalJSON(data []byte) (err error) {
+	var text string
+	if err = json.Unmarshal(data, &text); err == nil {
+		*value = flexString(text)
+		return
+	}
+
+	var number json.Number
+	if err = json.Unmarshal(data, &number); err != nil {
+		return
+	}
+	*value = flexString(number.String())
+
+	return
+}
+
+func (value flexString) Float() float64 {
+	f, _ := strconv.ParseFloat(string(value), 64)
+	return f
+}
+
+type OpenOrder struct {
+	OrderCurrency   string     `json:"order_currency"`
+	PaymentCurrency string     `json:"payment_currency"`
+	OrderId         string     `json:"order_id"`
+	OrderDate       flexString `json:"order_date"`
+	Type            string     `json:"type"`
+	Units           flexString `json:"units"`
+	UnitsRemaining  flexString `json:"units_remaining"`
+	Price           flexString `json:"price"`
+}
+
+type Contract struct {
+	TransactionDate flexString `json:"transaction_date"`
+	Price           flexString `json:"price"`
+	Units           flexString `json:"units"`
+	FeeCurrency     string     `json:"fee_currency"`
+	Fee             flexString `json:"fee"`
+	Total           flexString `json:"total"`
+}
+
+type OrderDetail struct {
+	OrderDate       flexString  `json:"order_date"`
+	Type            string      `json:"type"`
+	OrderStatus     string      `json:"order_status"`
+	OrderCurrency   string      `json:"order_currency"`
+	PaymentCurrency string      `json:"payment_currency"`
+	OrderPrice      flexString  `json:"order_price"`
+	OrderQty        flexString  `json:"order_qty"`
+	Contract        []*Contract `json:"contract"`
+}
+
+/*
+ * 잔고 조회 : total_xxx, in_use_xxx, available_xxx 형태의 Map
+ */
+func (client *Client) Balance() (balance map[string]flexString, err error) {
+	response := struct {
+		Data map[string]flexString `json:"data"`
+	}{}
+
+	err = client.private("/info/balance", url.Values{"currency": []string{"ALL"}}, &response)
+	balance = response.Data
+
+	return
+}
+
+/*
+ * 미체결 주문 조회. 주문이 없으면 빈 목록
+ */
+func (client *Client) Orders(orderCurrency string, paymentCurrency string) (orders []*OpenOrder, err error) {
+	response := struct {
+		Data []*OpenOrder `json:"data"`
+	}{}
+
+	err = client.private("/info/orders", url.Values{
+		"order_currency":   []string{orderCurrency},
+		"payment_currency": []string{paymentCurrency},
+		"count":            []string{"1000"}}, &response)
+
+	// 5600 : 거래 진행중인 내역이 존재하지 않습니다.
+	if apiErr, ok := err.(*APIError); ok && apiErr.Status == "5600" {
+		err = nil
+	}
+	orders = response.Data
+
+	return
+}
+
+func (client *Client) OrderDetail(orderId string, orderCurrency string, paymentCurrency string) (detail *OrderDetail, err error) {
+	response := struct {
+		Data *OrderDetail `json:"data"`
+	}{}
+
+	err = client.private("/info/order_detail", url.Values{
+		"order_id":         []string{orderId},
+		"order_currency":   []string{orderCurrency},
+		"payment_currency": []string{paymentCurrency}}, &response)
+	detail = response.Data
+
+	return
+}
+
+/*
+ * 지정가 주문, 주문 번호를 돌려준다.
+ */
+func (client *Client) Place(orderCurrency string, paymentCurrency string, side string, units string, price string) (orderId string, err error) {
+	return client.order("/trade/place", url.Values{
+		"order_currency":   []string{orderCurrency},
+		"payment_currency": []string{paymentCurrency},
+		"type":             []string{side},
+		"units":            []string{units},
+		"price":            []string{price}})
+}
+
+func (client *Client) MarketSell(orderCurrency string, paymentCurrency string, units string) (orderId string, err error) {
+	return client.order("/trade/market_sell", url.Values{
+		"order_currency":   []string{orderCurrency},
+		"payment_currency": []string{paymentCurrency},
+		"units":            []string{units}})
+}
+
+func (client *Client) MarketBuy(orderCurrency string, paymentCurrency string, units string) (orderId string, err error) {
+	return client.order("/trade/market_buy", url.Values{
+		"order_currency":   []string{orderCurrency},
+		"payment_currency": []string{paymentCurrency},
+		"units":            []string{units}})
+}
+
+func (client *Client) Cancel(orderId string, orderCurrency string, paymentCurrency string, side string) (err error) {
+	return client.private("/trade/cancel", url.Values{
+		"order_id":         []string{orderId},
+		"order_currency":   []string{orderCurrency},
+		"payment_currency": []string{paymentCurrency},
+		"type":             []string{side}}, nil)
+}
+
+/*
+ * 일봉 조회 (과거 -> 최신 순)
+ * 각 행 : [시각(ms), 시가, 종가, 고가, 저가, 거래량]
+ */
+func (client *Client) DayCandles(orderCurrency string, paymentCurrency string) (rows [][]flexString, err error) {
+	response := struct {
+		Data [][]flexString `json:"data"`
+	}{}
+
+	err = client.public("/public/candlestick/"+orderCurrency+"_"+paymentCurrency+"/24h", &response)
+	rows = response.Data
+
+	return
+}
+
+type Ticker struct {
+	OpeningPrice     flexString `json:"opening_price"`
+	ClosingPrice     flexString `json:"closing_price"`
+	AccTradeValue24H flexString `json:"acc_trade_value_24H"`
+	UnitsTraded24H   flexString `json:"units_traded_24H"`
+	FluctateRate24H  flexString `json:"fluctate_rate_24H"`
+}
+
+/*
+ * 기준 통화 전체 코인의 현재가 (코인 -> 현재가)
+ * 응답의 data 에 코인별 현재가와 함께 오는 date 값은 뺀다.
+ */
+func (client *Client) Tickers(paymentCurrency string) (tickers map[string]*Ticker, err error) {
+	response := struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}{}
+
+	if err = client.public("/public/ticker/ALL_"+paymentCurrency, &response); err != nil {
+		return
+	}
+
+	tickers = make(map[string]*Ticker)
+	for currency, data := range response.Data {
+		if currency == "date" {
+			continue
+		}
+
+		ticker := new(Ticker)
+		if err = json.Unmarshal(data, ticker); err != nil {
+			return nil, err
+		}
+		tickers[currency] = ticker
+	}
+
+	return
+}
+
+func (client *Client) order(endpoint string, params url.Values) (orderId string, err error) {
+	response := struct {
+		OrderId string `json:"order_id"`
+	}{}
+
+	err = client.private(endpoint, params, &response)
+	orderId = response.OrderId
+
+	return
+}
@@ -0,0 +1,139 @@
+package bithumb
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+ * Bithumb REST API 호출
+ * Private API 는 endpoint + NUL + body + NUL + nonce 를 HMAC-SHA512 로 서명해 Api-Sign 헤더에 넣는다.
+ */
+
+const BaseURL = "https://api.bithumb.com"
+
+const statusOK = "0000"
+
+type Client struct {
+	accessKey  string
+	secretKey  string
+	baseURL    string
+	httpClient *http.Client
+}
+
+type APIError struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	// HTTP 응답 코드 (서버 오류로 본문을 해석할 수 없을 때만 채움)
+	HTTPStatus int `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	if e.HTTPStatus > 0 {
+		return fmt.Sprintf("bithumb api error (%d) : %s", e.HTTPStatus, e.Message)
+	}
+	return fmt.Sprintf("bithumb api error %s : %s", e.Status, e.Message)
+}
+
+/*
+ * 서버 오류, 일시적인 DB 오류(5400), 알 수 없는 오류(5900)는 다시 시도할 수 있다.
+ */
+func (e *APIError) Retryable() bool {
+	return e.HTTPStatus >= http.StatusInternalServerError || e.Status == "5400" || e.Status == "5900"
+}
+
+func NewClient(accessKey string, secretKey string) *Client {
+	return &Client{
+		accessKey:  accessKey,
+		secretKey:  secretKey,
+		baseURL:    BaseURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+/*
+ * 요청을 보낼 Transport 를 바꾼다. (기록된 응답 재생 등)
+ */
+func (client *Client) SetTransport(transport http.RoundTripper) {
+	client.httpClient.Transport = transport
+}
+
+func (client *Client) public(path string, result interface{}) (err error) {
+	request, err := http.NewRequest(http.MethodGet, client.baseURL+path, nil)
+	if err != nil {
+		return
+	}
+
+	return client.do(request, result)
+}
+
+func (client *Client) private(endpoint string, params url.Values, result interface{}) (err error) {
+	params.Set("endpoint", endpoint)
+	body := params.Encode()
+
+	request, err := http.NewRequest(http.MethodPost, client.baseURL+endpoint, strings.NewReader(body))
+	if err != nil {
+		return
+	}
+
+	nonce := strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Api-Key", client.accessKey)
+	request.Header.Set("Api-Nonce", nonce)
+	request.Header.Set("Api-Sign", client.sign(endpoint, body, nonce))
+
+	return client.do(request, result)
+}
+
+func (client *Client) sign(endpoint string, body string, nonce string) string {
+	mac := hmac.New(sha512.New, []byte(client.secretKey))
+	mac.Write([]byte(endpoint + "\x00" + body + "\x00" + nonce))
+
+	return base64.StdEncoding.EncodeToString([]byte(hex.EncodeToString(mac.Sum(nil))))
+}
+
+/*
+ * 응답의 status 가 0000 이 아니면 APIError 를 돌려준다.
+ */
+func (client *Client) do(request *http.Request, result interface{}) (err error) {
+	request.Header.Set("Accept", "application/json")
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return
+	}
+
+	status := new(APIError)
+	if err = json.Unmarshal(body, status); err != nil {
+		if response.StatusCode >= http.StatusInternalServerError {
+			return &APIError{HTTPStatus: response.StatusCode, Message: http.StatusText(response.StatusCode)}
+		}
+		return fmt.Errorf("bithumb 응답 해석 실패 (%d) : %s", response.StatusCode, err.Error())
+	}
+
+	if status.Status != statusOK {
+		return status
+	}
+
+	if result == nil {
+		return
+	}
+
+	return json.Unmarshal(body, result)
+}
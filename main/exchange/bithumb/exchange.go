@@ -0,0 +1,446 @@
+package bithumb
+
+import (
+	"fmt"
+	"github.com/jekeun/upbit-go/types"
+	"raindrop/main/exchange"
+	"raindrop/main/model"
+	"raindrop/main/money"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+ * Bithumb 거래소
+ * 마켓 코드는 Upbit 형식(KRW-BTC)을 그대로 쓰고 내부에서 BTC / KRW 로 나눠 호출한다.
+ * Bithumb 은 주문 Identifier 를 지원하지 않으므로 재시도 중복 방지는 되지 않는다.
+ * 체결 조회, 취소에 마켓과 매수/매도 구분이 필요해 주문 기록(OrderSource)에서 찾는다.
+ */
+type Exchange struct {
+	client  *Client
+	markets []string
+	source  OrderSource
+
+	mutex  sync.Mutex
+	orders map[string]*orderKey
+}
+
+/*
+ * 주문 번호로 마켓, 매수/매도 구분을 알려주는 주문 기록 (state.Store)
+ * 재시작 전에 낸 주문은 메모리에 없으므로 이 기록에서 찾는다.
+ * PendingMarkets : 체결이 끝나지 않은 주문이 있는 마켓 (유니버스로 고른 마켓 등 markets 밖의 미체결 주문 조회)
+ */
+type OrderSource interface {
+	OrderKey(uuid string) (market string, side string, exist bool)
+	PendingMarkets() []string
+}
+
+// 취소, 체결 조회에 필요한 주문 정보
+type orderKey struct {
+	side   string
+	market string
+}
+
+// 주문 수량 소수점 자리수
+const unitsPrecision = 4
+
+/*
+ * markets : 미체결 주문을 조회할 마켓 목록 (Bithumb 은 전체 조회를 지원하지 않음)
+ */
+func New(accessKey string, secretKey string, markets []string) *Exchange {
+	return NewWithClient(NewClient(accessKey, secretKey), markets)
+}
+
+func NewWithClient(client *Client, markets []string) *Exchange {
+	return &Exchange{
+		client:  client,
+		markets: markets,
+		orders:  make(map[string]*orderKey)}
+}
+
+/*
+ * 주문 기록을 설정한다. 설정하지 않으면 이번 실행에서 본 주문과 미체결 주문만 찾을 수 있다.
+ */
+func (ex *Exchange) SetOrderSource(source OrderSource) {
+	ex.source = source
+}
+
+func (ex *Exchange) Name() string {
+	return "bithumb"
+}
+
+/*
+ * KRW-BTC -> BTC, KRW
+ */
+func SplitMarket(market string) (orderCurrency string, paymentCurrency string) {
+	fields := strings.SplitN(market, "-", 2)
+	if len(fields) != 2 {
+		return market, "KRW"
+	}
+
+	return fields[1], fields[0]
+}
+
+/*
+ * BTC, KRW -> KRW-BTC
+ */
+func ToMarket(orderCurrency string, paymentCurrency string) string {
+	return strings.ToUpper(paymentCurrency) + "-" + strings.ToUpper(orderCurrency)
+}
+
+/*
+ * 잔고 조회
+ * Upbit 과 같이 원화와 보유량이 있는 코인만 돌려준다.
+ */
+func (ex *Exchange) Accounts() (balances []*types.Balance, err error) {
+	balanceMap, err := ex.client.Balance()
+	if err != nil {
+		return
+	}
+
+	currencies := make([]string, 0)
+	for key := range balanceMap {
+		if strings.HasPrefix(key, "total_") {
+			currencies = append(currencies, strings.TrimPrefix(key, "total_"))
+		}
+	}
+	sort.Strings(currencies)
+
+	balances = make([]*types.Balance, 0)
+	for _, currency := range currencies {
+		total := balanceMap["total_"+currency].Float()
+		if currency != "krw" && total <= 0 {
+			continue
+		}
+
+		// Bithumb 은 평균 매수가를 알려주지 않으므로 비워둔다. (봇 주문 체결로 구한다. state.Store.AvgPrice)
+		balance := &types.Balance{
+			Currency: strings.ToUpper(currency),
+			Balance:  string(balanceMap["available_"+currency]),
+			Locked:   string(balanceMap["in_use_"+currency])}
+
+		if currency == "krw" {
+			balances = append([]*types.Balance{balance}, balances...)
+		} else {
+			balances = append(balances, balance)
+		}
+	}
+
+	return
+}
+
+/*
+ * 미체결 주문 조회 (wait 상태만 지원)
+ */
+func (ex *Exchange) OrdersMap(market string, state string, page int, orderBy string) (ordersMap map[string][]*types.Order, err error) {
+	if state != types.ORDERSTATE_WAIT {
+		return nil, fmt.Errorf("bithumb : 지원하지 않는 주문 상태 조회 %s", state)
+	}
+
+	markets := ex.markets
+	if len(market) > 0 {
+		markets = []string{market}
+	} else if ex.source != nil {
+		markets = append([]string{}, ex.markets...)
+		for _, pending := range ex.source.PendingMarkets() {
+			if !contains(markets, pending) {
+				markets = append(markets, pending)
+			}
+		}
+	}
+
+	ordersMap = make(map[string][]*types.Order)
+
+	for _, target := range markets {
+		orderCurrency, paymentCurrency := SplitMarket(target)
+
+		openOrders, ordersErr := ex.client.Orders(orderCurrency, paymentCurrency)
+		if ordersErr != nil {
+			return nil, ordersErr
+		}
+
+		for _, openOrder := range openOrders {
+			order := convertOrder(openOrder)
+			ex.remember(order.Uuid, order.Side, order.Market)
+			ordersMap[order.Side] = append(ordersMap[order.Side], order)
+		}
+	}
+
+	for _, orders := range ordersMap {
+		sort.Slice(orders, func(i, j int) bool {
+			if orderBy == types.ORDERBY_DESC {
+				return orders[i].CreatedAt > orders[j].CreatedAt
+			}
+			return orders[i].CreatedAt < orders[j].CreatedAt
+		})
+	}
+
+	return
+}
+
+/*
+ * 기준 통화의 전체 마켓과 24시간 거래대금
+ * Bithumb 은 유의 종목 정보를 주지 않으므로 Warning 은 항상 false 다.
+ */
+func (ex *Exchange) MarketSummaries(quoteCurrency string) (summaries []*exchange.MarketSummary, err error) {
+	tickers, err := ex.client.Tickers(quoteCurrency)
+	if err != nil {
+		return
+	}
+
+	summaries = make([]*exchange.MarketSummary, 0, len(tickers))
+	for currency, ticker := range tickers {
+		summaries = append(summaries, &exchange.MarketSummary{
+			Market:     ToMarket(currency, quoteCurrency),
+			TradeValue: ticker.AccTradeValue24H.Float()})
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Market < summaries[j].Market
+	})
+
+	return
+}
+
+/*
+ * 일봉 조회 (Upbit 과 같이 최신순)
+ */
+func (ex *Exchange) DayCandles(market string, count int) (candles []*types.DayCandle, err error) {
+	orderCurrency, paymentCurrency := SplitMarket(market)
+
+	rows, err := ex.client.DayCandles(orderCurrency, paymentCurrency)
+	if err != nil {
+		return
+	}
+
+	candles = make([]*types.DayCandle, 0, count)
+	for index := len(rows) - 1; index >= 0 && len(candles) < count; index-- {
+		row := rows[index]
+		if len(row) < 5 {
+			continue
+		}
+
+		candles = append(candles, &types.DayCandle{
+			OpeningPrice: row[1].Float(),
+			TradePrice:   row[2].Float(),
+			HighPrice:    row[3].Float(),
+			LowPrice:     row[4].Float()})
+	}
+
+	return
+}
+
+/*
+ * 체결 내역 조회
+ * 매수 수수료가 코인으로 부과되면 체결가로 환산한다.
+ */
+func (ex *Exchange) OrderFill(uuid string) (fill *model.OrderFill, err error) {
+	key, err := ex.lookup(uuid)
+	if err != nil {
+		return
+	}
+
+	orderCurrency, paymentCurrency := SplitMarket(key.market)
+
+	detail, err := ex.client.OrderDetail(uuid, orderCurrency, paymentCurrency)
+	if err != nil {
+		return
+	}
+
+	fill = &model.OrderFill{
+		Uuid:  uuid,
+		State: convertOrderStatus(detail.OrderStatus)}
+
+	total := 0.0
+	for _, contract := range detail.Contract {
+		units := contract.Units.Float()
+		fee := contract.Fee.Float()
+		if !strings.EqualFold(contract.FeeCurrency, paymentCurrency) {
+			fee *= contract.Price.Float()
+		}
+
+		fill.ExecutedVolume += units
+		fill.PaidFee += fee
+		total += contract.Price.Float() * units
+	}
+
+	if fill.ExecutedVolume > 0 {
+		fill.AvgPrice = total / fill.ExecutedVolume
+	}
+
+	return
+}
+
+func (ex *Exchange) OrderByInfo(orderInfo types.OrderInfo) (order *types.Order, err error) {
+	orderCurrency, paymentCurrency := SplitMarket(orderInfo.Market)
+	units := formatUnits(orderInfo.Volume)
+
+	var orderId string
+	switch {
+	case orderInfo.OrdType == types.ORDERTYPE_LIMIT:
+		orderId, err = ex.client.Place(orderCurrency, paymentCurrency, orderInfo.Side, units, orderInfo.Price)
+	case orderInfo.OrdType == exchange.OrderTypeMarket && orderInfo.Side == types.ORDERSIDE_ASK:
+		orderId, err = ex.client.MarketSell(orderCurrency, paymentCurrency, units)
+	default:
+		err = fmt.Errorf("bithumb : 지원하지 않는 주문 유형 %s %s", orderInfo.Side, orderInfo.OrdType)
+	}
+
+	if err != nil {
+		return
+	}
+
+	ex.remember(orderId, orderInfo.Side, orderInfo.Market)
+
+	order = &types.Order{
+		Uuid:      orderId,
+		Side:      orderInfo.Side,
+		OrdType:   orderInfo.OrdType,
+		Price:     orderInfo.Price,
+		State:     types.ORDERSTATE_WAIT,
+		Market:    orderInfo.Market,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		Volume:    units}
+
+	return
+}
+
+func (ex *Exchange) CancelOrder(uuid string) (order *types.Order, err error) {
+	key, err := ex.lookup(uuid)
+	if err != nil {
+		return
+	}
+
+	orderCurrency, paymentCurrency := SplitMarket(key.market)
+
+	if err = ex.client.Cancel(uuid, orderCurrency, paymentCurrency, key.side); err != nil {
+		return
+	}
+
+	order = &types.Order{
+		Uuid:   uuid,
+		Side:   key.side,
+		Market: key.market,
+		State:  types.ORDERSTATE_CANCEL}
+
+	return
+}
+
+func (ex *Exchange) AskMarketOrder(identifier string, market string, volume string) (*types.Order, error) {
+	return ex.OrderByInfo(types.OrderInfo{
+		Identifier: identifier,
+		Side:       types.ORDERSIDE_ASK,
+		Market:     market,
+		Volume:     volume,
+		OrdType:    exchange.OrderTypeMarket})
+}
+
+func (ex *Exchange) CancelOrderAndAskMarketOrder(identifier string, order *types.Order) (*types.Order, error) {
+	volume, known := exchange.RemainingVolume(order)
+	if !known || volume.Sign() <= 0 {
+		return nil, fmt.Errorf("bithumb : 매도할 남은 수량 없음 %s", order.Uuid)
+	}
+
+	if _, err := ex.CancelOrder(order.Uuid); err != nil {
+		return nil, err
+	}
+
+	return ex.AskMarketOrder(identifier, order.Market, volume.String())
+}
+
+func (ex *Exchange) remember(uuid string, side string, market string) {
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+
+	ex.orders[uuid] = &orderKey{side: side, market: market}
+}
+
+/*
+ * 주문 번호로 마켓, 매수/매도 구분을 찾는다.
+ * 메모리, 주문 기록 순으로 찾고 둘 다 모르는 주문이면 미체결 주문을 다시 조회한다.
+ */
+func (ex *Exchange) lookup(uuid string) (key *orderKey, err error) {
+	ex.mutex.Lock()
+	key, exist := ex.orders[uuid]
+	ex.mutex.Unlock()
+
+	if exist {
+		return
+	}
+
+	if ex.source != nil {
+		if market, side, found := ex.source.OrderKey(uuid); found {
+			ex.remember(uuid, side, market)
+			return &orderKey{side: side, market: market}, nil
+		}
+	}
+
+	if _, err = ex.OrdersMap("", types.ORDERSTATE_WAIT, 1, types.ORDERBY_DESC); err != nil {
+		return
+	}
+
+	ex.mutex.Lock()
+	key, exist = ex.orders[uuid]
+	ex.mutex.Unlock()
+
+	if !exist {
+		err = fmt.Errorf("bithumb : 알 수 없는 주문 %s", uuid)
+	}
+
+	return
+}
+
+func convertOrder(openOrder *OpenOrder) *types.Order {
+	createdAt := ""
+	if microSeconds, err := strconv.ParseInt(string(openOrder.OrderDate), 10, 64); err == nil {
+		createdAt = time.Unix(0, microSeconds*int64(time.Microsecond)).Format(time.RFC3339)
+	}
+
+	// 취소 후 재주문에 사용하도록 Volume 에도 남은 수량을 넣는다.
+	return &types.Order{
+		Uuid:            openOrder.OrderId,
+		Side:            openOrder.Type,
+		OrdType:         types.ORDERTYPE_LIMIT,
+		Price:           string(openOrder.Price),
+		State:           types.ORDERSTATE_WAIT,
+		Market:          ToMarket(openOrder.OrderCurrency, openOrder.PaymentCurrency),
+		CreatedAt:       createdAt,
+		Volume:          string(openOrder.UnitsRemaining),
+		RemainingVolume: string(openOrder.UnitsRemaining)}
+}
+
+func convertOrderStatus(status string) string {
+	switch status {
+	case "Completed":
+		return types.ORDERSTATE_DONE
+	case "Cancel":
+		return types.ORDERSTATE_CANCEL
+	default:
+		return types.ORDERSTATE_WAIT
+	}
+}
+
+/*
+ * Bithumb 은 주문 수량을 소수점 4자리까지 받으므로 버림 처리한다.
+ * float 로 계산하면 0.57 이 0.5699 가 되므로 10진수로 자른다. (이미 맞춘 수량은 그대로)
+ */
+func formatUnits(volume string) string {
+	units, err := money.NewFromString(volume)
+	if err != nil {
+		return volume
+	}
+
+	return units.FloorTo(money.Unit(unitsPrecision)).String()
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
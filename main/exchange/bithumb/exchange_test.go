@@ -0,0 +1,386 @@
+package bithumb
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"math"
+	"path/filepath"
+	"raindrop/main/exchange"
+	"testing"
+)
+
+var testMarkets = []string{"KRW-BTC", "KRW-ETH"}
+
+func newReplayExchange(t *testing.T) *Exchange {
+	t.Helper()
+
+	transport, err := LoadReplayTransport("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient("access", "secret")
+	client.SetTransport(transport)
+
+	return NewWithClient(client, testMarkets)
+}
+
+// 상태 파일 대신 쓰는 주문 기록
+type orderSource map[string][2]string
+
+func (source orderSource) OrderKey(uuid string) (market string, side string, exist bool) {
+	key, exist := source[uuid]
+	return key[0], key[1], exist
+}
+
+func (source orderSource) PendingMarkets() (markets []string) {
+	for _, key := range source {
+		markets = append(markets, key[0])
+	}
+	return
+}
+
+func almostEqual(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestMarketMapping(t *testing.T) {
+	tests := []struct {
+		market          string
+		orderCurrency   string
+		paymentCurrency string
+	}{
+		{"KRW-BTC", "BTC", "KRW"},
+		{"KRW-ETH", "ETH", "KRW"},
+		{"BTC", "BTC", "KRW"},
+	}
+
+	for _, test := range tests {
+		orderCurrency, paymentCurrency := SplitMarket(test.market)
+		if orderCurrency != test.orderCurrency || paymentCurrency != test.paymentCurrency {
+			t.Errorf("SplitMarket(%s) = %s, %s", test.market, orderCurrency, paymentCurrency)
+		}
+	}
+
+	if market := ToMarket("btc", "krw"); market != "KRW-BTC" {
+		t.Errorf("ToMarket(btc, krw) = %s", market)
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	balances, err := newReplayExchange(t).Accounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 원화가 맨 앞, 보유량이 없는 ETH 는 빠진다.
+	tests := []struct {
+		currency string
+		balance  string
+		locked   string
+	}{
+		{"KRW", "1373000", "150000"},
+		{"BTC", "0.01250000", "0.00000000"},
+	}
+
+	if len(balances) != len(tests) {
+		t.Fatalf("balances = %d, want %d", len(balances), len(tests))
+	}
+
+	for index, test := range tests {
+		balance := balances[index]
+		if balance.Currency != test.currency || balance.Balance != test.balance || balance.Locked != test.locked {
+			t.Errorf("balance[%d] = %+v, want %+v", index, balance, test)
+		}
+		if len(balance.AvgBuyPrice) != 0 {
+			t.Errorf("balance[%d] AvgBuyPrice = %q, want empty", index, balance.AvgBuyPrice)
+		}
+	}
+}
+
+func TestOrdersMap(t *testing.T) {
+	ordersMap, err := newReplayExchange(t).OrdersMap("", types.ORDERSTATE_WAIT, 1, types.ORDERBY_DESC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ordersMap[types.ORDERSIDE_ASK]) != 0 {
+		t.Errorf("ask orders = %d, want 0", len(ordersMap[types.ORDERSIDE_ASK]))
+	}
+
+	bids := ordersMap[types.ORDERSIDE_BID]
+	if len(bids) != 1 {
+		t.Fatalf("bid orders = %d, want 1", len(bids))
+	}
+
+	order := bids[0]
+	if order.Uuid != "C0101000001234567890" || order.Market != "KRW-BTC" || order.Price != "90000000" ||
+		order.Volume != "0.0015" || order.State != types.ORDERSTATE_WAIT || order.OrdType != types.ORDERTYPE_LIMIT {
+		t.Errorf("order = %+v", order)
+	}
+
+	if _, err = newReplayExchange(t).OrdersMap("", types.ORDERSTATE_DONE, 1, types.ORDERBY_DESC); err == nil {
+		t.Error("done 상태 조회는 에러여야 함")
+	}
+}
+
+func TestDayCandles(t *testing.T) {
+	tests := []struct {
+		count int
+		want  []types.DayCandle
+	}{
+		{2, []types.DayCandle{
+			{OpeningPrice: 91250000, TradePrice: 91100000, HighPrice: 91400000, LowPrice: 90700000},
+			{OpeningPrice: 89700000, TradePrice: 91250000, HighPrice: 91600000, LowPrice: 89400000}}},
+		{10, nil},
+	}
+
+	for _, test := range tests {
+		candles, err := newReplayExchange(t).DayCandles("KRW-BTC", test.count)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if test.want == nil {
+			if len(candles) != 5 {
+				t.Errorf("count %d : candles = %d, want 5", test.count, len(candles))
+			}
+			continue
+		}
+
+		if len(candles) != len(test.want) {
+			t.Fatalf("count %d : candles = %d, want %d", test.count, len(candles), len(test.want))
+		}
+		for index, want := range test.want {
+			if *candles[index] != want {
+				t.Errorf("count %d : candle[%d] = %+v, want %+v", test.count, index, *candles[index], want)
+			}
+		}
+	}
+}
+
+func TestMarketSummaries(t *testing.T) {
+	summaries, err := newReplayExchange(t).MarketSummaries("KRW")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// date 값은 빠지고 마켓 코드순으로 정렬된다.
+	tests := []exchange.MarketSummary{
+		{Market: "KRW-BTC", TradeValue: 23357346120.5},
+		{Market: "KRW-ETH", TradeValue: 8123400000},
+	}
+
+	if len(summaries) != len(tests) {
+		t.Fatalf("summaries = %d, want %d", len(summaries), len(tests))
+	}
+	for index, test := range tests {
+		if *summaries[index] != test {
+			t.Errorf("summary[%d] = %+v, want %+v", index, *summaries[index], test)
+		}
+	}
+
+	dryRun, err := exchange.NewDryRun(newReplayExchange(t), filepath.Join(t.TempDir(), "decision.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := exchange.MarketListerOf(dryRun); !ok {
+		t.Error("dry-run 으로 감싼 거래소에서 MarketLister 를 찾지 못함")
+	}
+}
+
+func TestOrderByInfo(t *testing.T) {
+	tests := []struct {
+		name   string
+		info   types.OrderInfo
+		uuid   string
+		volume string
+		err    bool
+	}{
+		{"limit bid", types.OrderInfo{Side: types.ORDERSIDE_BID, Market: "KRW-BTC", Price: "91000000", Volume: "0.00209876", OrdType: types.ORDERTYPE_LIMIT},
+			"C0101000001234567891", "0.002", false},
+		{"limit ask", types.OrderInfo{Side: types.ORDERSIDE_ASK, Market: "KRW-BTC", Price: "92000000", Volume: "0.0125", OrdType: types.ORDERTYPE_LIMIT},
+			"C0101000001234567893", "0.0125", false},
+		{"market ask", types.OrderInfo{Side: types.ORDERSIDE_ASK, Market: "KRW-BTC", Volume: "0.01259999", OrdType: exchange.OrderTypeMarket},
+			"C0101000001234567892", "0.0125", false},
+		{"market bid", types.OrderInfo{Side: types.ORDERSIDE_BID, Market: "KRW-BTC", Price: "100000", OrdType: exchange.OrderTypeMarket},
+			"", "", true},
+	}
+
+	for _, test := range tests {
+		order, err := newReplayExchange(t).OrderByInfo(test.info)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s : 에러여야 함", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s : %s", test.name, err.Error())
+			continue
+		}
+
+		if order.Uuid != test.uuid || order.Volume != test.volume || order.Market != test.info.Market ||
+			order.Side != test.info.Side || order.State != types.ORDERSTATE_WAIT {
+			t.Errorf("%s : order = %+v", test.name, order)
+		}
+	}
+}
+
+func TestFormatUnits(t *testing.T) {
+	tests := []struct {
+		volume string
+		want   string
+	}{
+		// float 로 자르면 0.5699, 0.0002 가 되는 값
+		{"0.57", "0.57"},
+		{"0.0003", "0.0003"},
+		{"1.23456", "1.2345"},
+		{"0.00209876", "0.002"},
+		{"0.00009", "0"},
+		{"12", "12"},
+		{"abc", "abc"},
+	}
+
+	for _, test := range tests {
+		if units := formatUnits(test.volume); units != test.want {
+			t.Errorf("formatUnits(%s) = %s, want %s", test.volume, units, test.want)
+		}
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		uuid   string
+		source orderSource
+		err    bool
+	}{
+		// 미체결 주문을 다시 조회해 마켓, 매수/매도 구분을 찾는다.
+		{"open order", "C0101000001234567890", nil, false},
+		{"unknown order", "C0101000009999999999", nil, true},
+		// 거래소에 남아 있지 않은 주문은 Bithumb 이 에러를 돌려준다.
+		{"recorded order", "C0101000001234567891", orderSource{"C0101000001234567891": {"KRW-BTC", types.ORDERSIDE_BID}}, true},
+	}
+
+	for _, test := range tests {
+		ex := newReplayExchange(t)
+		if test.source != nil {
+			ex.SetOrderSource(test.source)
+		}
+
+		order, err := ex.CancelOrder(test.uuid)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s : 에러여야 함", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s : %s", test.name, err.Error())
+			continue
+		}
+
+		if order.Uuid != test.uuid || order.Market != "KRW-BTC" || order.Side != types.ORDERSIDE_BID ||
+			order.State != types.ORDERSTATE_CANCEL {
+			t.Errorf("%s : order = %+v", test.name, order)
+		}
+	}
+}
+
+/*
+ * 부분 체결된 매도 주문은 남은 수량만 시장가로 매도한다.
+ */
+func TestCancelOrderAndAskMarketOrder(t *testing.T) {
+	const uuid = "C0101000001234567894"
+
+	tests := []struct {
+		name   string
+		order  *types.Order
+		volume string
+		err    bool
+	}{
+		{"unfilled", &types.Order{Uuid: uuid, Market: "KRW-BTC", Volume: "0.01", RemainingVolume: "0.01"}, "0.01", false},
+		{"partially filled", &types.Order{Uuid: uuid, Market: "KRW-BTC", Volume: "0.01", RemainingVolume: "0.0042"}, "0.0042", false},
+		{"executed only", &types.Order{Uuid: uuid, Market: "KRW-BTC", Volume: "0.01", ExecutedVolume: "0.007"}, "0.003", false},
+		{"nothing left", &types.Order{Uuid: uuid, Market: "KRW-BTC", Volume: "0.01", RemainingVolume: "0"}, "", true},
+		{"unknown remaining", &types.Order{Uuid: uuid, Market: "KRW-BTC", Volume: "0.01"}, "", true},
+	}
+
+	for _, test := range tests {
+		ex := newReplayExchange(t)
+		ex.SetOrderSource(orderSource{uuid: {"KRW-BTC", types.ORDERSIDE_ASK}})
+
+		order, err := ex.CancelOrderAndAskMarketOrder("id", test.order)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s : 에러여야 함, order = %+v", test.name, order)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s : %s", test.name, err.Error())
+			continue
+		}
+
+		if order.Uuid != "C0101000001234567892" || order.Volume != test.volume || order.OrdType != exchange.OrderTypeMarket {
+			t.Errorf("%s : order = %+v, want volume %s", test.name, order, test.volume)
+		}
+	}
+}
+
+func TestOrderFill(t *testing.T) {
+	const uuid = "C0101000001234567891"
+
+	tests := []struct {
+		name   string
+		source orderSource
+		place  bool
+		err    bool
+	}{
+		// 이번 실행에서 낸 주문
+		{"placed", nil, true, false},
+		// 재시작 후 상태 파일에 기록된 주문
+		{"recorded", orderSource{uuid: {"KRW-BTC", types.ORDERSIDE_BID}}, false, false},
+		// 체결되어 미체결 목록에 없고 기록도 없는 주문
+		{"unknown", nil, false, true},
+	}
+
+	for _, test := range tests {
+		ex := newReplayExchange(t)
+		if test.source != nil {
+			ex.SetOrderSource(test.source)
+		}
+
+		if test.place {
+			if _, err := ex.OrderByInfo(types.OrderInfo{Side: types.ORDERSIDE_BID, Market: "KRW-BTC",
+				Price: "91000000", Volume: "0.002", OrdType: types.ORDERTYPE_LIMIT}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		fill, err := ex.OrderFill(uuid)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s : 에러여야 함", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s : %s", test.name, err.Error())
+			continue
+		}
+
+		if fill.Uuid != uuid || fill.State != types.ORDERSTATE_DONE {
+			t.Errorf("%s : fill = %+v", test.name, fill)
+		}
+		if !almostEqual(fill.ExecutedVolume, 0.002) {
+			t.Errorf("%s : executed volume = %v, want 0.002", test.name, fill.ExecutedVolume)
+		}
+		if !almostEqual(fill.PaidFee, 45.498) {
+			t.Errorf("%s : paid fee = %v, want 45.498", test.name, fill.PaidFee)
+		}
+		if !almostEqual(fill.AvgPrice, 90996000) {
+			t.Errorf("%s : avg price = %v, want 90996000", test.name, fill.AvgPrice)
+		}
+	}
+}
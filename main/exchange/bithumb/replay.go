@@ -0,0 +1,111 @@
+package bithumb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+)
+
+/*
+ * 기록된 HTTP 응답을 재생하는 Transport
+ * 실제 계정 없이 어댑터 동작을 확인할 때 사용한다. (testdata/*.json)
+ * 요청의 method, path 와 params 에 적힌 값이 모두 같으면 해당 응답을 돌려준다.
+ */
+
+type Fixture struct {
+	Method string            `json:"method"`
+	Path   string            `json:"path"`
+	Params map[string]string `json:"params"`
+	Status int               `json:"status"`
+	Body   json.RawMessage   `json:"body"`
+}
+
+type ReplayTransport struct {
+	fixtures []*Fixture
+}
+
+/*
+ * 디렉토리의 json 파일을 읽어 Fixture 목록을 만든다. (파일 이름 순)
+ */
+func LoadReplayTransport(dir string) (transport *ReplayTransport, err error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return
+	}
+	sort.Strings(paths)
+
+	transport = new(ReplayTransport)
+
+	for _, path := range paths {
+		data, readErr := ioutil.ReadFile(path)
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		fixtures := make([]*Fixture, 0)
+		if err = json.Unmarshal(data, &fixtures); err != nil {
+			return nil, fmt.Errorf("%s : %s", path, err.Error())
+		}
+
+		transport.fixtures = append(transport.fixtures, fixtures...)
+	}
+
+	return
+}
+
+func (transport *ReplayTransport) RoundTrip(request *http.Request) (response *http.Response, err error) {
+	params := request.URL.Query()
+
+	if request.Body != nil {
+		body, readErr := ioutil.ReadAll(request.Body)
+		request.Body.Close()
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		form, parseErr := url.ParseQuery(string(body))
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		for key, values := range form {
+			params[key] = values
+		}
+	}
+
+	for _, fixture := range transport.fixtures {
+		if fixture.match(request.Method, request.URL.Path, params) {
+			status := fixture.Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			return &http.Response{
+				StatusCode: status,
+				Status:     http.StatusText(status),
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       ioutil.NopCloser(bytes.NewReader(fixture.Body)),
+				Request:    request}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("기록된 응답 없음 : %s %s %s", request.Method, request.URL.Path, params.Encode())
+}
+
+func (fixture *Fixture) match(method string, path string, params url.Values) bool {
+	if fixture.Method != method || fixture.Path != path {
+		return false
+	}
+
+	for key, value := range fixture.Params {
+		if params.Get(key) != value {
+			return false
+		}
+	}
+
+	return true
+}
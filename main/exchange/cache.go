@@ -0,0 +1,64 @@
+package exchange
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"sync"
+)
+
+/*
+ * 전략 주기 동안 일봉 조회 결과를 공유하는 거래소
+ * 실매매 전략과 shadow 전략이 같은 주기에 같은 시세를 보도록 하고, 같은 캔들을 다시 조회하지 않는다.
+ * 주기가 시작될 때 Reset 으로 비운다.
+ */
+type CandleCache struct {
+	Exchange
+
+	mutex   sync.Mutex
+	candles map[string][]*types.DayCandle
+}
+
+func NewCandleCache(live Exchange) *CandleCache {
+	return &CandleCache{
+		Exchange: live,
+		candles:  make(map[string][]*types.DayCandle)}
+}
+
+/*
+ * 실제 거래소
+ */
+func (ex *CandleCache) Unwrap() Exchange {
+	return ex.Exchange
+}
+
+func (ex *CandleCache) Reset() {
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+
+	ex.candles = make(map[string][]*types.DayCandle)
+}
+
+/*
+ * 이번 주기에 count 개 이상 조회한 적이 있으면 그 결과의 최근 count 개를 돌려준다.
+ */
+func (ex *CandleCache) DayCandles(market string, count int) ([]*types.DayCandle, error) {
+	ex.mutex.Lock()
+	cached, exist := ex.candles[market]
+	ex.mutex.Unlock()
+
+	if exist && len(cached) >= count {
+		return cached[:count], nil
+	}
+
+	candles, err := ex.Exchange.DayCandles(market, count)
+	if err != nil {
+		return nil, err
+	}
+
+	ex.mutex.Lock()
+	if len(candles) > len(ex.candles[market]) {
+		ex.candles[market] = candles
+	}
+	ex.mutex.Unlock()
+
+	return candles, nil
+}
@@ -0,0 +1,222 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jekeun/upbit-go/types"
+	"os"
+	"path/filepath"
+	"raindrop/main/model"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * Dry-run 거래소
+ * 잔고, 주문 조회, 캔들은 실제 거래소를 그대로 사용하고
+ * 주문/취소 요청은 거래소로 보내지 않고 decision log 에 JSON 한 줄씩 기록한다.
+ * 가상 지정가 주문은 취소할 때까지 미체결 주문으로 돌려줘 같은 주문을 주기마다 다시 내지 않게 한다. (체결은 되지 않음)
+ */
+type DryRun struct {
+	Exchange
+
+	mutex    sync.Mutex
+	file     *os.File
+	sequence uint64
+	// 취소되지 않은 가상 지정가 주문
+	pending map[string]*types.Order
+}
+
+type Decision struct {
+	Time     time.Time   `json:"time"`
+	Exchange string      `json:"exchange"`
+	Action   string      `json:"action"`
+	Payload  interface{} `json:"payload"`
+}
+
+const dryRunUuidPrefix = "dryrun-"
+
+func NewDryRun(live Exchange, decisionLogPath string) (ex *DryRun, err error) {
+	if err = os.MkdirAll(filepath.Dir(decisionLogPath), 0755); err != nil {
+		return
+	}
+
+	file, err := os.OpenFile(decisionLogPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return
+	}
+
+	ex = &DryRun{
+		Exchange: live,
+		file:     file,
+		pending:  make(map[string]*types.Order)}
+
+	return
+}
+
+func (ex *DryRun) Name() string {
+	return ex.Exchange.Name() + "(dry-run)"
+}
+
+/*
+ * 실제 거래소
+ */
+func (ex *DryRun) Unwrap() Exchange {
+	return ex.Exchange
+}
+
+/*
+ * 실제 미체결 주문에 취소되지 않은 가상 지정가 주문을 더한다.
+ */
+func (ex *DryRun) OrdersMap(market string, state string, page int, orderBy string) (map[string][]*types.Order, error) {
+	ordersMap, err := ex.Exchange.OrdersMap(market, state, page, orderBy)
+	if err != nil || state != types.ORDERSTATE_WAIT {
+		return ordersMap, err
+	}
+
+	if ordersMap == nil {
+		ordersMap = make(map[string][]*types.Order)
+	}
+
+	ex.mutex.Lock()
+	for _, order := range ex.pending {
+		if len(market) == 0 || order.Market == market {
+			virtual := *order
+			ordersMap[order.Side] = append(ordersMap[order.Side], &virtual)
+		}
+	}
+	ex.mutex.Unlock()
+
+	for _, orders := range ordersMap {
+		sort.SliceStable(orders, func(i, j int) bool {
+			if orderBy == types.ORDERBY_DESC {
+				return orders[i].CreatedAt > orders[j].CreatedAt
+			}
+			return orders[i].CreatedAt < orders[j].CreatedAt
+		})
+	}
+
+	return ordersMap, nil
+}
+
+/*
+ * 가상 주문은 체결되지 않는다. 취소 전의 지정가 주문은 미체결, 그 외는 취소 상태로 돌려준다.
+ */
+func (ex *DryRun) OrderFill(uuid string) (*model.OrderFill, error) {
+	if strings.HasPrefix(uuid, dryRunUuidPrefix) {
+		ex.mutex.Lock()
+		_, pending := ex.pending[uuid]
+		ex.mutex.Unlock()
+
+		if pending {
+			return &model.OrderFill{Uuid: uuid, State: types.ORDERSTATE_WAIT}, nil
+		}
+		return &model.OrderFill{Uuid: uuid, State: types.ORDERSTATE_CANCEL}, nil
+	}
+
+	return ex.Exchange.OrderFill(uuid)
+}
+
+func (ex *DryRun) OrderByInfo(orderInfo types.OrderInfo) (*types.Order, error) {
+	ex.record("order", orderInfo)
+
+	order := ex.virtualOrder(orderInfo.Side, orderInfo.Market, orderInfo.Price, orderInfo.Volume, orderInfo.OrdType)
+
+	if order.OrdType == types.ORDERTYPE_LIMIT {
+		pending := *order
+		pending.RemainingVolume = order.Volume
+
+		ex.mutex.Lock()
+		ex.pending[order.Uuid] = &pending
+		ex.mutex.Unlock()
+	}
+
+	return order, nil
+}
+
+func (ex *DryRun) CancelOrder(uuid string) (*types.Order, error) {
+	ex.record("cancel", map[string]string{"uuid": uuid})
+
+	order := &types.Order{Uuid: uuid, State: types.ORDERSTATE_CANCEL}
+
+	ex.mutex.Lock()
+	if pending, exist := ex.pending[uuid]; exist {
+		order.Side = pending.Side
+		order.Market = pending.Market
+		delete(ex.pending, uuid)
+	}
+	ex.mutex.Unlock()
+
+	return order, nil
+}
+
+func (ex *DryRun) AskMarketOrder(identifier string, market string, volume string) (*types.Order, error) {
+	ex.record("ask_market", types.OrderInfo{
+		Identifier: identifier,
+		Side:       types.ORDERSIDE_ASK,
+		Market:     market,
+		Volume:     volume,
+		OrdType:    OrderTypeMarket})
+
+	return ex.virtualOrder(types.ORDERSIDE_ASK, market, "", volume, OrderTypeMarket), nil
+}
+
+func (ex *DryRun) CancelOrderAndAskMarketOrder(identifier string, order *types.Order) (*types.Order, error) {
+	volume, known := RemainingVolume(order)
+	if !known || volume.Sign() <= 0 {
+		return nil, fmt.Errorf("매도할 남은 수량 없음 : %s", order.Uuid)
+	}
+
+	ex.record("cancel_and_ask_market", map[string]interface{}{
+		"cancel": order,
+		"order": types.OrderInfo{
+			Identifier: identifier,
+			Side:       types.ORDERSIDE_ASK,
+			Market:     order.Market,
+			Volume:     volume.String(),
+			OrdType:    OrderTypeMarket}})
+
+	ex.mutex.Lock()
+	delete(ex.pending, order.Uuid)
+	ex.mutex.Unlock()
+
+	return ex.virtualOrder(types.ORDERSIDE_ASK, order.Market, "", volume.String(), OrderTypeMarket), nil
+}
+
+func (ex *DryRun) record(action string, payload interface{}) {
+	decision := Decision{
+		Time:     time.Now(),
+		Exchange: ex.Exchange.Name(),
+		Action:   action,
+		Payload:  payload}
+
+	data, err := json.Marshal(decision)
+	if err != nil {
+		data = []byte(fmt.Sprintf(`{"action":%q,"error":%q}`, action, err.Error()))
+	}
+
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+
+	ex.file.Write(append(data, '\n'))
+}
+
+/*
+ * 접수된 것처럼 보이는 가상 주문
+ */
+func (ex *DryRun) virtualOrder(side string, market string, price string, volume string, ordType string) *types.Order {
+	sequence := atomic.AddUint64(&ex.sequence, 1)
+
+	return &types.Order{
+		Uuid:      fmt.Sprintf("%s%d-%d", dryRunUuidPrefix, time.Now().UnixNano(), sequence),
+		Side:      side,
+		OrdType:   ordType,
+		Price:     price,
+		State:     "wait",
+		Market:    market,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		Volume:    volume}
+}
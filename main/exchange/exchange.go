@@ -0,0 +1,195 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jekeun/upbit-go/types"
+	"net"
+	"raindrop/main/model"
+	"raindrop/main/money"
+	"time"
+)
+
+/*
+ * 전략이 사용하는 거래소 기능
+ * 잔고, 주문, 캔들 정보는 upbit-go 타입을 공통 형식으로 사용한다.
+ */
+type Exchange interface {
+	Name() string
+
+	Accounts() ([]*types.Balance, error)
+	OrdersMap(market string, state string, page int, orderBy string) (map[string][]*types.Order, error)
+	DayCandles(market string, count int) ([]*types.DayCandle, error)
+	OrderFill(uuid string) (*model.OrderFill, error)
+
+	OrderByInfo(orderInfo types.OrderInfo) (*types.Order, error)
+	CancelOrder(uuid string) (*types.Order, error)
+	AskMarketOrder(identifier string, market string, volume string) (*types.Order, error)
+	CancelOrderAndAskMarketOrder(identifier string, order *types.Order) (*types.Order, error)
+}
+
+const OrderTypeMarket = "market"
+
+/*
+ * 미체결 주문의 남은 수량 : 부분 체결된 주문을 취소하고 다시 낼 때 주문 수량 대신 사용한다.
+ * 남은 수량이 없으면 주문 수량에서 체결 수량을 빼고, 체결 수량도 모르면 known 이 false (주문 수량은 이미 체결된 수량을 포함할 수 있다)
+ */
+func RemainingVolume(order *types.Order) (volume money.Decimal, known bool) {
+	if order == nil {
+		return money.Zero, false
+	}
+
+	if remaining, err := money.NewFromString(order.RemainingVolume); err == nil {
+		return remaining, true
+	}
+
+	total, totalErr := money.NewFromString(order.Volume)
+	executed, executedErr := money.NewFromString(order.ExecutedVolume)
+	if totalErr != nil || executedErr != nil {
+		return money.Zero, false
+	}
+
+	volume = total.Sub(executed)
+	if volume.Sign() < 0 {
+		volume = money.Zero
+	}
+
+	return volume, true
+}
+
+// 캔들 조회 API 요청 간격
+const candleRequestInterval = 100 * time.Millisecond
+
+/*
+ * 코인별 일봉 목록을 가져온다. (최신순)
+ * 조회에 실패한 코인은 결과에서 빠진다.
+ */
+func GetDayCandlesByCoins(ex Exchange, coins []string, count int) (candleMap map[string][]*types.DayCandle) {
+	candleMap = make(map[string][]*types.DayCandle)
+
+	for index, coin := range coins {
+		if index > 0 {
+			time.Sleep(candleRequestInterval)
+		}
+
+		candles, err := ex.DayCandles(coin, count)
+		if err != nil || len(candles) == 0 {
+			continue
+		}
+
+		candleMap[coin] = candles
+	}
+
+	return
+}
+
+/*
+ * 거래소에서 마켓별 수수료율을 조회할 수 있는 경우 구현한다. (비율, 0.0005 = 0.05%)
+ */
+type FeeSource interface {
+	FeeRates(market string) (bidRate float64, askRate float64, err error)
+}
+
+/*
+ * 감싸진 거래소(dry-run 등)까지 확인해 FeeSource 를 찾는다.
+ */
+func FeeSourceOf(ex Exchange) (FeeSource, bool) {
+	for ex != nil {
+		if source, ok := ex.(FeeSource); ok {
+			return source, true
+		}
+
+		wrapper, ok := ex.(interface{ Unwrap() Exchange })
+		if !ok {
+			break
+		}
+		ex = wrapper.Unwrap()
+	}
+
+	return nil, false
+}
+
+/*
+ * 마켓의 24시간 거래대금(기준 통화)과 유의 종목 여부
+ */
+type MarketSummary struct {
+	Market     string
+	TradeValue float64
+	Warning    bool
+}
+
+/*
+ * 기준 통화의 전체 마켓을 조회할 수 있는 거래소가 구현한다. (유니버스 선정에 사용)
+ */
+type MarketLister interface {
+	MarketSummaries(quoteCurrency string) ([]*MarketSummary, error)
+}
+
+/*
+ * 감싸진 거래소(dry-run 등)까지 확인해 MarketLister 를 찾는다.
+ */
+func MarketListerOf(ex Exchange) (MarketLister, bool) {
+	for ex != nil {
+		if lister, ok := ex.(MarketLister); ok {
+			return lister, true
+		}
+
+		wrapper, ok := ex.(interface{ Unwrap() Exchange })
+		if !ok {
+			break
+		}
+		ex = wrapper.Unwrap()
+	}
+
+	return nil, false
+}
+
+/*
+ * 주문 Identifier 로 주문을 조회할 수 있는 거래소가 구현한다.
+ */
+type IdentifierLookup interface {
+	OrderByIdentifier(identifier string) (*types.Order, error)
+}
+
+/*
+ * 감싸진 거래소까지 확인해 Identifier 로 주문을 조회한다.
+ */
+func OrderByIdentifier(ex Exchange, identifier string) (*types.Order, error) {
+	for ex != nil {
+		if lookup, ok := ex.(IdentifierLookup); ok {
+			return lookup.OrderByIdentifier(identifier)
+		}
+
+		wrapper, ok := ex.(interface{ Unwrap() Exchange })
+		if !ok {
+			break
+		}
+		ex = wrapper.Unwrap()
+	}
+
+	return nil, fmt.Errorf("identifier 로 주문을 조회할 수 없는 거래소 : %s", identifier)
+}
+
+/*
+ * 다시 시도해도 되는 주문 에러인지 확인한다.
+ * 네트워크 오류와 거래소가 일시적인 오류로 알려준 경우(서버 오류 등)만 다시 시도한다.
+ */
+func IsRetryable(err error) bool {
+	var apiErr interface{ Retryable() bool }
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+/*
+ * 같은 Identifier 의 주문이 이미 있어 거절된 에러인지 확인한다.
+ * 응답을 받지 못한 주문이 실제로는 들어간 경우 재시도에서 발생한다.
+ */
+func IsDuplicateIdentifier(err error) bool {
+	var apiErr interface{ DuplicateIdentifier() bool }
+
+	return errors.As(err, &apiErr) && apiErr.DuplicateIdentifier()
+}
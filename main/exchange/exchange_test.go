@@ -0,0 +1,174 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jekeun/upbit-go/types"
+	"net"
+	"path/filepath"
+	"raindrop/main/exchange/upbitapi"
+	"raindrop/main/money"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestOrderErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+		duplicate bool
+	}{
+		{"server error", &upbitapi.APIError{StatusCode: 500, Name: "server_error"}, true, false},
+		{"too many requests", &upbitapi.APIError{StatusCode: 429, Name: "too_many_requests"}, true, false},
+		{"insufficient funds", &upbitapi.APIError{StatusCode: 400, Name: "insufficient_funds_bid"}, false, false},
+		{"duplicate identifier", &upbitapi.APIError{StatusCode: 400, Name: "duplicate_identifier"}, false, true},
+		{"wrapped server error", fmt.Errorf("주문 : %w", &upbitapi.APIError{StatusCode: 503}), true, false},
+		{"timeout", &net.DNSError{Err: "timeout", IsTimeout: true}, true, false},
+		{"other", errors.New("unknown"), false, false},
+	}
+
+	for _, test := range tests {
+		if retryable := IsRetryable(test.err); retryable != test.retryable {
+			t.Errorf("%s : IsRetryable = %v, want %v", test.name, retryable, test.retryable)
+		}
+		if duplicate := IsDuplicateIdentifier(test.err); duplicate != test.duplicate {
+			t.Errorf("%s : IsDuplicateIdentifier = %v, want %v", test.name, duplicate, test.duplicate)
+		}
+	}
+}
+
+// 미체결 주문이 하나 있는 실제 거래소
+type liveExchange struct {
+	Exchange
+}
+
+func (ex *liveExchange) Name() string {
+	return "live"
+}
+
+func (ex *liveExchange) OrdersMap(market string, state string, page int, orderBy string) (map[string][]*types.Order, error) {
+	ordersMap := make(map[string][]*types.Order)
+	if len(market) == 0 || market == "KRW-ETH" {
+		ordersMap[types.ORDERSIDE_BID] = []*types.Order{
+			{Uuid: "live-1", Side: types.ORDERSIDE_BID, Market: "KRW-ETH", State: types.ORDERSTATE_WAIT}}
+	}
+
+	return ordersMap, nil
+}
+
+func TestDryRunPendingOrders(t *testing.T) {
+	ex, err := NewDryRun(&liveExchange{}, filepath.Join(t.TempDir(), "decisions.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bid, _ := ex.OrderByInfo(types.OrderInfo{Side: types.ORDERSIDE_BID, Market: "KRW-BTC", Price: "100", Volume: "1", OrdType: types.ORDERTYPE_LIMIT})
+	ask, _ := ex.AskMarketOrder("id", "KRW-XRP", "1")
+
+	tests := []struct {
+		name    string
+		market  string
+		cancel  string
+		bids    []string
+		bidFill string
+	}{
+		// 시장가 주문은 미체결로 남지 않는다.
+		{"all markets", "", "", []string{"live-1", bid.Uuid}, types.ORDERSTATE_WAIT},
+		{"market filter", "KRW-BTC", "", []string{bid.Uuid}, types.ORDERSTATE_WAIT},
+		{"after cancel", "", bid.Uuid, []string{"live-1"}, types.ORDERSTATE_CANCEL},
+	}
+
+	for _, test := range tests {
+		if len(test.cancel) > 0 {
+			if _, err := ex.CancelOrder(test.cancel); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		ordersMap, err := ex.OrdersMap(test.market, types.ORDERSTATE_WAIT, 1, types.ORDERBY_ASC)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		uuids := make([]string, 0)
+		for _, order := range ordersMap[types.ORDERSIDE_BID] {
+			uuids = append(uuids, order.Uuid)
+		}
+		sort.Strings(uuids)
+		sort.Strings(test.bids)
+		if strings.Join(uuids, ",") != strings.Join(test.bids, ",") {
+			t.Errorf("%s : bids = %v, want %v", test.name, uuids, test.bids)
+		}
+		if len(ordersMap[types.ORDERSIDE_ASK]) != 0 {
+			t.Errorf("%s : asks = %d, want 0", test.name, len(ordersMap[types.ORDERSIDE_ASK]))
+		}
+
+		if fill, _ := ex.OrderFill(bid.Uuid); fill.State != test.bidFill {
+			t.Errorf("%s : bid fill state = %s, want %s", test.name, fill.State, test.bidFill)
+		}
+		if fill, _ := ex.OrderFill(ask.Uuid); fill.State != types.ORDERSTATE_CANCEL {
+			t.Errorf("%s : ask fill state = %s, want cancel", test.name, fill.State)
+		}
+	}
+}
+
+func TestRemainingVolume(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  *types.Order
+		volume string
+		known  bool
+	}{
+		{"remaining", &types.Order{Volume: "10", RemainingVolume: "4", ExecutedVolume: "6"}, "4", true},
+		{"executed only", &types.Order{Volume: "10", ExecutedVolume: "2.5"}, "7.5", true},
+		{"over executed", &types.Order{Volume: "1", ExecutedVolume: "2"}, "0", true},
+		// 주문 수량만 있으면 체결 여부를 알 수 없다.
+		{"volume only", &types.Order{Volume: "10"}, "0", false},
+		{"nil", nil, "0", false},
+	}
+
+	for _, test := range tests {
+		volume, known := RemainingVolume(test.order)
+		if known != test.known || volume.Cmp(money.MustParse(test.volume)) != 0 {
+			t.Errorf("%s : volume = %s, known = %t, want %s, %t", test.name, volume, known, test.volume, test.known)
+		}
+	}
+}
+
+func TestDryRunCancelOrderAndAskMarketOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  *types.Order
+		volume string
+		err    bool
+	}{
+		{"unfilled", &types.Order{Uuid: "ask-1", Market: "KRW-XRP", Volume: "10", RemainingVolume: "10"}, "10", false},
+		{"partially filled", &types.Order{Uuid: "ask-2", Market: "KRW-XRP", Volume: "10", RemainingVolume: "3.5"}, "3.5", false},
+		{"nothing left", &types.Order{Uuid: "ask-3", Market: "KRW-XRP", Volume: "10", RemainingVolume: "0"}, "", true},
+	}
+
+	ex, err := NewDryRun(&liveExchange{}, filepath.Join(t.TempDir(), "decisions.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		order, err := ex.CancelOrderAndAskMarketOrder("id", test.order)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s : 에러여야 함, order = %+v", test.name, order)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s : %s", test.name, err.Error())
+			continue
+		}
+
+		if order.Volume != test.volume || order.OrdType != OrderTypeMarket || order.Side != types.ORDERSIDE_ASK {
+			t.Errorf("%s : order = %+v, want volume %s", test.name, order, test.volume)
+		}
+	}
+}
@@ -0,0 +1,649 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jekeun/upbit-go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"raindrop/main/model"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * 가상 지갑 거래소 (shadow 전략용)
+ * 캔들은 실제 거래소를 그대로 사용하고, 잔고와 주문은 파일에 저장하는 가상 지갑에서 처리한다.
+ * 미체결 지정가 주문은 잔고, 주문 조회 때마다 현재가(일봉 종가)와 비교해 주문 가격에 체결한다.
+ * (매수 : 현재가 <= 주문 가격, 매도 : 현재가 >= 주문 가격)
+ * 시장가 매도는 현재가에 슬리피지를 적용해 바로 체결한다.
+ */
+type Paper struct {
+	Exchange
+
+	path     string
+	bidRate  float64
+	askRate  float64
+	slippage float64
+
+	mutex    sync.Mutex
+	sequence uint64
+	wallet   *paperWallet
+}
+
+type paperBalance struct {
+	Balance     float64 `json:"balance"`
+	Locked      float64 `json:"locked"`
+	AvgBuyPrice float64 `json:"avg_buy_price"`
+	// 코인을 마지막으로 매수한 마켓 (평가 금액 계산에 사용, 없으면 원화 마켓)
+	Market string `json:"market,omitempty"`
+}
+
+type paperOrder struct {
+	Uuid           string    `json:"uuid"`
+	Identifier     string    `json:"identifier"`
+	Side           string    `json:"side"`
+	OrdType        string    `json:"ord_type"`
+	Market         string    `json:"market"`
+	Price          float64   `json:"price"`
+	Volume         float64   `json:"volume"`
+	State          string    `json:"state"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExecutedVolume float64   `json:"executed_volume"`
+	AvgPrice       float64   `json:"avg_price"`
+	PaidFee        float64   `json:"paid_fee"`
+	// 매수 주문에 묶어 둔 금액 (주문 금액 + 수수료)
+	LockedAmount float64 `json:"locked_amount"`
+}
+
+type paperWallet struct {
+	InitialCash float64                  `json:"initial_cash"`
+	Balances    map[string]*paperBalance `json:"balances"`
+	Orders      map[string]*paperOrder   `json:"orders"`
+}
+
+const (
+	paperUuidPrefix = "paper-"
+	paperQuote      = "KRW"
+)
+
+/*
+ * walletPath 의 가상 지갑을 읽는다. 파일이 없으면 initialCash 원화로 시작한다.
+ * 수수료, 슬리피지는 설정값(%)을 사용한다.
+ */
+func NewPaper(live Exchange, walletPath string, initialCash float64, feeConfig model.FeeConfig) (ex *Paper, err error) {
+	ex = &Paper{
+		Exchange: live,
+		path:     walletPath,
+		bidRate:  feeConfig.BidRate / 100,
+		askRate:  feeConfig.AskRate / 100,
+		slippage: feeConfig.Slippage / 100,
+		wallet: &paperWallet{
+			InitialCash: initialCash,
+			Balances:    map[string]*paperBalance{paperQuote: {Balance: initialCash}},
+			Orders:      make(map[string]*paperOrder)}}
+
+	// 수수료 설정이 비어 있으면 Upbit 원화 마켓 기본 수수료를 사용한다.
+	if ex.bidRate <= 0 {
+		ex.bidRate = 0.0005
+	}
+	if ex.askRate <= 0 {
+		ex.askRate = 0.0005
+	}
+
+	data, err := ioutil.ReadFile(walletPath)
+	if os.IsNotExist(err) {
+		return ex, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, ex.wallet); err != nil {
+		return nil, err
+	}
+	if ex.wallet.Balances == nil {
+		ex.wallet.Balances = make(map[string]*paperBalance)
+	}
+	if ex.wallet.Orders == nil {
+		ex.wallet.Orders = make(map[string]*paperOrder)
+	}
+
+	return
+}
+
+func (ex *Paper) Name() string {
+	return ex.Exchange.Name() + "(shadow)"
+}
+
+/*
+ * 실제 거래소
+ */
+func (ex *Paper) Unwrap() Exchange {
+	return ex.Exchange
+}
+
+func (ex *Paper) InitialCash() float64 {
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+
+	return ex.wallet.InitialCash
+}
+
+func (ex *Paper) Accounts() (balances []*types.Balance, err error) {
+	if err = ex.match(); err != nil {
+		return
+	}
+
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+
+	currencies := make([]string, 0, len(ex.wallet.Balances))
+	for currency := range ex.wallet.Balances {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	balances = make([]*types.Balance, 0, len(currencies))
+	for _, currency := range currencies {
+		balance := ex.wallet.Balances[currency]
+		if balance.Balance <= 0 && balance.Locked <= 0 && currency != paperQuote {
+			continue
+		}
+
+		balances = append(balances, &types.Balance{
+			Currency:    currency,
+			Balance:     formatPaperFloat(balance.Balance),
+			Locked:      formatPaperFloat(balance.Locked),
+			AvgBuyPrice: formatPaperFloat(balance.AvgBuyPrice)})
+	}
+
+	return
+}
+
+/*
+ * 미체결(wait) 주문만 돌려준다. 체결, 취소된 주문은 OrderFill 로 조회한다.
+ */
+func (ex *Paper) OrdersMap(market string, state string, page int, orderBy string) (ordersMap map[string][]*types.Order, err error) {
+	if err = ex.match(); err != nil {
+		return
+	}
+
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+
+	ordersMap = make(map[string][]*types.Order)
+	if state != types.ORDERSTATE_WAIT {
+		return
+	}
+
+	for _, order := range ex.sortedOrders(orderBy) {
+		if order.State != types.ORDERSTATE_WAIT || (len(market) > 0 && order.Market != market) {
+			continue
+		}
+		ordersMap[order.Side] = append(ordersMap[order.Side], order.toOrder())
+	}
+
+	return
+}
+
+func (ex *Paper) OrderFill(uuid string) (*model.OrderFill, error) {
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+
+	order, exist := ex.wallet.Orders[uuid]
+	if !exist {
+		return nil, fmt.Errorf("가상 주문 없음 : %s", uuid)
+	}
+
+	return &model.OrderFill{
+		Uuid:           order.Uuid,
+		State:          order.State,
+		ExecutedVolume: order.ExecutedVolume,
+		AvgPrice:       order.AvgPrice,
+		PaidFee:        order.PaidFee}, nil
+}
+
+func (ex *Paper) OrderByInfo(orderInfo types.OrderInfo) (*types.Order, error) {
+	volume, err := strconv.ParseFloat(orderInfo.Volume, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	if orderInfo.OrdType == OrderTypeMarket {
+		if orderInfo.Side != types.ORDERSIDE_ASK {
+			return nil, fmt.Errorf("가상 지갑은 시장가 매수를 지원하지 않음")
+		}
+		return ex.AskMarketOrder(orderInfo.Identifier, orderInfo.Market, orderInfo.Volume)
+	}
+
+	price, err := strconv.ParseFloat(orderInfo.Price, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+
+	quoteCurrency, baseCurrency := splitPaperMarket(orderInfo.Market)
+
+	order := ex.newOrder(orderInfo.Identifier, orderInfo.Side, orderInfo.OrdType, orderInfo.Market, price, volume)
+
+	switch orderInfo.Side {
+	case types.ORDERSIDE_BID:
+		amount := price * volume * (1 + ex.bidRate)
+		balance := ex.balance(quoteCurrency)
+		if balance.Balance < amount {
+			return nil, fmt.Errorf("가상 지갑 잔고 부족 : %s %f < %f", quoteCurrency, balance.Balance, amount)
+		}
+		balance.Balance -= amount
+		balance.Locked += amount
+		order.LockedAmount = amount
+	case types.ORDERSIDE_ASK:
+		balance := ex.balance(baseCurrency)
+		if balance.Balance < volume {
+			return nil, fmt.Errorf("가상 지갑 잔고 부족 : %s %f < %f", baseCurrency, balance.Balance, volume)
+		}
+		balance.Balance -= volume
+		balance.Locked += volume
+	default:
+		return nil, fmt.Errorf("알 수 없는 주문 방향 : %s", orderInfo.Side)
+	}
+
+	ex.wallet.Orders[order.Uuid] = order
+
+	return order.toOrder(), ex.saveLocked()
+}
+
+func (ex *Paper) CancelOrder(uuid string) (*types.Order, error) {
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+
+	order, exist := ex.wallet.Orders[uuid]
+	if !exist {
+		return nil, fmt.Errorf("가상 주문 없음 : %s", uuid)
+	}
+
+	ex.cancelLocked(order)
+
+	return order.toOrder(), ex.saveLocked()
+}
+
+func (ex *Paper) AskMarketOrder(identifier string, market string, volumeStr string) (*types.Order, error) {
+	volume, err := strconv.ParseFloat(volumeStr, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := ex.currentPrice(market)
+	if err != nil {
+		return nil, err
+	}
+
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+
+	_, baseCurrency := splitPaperMarket(market)
+	balance := ex.balance(baseCurrency)
+	if balance.Balance < volume {
+		return nil, fmt.Errorf("가상 지갑 잔고 부족 : %s %f < %f", baseCurrency, balance.Balance, volume)
+	}
+	balance.Balance -= volume
+	balance.Locked += volume
+
+	order := ex.newOrder(identifier, types.ORDERSIDE_ASK, OrderTypeMarket, market, 0, volume)
+	ex.wallet.Orders[order.Uuid] = order
+	ex.fillLocked(order, price*(1-ex.slippage))
+
+	return order.toOrder(), ex.saveLocked()
+}
+
+func (ex *Paper) CancelOrderAndAskMarketOrder(identifier string, order *types.Order) (*types.Order, error) {
+	ex.mutex.Lock()
+	paper, exist := ex.wallet.Orders[order.Uuid]
+	if !exist {
+		ex.mutex.Unlock()
+		return nil, fmt.Errorf("가상 주문 없음 : %s", order.Uuid)
+	}
+
+	ex.cancelLocked(paper)
+	remaining := paper.Volume - paper.ExecutedVolume
+	ex.mutex.Unlock()
+
+	return ex.AskMarketOrder(identifier, order.Market, formatPaperFloat(remaining))
+}
+
+/*
+ * 가상 지갑 코인을 평가할 마켓들의 기준 통화 (원화 포함, 정렬)
+ * Equity 에 넘길 원화 환산 비율(quote.Rates)을 구할 때 사용한다.
+ */
+func (ex *Paper) QuoteCurrencies() (currencies []string) {
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+
+	exist := map[string]bool{paperQuote: true}
+	currencies = []string{paperQuote}
+	for currency, balance := range ex.wallet.Balances {
+		if balance.Balance+balance.Locked <= 0 {
+			continue
+		}
+
+		quoteCurrency, _ := splitPaperMarket(balance.market(currency))
+		if !exist[quoteCurrency] {
+			exist[quoteCurrency] = true
+			currencies = append(currencies, quoteCurrency)
+		}
+	}
+	sort.Strings(currencies)
+
+	return
+}
+
+/*
+ * 가상 지갑의 평가 금액 (원화, 미체결 주문에 묶인 금액 포함)
+ * rates : 기준 통화의 원화 환산 비율 (KRW 는 1)
+ * 환산 비율이 있는 통화는 잔고에 비율을 곱하고, 코인은 매수한 마켓의 현재가를 그 마켓 기준 통화의 비율로 환산한다.
+ * 환산 비율이 없어 평가에서 뺀 통화는 missing 으로 돌려준다.
+ */
+func (ex *Paper) Equity(rates map[string]float64) (equity float64, missing []string, err error) {
+	ex.mutex.Lock()
+	holdings := make(map[string]float64)
+	markets := make(map[string]string)
+	for currency, balance := range ex.wallet.Balances {
+		volume := balance.Balance + balance.Locked
+		if volume <= 0 {
+			continue
+		}
+
+		if rate, exist := rates[currency]; exist {
+			equity += volume * rate
+			continue
+		}
+
+		holdings[currency] = volume
+		markets[currency] = balance.market(currency)
+	}
+	ex.mutex.Unlock()
+
+	currencies := make([]string, 0, len(holdings))
+	for currency := range holdings {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	for _, currency := range currencies {
+		quoteCurrency, _ := splitPaperMarket(markets[currency])
+		rate, exist := rates[quoteCurrency]
+		if !exist {
+			missing = append(missing, currency)
+			continue
+		}
+
+		price, priceErr := ex.currentPrice(markets[currency])
+		if priceErr != nil {
+			return 0, nil, priceErr
+		}
+		equity += holdings[currency] * price * rate
+	}
+
+	return
+}
+
+/*
+ * 체결, 취소가 끝났고 synced 가 true 인(상태 파일에 최종 상태가 기록된) 주문을 지갑에서 지운다.
+ * 지갑 파일이 끝난 주문으로 계속 커지지 않도록 체결 동기화(SyncFills) 후에 호출한다.
+ */
+func (ex *Paper) PruneOrders(synced func(uuid string) bool) (pruned int, err error) {
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+
+	for uuid, order := range ex.wallet.Orders {
+		if order.State == types.ORDERSTATE_WAIT || !synced(uuid) {
+			continue
+		}
+
+		delete(ex.wallet.Orders, uuid)
+		pruned++
+	}
+
+	if pruned > 0 {
+		err = ex.saveLocked()
+	}
+
+	return
+}
+
+/*
+ * 미체결 지정가 주문을 현재가와 비교해 체결한다.
+ */
+func (ex *Paper) match() (err error) {
+	ex.mutex.Lock()
+	markets := make(map[string]bool)
+	for _, order := range ex.wallet.Orders {
+		if order.State == types.ORDERSTATE_WAIT {
+			markets[order.Market] = true
+		}
+	}
+	ex.mutex.Unlock()
+
+	if len(markets) == 0 {
+		return
+	}
+
+	prices := make(map[string]float64)
+	for market := range markets {
+		if prices[market], err = ex.currentPrice(market); err != nil {
+			return
+		}
+	}
+
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+
+	changed := false
+	for _, order := range ex.wallet.Orders {
+		price, exist := prices[order.Market]
+		if !exist || order.State != types.ORDERSTATE_WAIT {
+			continue
+		}
+
+		if (order.Side == types.ORDERSIDE_BID && price <= order.Price) ||
+			(order.Side == types.ORDERSIDE_ASK && price >= order.Price) {
+			ex.fillLocked(order, order.Price)
+			changed = true
+		}
+	}
+
+	if changed {
+		err = ex.saveLocked()
+	}
+
+	return
+}
+
+/*
+ * 주문의 남은 수량을 price 에 체결한다.
+ */
+func (ex *Paper) fillLocked(order *paperOrder, price float64) {
+	quoteCurrency, baseCurrency := splitPaperMarket(order.Market)
+	volume := order.Volume - order.ExecutedVolume
+
+	quoteBalance := ex.balance(quoteCurrency)
+	baseBalance := ex.balance(baseCurrency)
+
+	switch order.Side {
+	case types.ORDERSIDE_BID:
+		cost := price * volume
+		fee := cost * ex.bidRate
+
+		// 묶어 둔 금액 중 쓰고 남은 금액은 돌려준다.
+		quoteBalance.Locked -= order.LockedAmount
+		quoteBalance.Balance += order.LockedAmount - cost - fee
+
+		holding := baseBalance.Balance + baseBalance.Locked
+		if holding+volume > 0 {
+			baseBalance.AvgBuyPrice = (baseBalance.AvgBuyPrice*holding + cost) / (holding + volume)
+		}
+		baseBalance.Balance += volume
+		baseBalance.Market = order.Market
+
+		order.PaidFee += fee
+	case types.ORDERSIDE_ASK:
+		proceeds := price * volume
+		fee := proceeds * ex.askRate
+
+		baseBalance.Locked -= volume
+		quoteBalance.Balance += proceeds - fee
+
+		order.PaidFee += fee
+	}
+
+	order.AvgPrice = price
+	order.ExecutedVolume = order.Volume
+	order.State = types.ORDERSTATE_DONE
+}
+
+func (ex *Paper) cancelLocked(order *paperOrder) {
+	if order.State != types.ORDERSTATE_WAIT {
+		return
+	}
+
+	quoteCurrency, baseCurrency := splitPaperMarket(order.Market)
+	switch order.Side {
+	case types.ORDERSIDE_BID:
+		balance := ex.balance(quoteCurrency)
+		balance.Locked -= order.LockedAmount
+		balance.Balance += order.LockedAmount
+	case types.ORDERSIDE_ASK:
+		balance := ex.balance(baseCurrency)
+		remaining := order.Volume - order.ExecutedVolume
+		balance.Locked -= remaining
+		balance.Balance += remaining
+	}
+
+	order.State = types.ORDERSTATE_CANCEL
+}
+
+func (ex *Paper) newOrder(identifier string, side string, ordType string, market string, price float64, volume float64) *paperOrder {
+	sequence := atomic.AddUint64(&ex.sequence, 1)
+
+	return &paperOrder{
+		Uuid:       fmt.Sprintf("%s%d-%d", paperUuidPrefix, time.Now().UnixNano(), sequence),
+		Identifier: identifier,
+		Side:       side,
+		OrdType:    ordType,
+		Market:     market,
+		Price:      price,
+		Volume:     volume,
+		State:      types.ORDERSTATE_WAIT,
+		CreatedAt:  time.Now()}
+}
+
+func (ex *Paper) balance(currency string) *paperBalance {
+	balance, exist := ex.wallet.Balances[currency]
+	if !exist {
+		balance = new(paperBalance)
+		ex.wallet.Balances[currency] = balance
+	}
+
+	return balance
+}
+
+/*
+ * 코인을 평가할 마켓
+ */
+func (balance *paperBalance) market(currency string) string {
+	if len(balance.Market) > 0 {
+		return balance.Market
+	}
+
+	return paperQuote + "-" + currency
+}
+
+func (ex *Paper) sortedOrders(orderBy string) (orders []*paperOrder) {
+	orders = make([]*paperOrder, 0, len(ex.wallet.Orders))
+	for _, order := range ex.wallet.Orders {
+		orders = append(orders, order)
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		if orderBy == types.ORDERBY_ASC {
+			return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+		}
+		return orders[i].CreatedAt.After(orders[j].CreatedAt)
+	})
+
+	return
+}
+
+func (ex *Paper) currentPrice(market string) (price float64, err error) {
+	candles, err := ex.Exchange.DayCandles(market, 1)
+	if err != nil {
+		return
+	}
+	if len(candles) == 0 {
+		return 0, fmt.Errorf("현재가 조회 실패 : %s", market)
+	}
+
+	return candles[0].TradePrice, nil
+}
+
+func (ex *Paper) saveLocked() (err error) {
+	data, err := json.MarshalIndent(ex.wallet, "", "\t")
+	if err != nil {
+		return
+	}
+
+	if dir := filepath.Dir(ex.path); dir != "" {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return
+		}
+	}
+
+	// 저장 도중 종료되어도 기존 파일이 깨지지 않도록 임시 파일에 쓴 후 교체한다.
+	tmpPath := ex.path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return
+	}
+
+	return os.Rename(tmpPath, ex.path)
+}
+
+func (order *paperOrder) toOrder() *types.Order {
+	price := ""
+	if order.Price > 0 {
+		price = formatPaperFloat(order.Price)
+	}
+
+	return &types.Order{
+		Uuid:            order.Uuid,
+		Side:            order.Side,
+		OrdType:         order.OrdType,
+		Price:           price,
+		State:           order.State,
+		Market:          order.Market,
+		CreatedAt:       order.CreatedAt.Format(time.RFC3339),
+		Volume:          formatPaperFloat(order.Volume),
+		RemainingVolume: formatPaperFloat(order.Volume - order.ExecutedVolume),
+		ExecutedVolume:  formatPaperFloat(order.ExecutedVolume)}
+}
+
+/*
+ * 마켓 코드의 기준 통화, 거래 코인 : KRW-BTC -> KRW, BTC
+ */
+func splitPaperMarket(market string) (quoteCurrency string, baseCurrency string) {
+	if index := strings.Index(market, "-"); index > 0 {
+		return market[:index], market[index+1:]
+	}
+
+	return paperQuote, market
+}
+
+func formatPaperFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
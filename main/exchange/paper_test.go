@@ -0,0 +1,155 @@
+package exchange
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"path/filepath"
+	"raindrop/main/model"
+	"testing"
+)
+
+// 마켓별 현재가만 돌려주는 실제 거래소
+type priceExchange struct {
+	Exchange
+	prices map[string]float64
+}
+
+func (ex *priceExchange) Name() string {
+	return "prices"
+}
+
+func (ex *priceExchange) DayCandles(market string, count int) ([]*types.DayCandle, error) {
+	price, exist := ex.prices[market]
+	if !exist {
+		return nil, nil
+	}
+
+	return []*types.DayCandle{{TradePrice: price}}, nil
+}
+
+func newTestPaper(t *testing.T, prices map[string]float64) *Paper {
+	t.Helper()
+
+	paper, err := NewPaper(&priceExchange{prices: prices}, filepath.Join(t.TempDir(), "wallet.json"), 1000000,
+		model.FeeConfig{BidRate: 0.05, AskRate: 0.05})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return paper
+}
+
+func TestPaperEquity(t *testing.T) {
+	prices := map[string]float64{"KRW-BTC": 100000000, "KRW-XRP": 1000, "BTC-XRP": 0.00001}
+
+	tests := []struct {
+		name     string
+		balances map[string]*paperBalance
+		rates    map[string]float64
+		equity   float64
+		missing  []string
+	}{
+		{"krw only", map[string]*paperBalance{"KRW": {Balance: 900000, Locked: 100000}},
+			map[string]float64{"KRW": 1}, 1000000, nil},
+		{"krw market coin", map[string]*paperBalance{"KRW": {Balance: 500000}, "XRP": {Balance: 400, Locked: 100, Market: "KRW-XRP"}},
+			map[string]float64{"KRW": 1}, 1000000, nil},
+		// BTC 마켓 코인은 BTC 가격에 BTC 원화 환산 비율을 곱한다.
+		{"btc market coin", map[string]*paperBalance{"BTC": {Balance: 0.001}, "XRP": {Balance: 1000, Market: "BTC-XRP"}},
+			map[string]float64{"KRW": 1, "BTC": 100000000}, 100000 + 1000000, nil},
+		{"missing rate", map[string]*paperBalance{"KRW": {Balance: 1000}, "XRP": {Balance: 1000, Market: "BTC-XRP"}},
+			map[string]float64{"KRW": 1}, 1000, []string{"XRP"}},
+		// 이전 지갑 파일은 마켓이 없으므로 원화 마켓으로 평가한다.
+		{"legacy balance", map[string]*paperBalance{"XRP": {Balance: 10}},
+			map[string]float64{"KRW": 1}, 10000, nil},
+	}
+
+	for _, test := range tests {
+		paper := newTestPaper(t, prices)
+		paper.wallet.Balances = test.balances
+
+		equity, missing, err := paper.Equity(test.rates)
+		if err != nil {
+			t.Errorf("%s : %s", test.name, err.Error())
+			continue
+		}
+		if equity != test.equity || len(missing) != len(test.missing) {
+			t.Errorf("%s : equity = %v, missing = %v, want %v, %v", test.name, equity, missing, test.equity, test.missing)
+		}
+	}
+}
+
+func TestPaperQuoteCurrencies(t *testing.T) {
+	paper := newTestPaper(t, nil)
+	paper.wallet.Balances["XRP"] = &paperBalance{Balance: 1, Market: "BTC-XRP"}
+	paper.wallet.Balances["ETH"] = &paperBalance{Balance: 1}
+	paper.wallet.Balances["ADA"] = &paperBalance{Market: "USDT-ADA"}
+
+	currencies := paper.QuoteCurrencies()
+	if len(currencies) != 2 || currencies[0] != "BTC" || currencies[1] != "KRW" {
+		t.Errorf("currencies = %v, want [BTC KRW]", currencies)
+	}
+}
+
+func TestPaperPruneOrders(t *testing.T) {
+	paper := newTestPaper(t, map[string]float64{"KRW-XRP": 1000})
+
+	// 체결되는 매수, 미체결 매수, 취소한 매수
+	filled, err := paper.OrderByInfo(types.OrderInfo{Side: types.ORDERSIDE_BID, Market: "KRW-XRP",
+		Price: "1000", Volume: "10", OrdType: types.ORDERTYPE_LIMIT})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waiting, err := paper.OrderByInfo(types.OrderInfo{Side: types.ORDERSIDE_BID, Market: "KRW-XRP",
+		Price: "900", Volume: "10", OrdType: types.ORDERTYPE_LIMIT})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancelled, err := paper.OrderByInfo(types.OrderInfo{Side: types.ORDERSIDE_BID, Market: "KRW-XRP",
+		Price: "800", Volume: "10", OrdType: types.ORDERTYPE_LIMIT})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = paper.CancelOrder(cancelled.Uuid); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = paper.Accounts(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 취소 주문은 아직 상태 파일에 기록되지 않았다.
+	synced := map[string]bool{filled.Uuid: true, waiting.Uuid: true}
+
+	tests := []struct {
+		uuid  string
+		exist bool
+	}{
+		{filled.Uuid, false},
+		{waiting.Uuid, true},
+		{cancelled.Uuid, true},
+	}
+
+	pruned, err := paper.PruneOrders(func(uuid string) bool { return synced[uuid] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 1 {
+		t.Errorf("pruned = %d, want 1", pruned)
+	}
+
+	for _, test := range tests {
+		if _, fillErr := paper.OrderFill(test.uuid); (fillErr == nil) != test.exist {
+			t.Errorf("%s : exist = %t, want %t", test.uuid, fillErr == nil, test.exist)
+		}
+	}
+
+	// 지운 주문의 체결 결과(잔고)는 그대로 남는다.
+	reloaded, err := NewPaper(paper.Exchange, paper.path, 0, model.FeeConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance := reloaded.wallet.Balances["XRP"]; balance == nil || balance.Balance != 10 || balance.Market != "KRW-XRP" {
+		t.Errorf("XRP balance = %+v", balance)
+	}
+	if len(reloaded.wallet.Orders) != 2 {
+		t.Errorf("saved orders = %d, want 2", len(reloaded.wallet.Orders))
+	}
+}
@@ -0,0 +1,173 @@
+package exchange
+
+import (
+	"fmt"
+	"github.com/jekeun/upbit-go"
+	"github.com/jekeun/upbit-go/types"
+	"raindrop/main/exchange/upbitapi"
+	"raindrop/main/model"
+	"strconv"
+	"strings"
+)
+
+/*
+ * Upbit 거래소
+ * 주문, 잔고는 upbit-go 클라이언트를, 체결 조회는 upbitapi 를 사용한다.
+ */
+type Upbit struct {
+	client *upbit.Client
+	api    *upbitapi.Client
+}
+
+func NewUpbit(accessKey string, secretKey string) *Upbit {
+	return &Upbit{
+		client: upbit.NewClient(accessKey, secretKey),
+		api:    upbitapi.NewClient(accessKey, secretKey)}
+}
+
+func (ex *Upbit) Name() string {
+	return "upbit"
+}
+
+func (ex *Upbit) Accounts() ([]*types.Balance, error) {
+	return ex.client.Accounts()
+}
+
+func (ex *Upbit) OrdersMap(market string, state string, page int, orderBy string) (map[string][]*types.Order, error) {
+	return ex.client.OrdersMap(market, state, page, orderBy)
+}
+
+func (ex *Upbit) DayCandles(market string, count int) ([]*types.DayCandle, error) {
+	return ex.client.DayCandles(market, map[string]string{
+		"count": strconv.Itoa(count),
+	})
+}
+
+func (ex *Upbit) OrderFill(uuid string) (fill *model.OrderFill, err error) {
+	order, err := ex.api.Order(uuid)
+	if err != nil {
+		return
+	}
+
+	return order.OrderFill(), nil
+}
+
+/*
+ * 주문은 에러 종류(서버 오류, identifier 중복)를 구분할 수 있도록 upbitapi 로 보낸다.
+ */
+func (ex *Upbit) OrderByInfo(orderInfo types.OrderInfo) (*types.Order, error) {
+	order, err := ex.api.PlaceOrder(orderInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return order.Order(), nil
+}
+
+func (ex *Upbit) OrderByIdentifier(identifier string) (*types.Order, error) {
+	order, err := ex.api.OrderByIdentifier(identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return order.Order(), nil
+}
+
+func (ex *Upbit) CancelOrder(uuid string) (*types.Order, error) {
+	return ex.client.CancelOrder(uuid)
+}
+
+/*
+ * 시장가 매도 : 가격 없이 수량만 지정한다.
+ */
+func (ex *Upbit) AskMarketOrder(identifier string, market string, volume string) (*types.Order, error) {
+	return ex.OrderByInfo(types.OrderInfo{
+		Identifier: identifier,
+		Side:       types.ORDERSIDE_ASK,
+		Market:     market,
+		Volume:     volume,
+		OrdType:    OrderTypeMarket})
+}
+
+/*
+ * 미체결 주문을 취소하고 남은 수량을 시장가로 매도한다.
+ * 취소 응답의 남은 수량은 목록 조회 이후의 체결까지 반영하므로 먼저 사용한다.
+ */
+func (ex *Upbit) CancelOrderAndAskMarketOrder(identifier string, order *types.Order) (*types.Order, error) {
+	cancelled, err := ex.client.CancelOrder(order.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	volume, known := RemainingVolume(cancelled)
+	if !known {
+		volume, known = RemainingVolume(order)
+	}
+	if !known || volume.Sign() <= 0 {
+		return nil, fmt.Errorf("매도할 남은 수량 없음 : %s", order.Uuid)
+	}
+
+	return ex.AskMarketOrder(identifier, order.Market, volume.String())
+}
+
+/*
+ * 주문 가능 정보의 매수/매도 수수료율
+ */
+func (ex *Upbit) FeeRates(market string) (bidRate float64, askRate float64, err error) {
+	chance, err := ex.api.OrderChance(market)
+	if err != nil {
+		return
+	}
+
+	if bidRate, err = strconv.ParseFloat(chance.BidFee, 64); err != nil {
+		return
+	}
+	askRate, err = strconv.ParseFloat(chance.AskFee, 64)
+
+	return
+}
+
+// 한 번에 조회할 현재가 마켓 수
+const tickerBatchSize = 100
+
+/*
+ * 기준 통화의 전체 마켓과 24시간 거래대금
+ */
+func (ex *Upbit) MarketSummaries(quoteCurrency string) (summaries []*MarketSummary, err error) {
+	markets, err := ex.api.Markets()
+	if err != nil {
+		return
+	}
+
+	warnings := make(map[string]bool)
+	marketCodes := make([]string, 0)
+	for _, market := range markets {
+		if !strings.HasPrefix(market.Market, quoteCurrency+"-") {
+			continue
+		}
+		marketCodes = append(marketCodes, market.Market)
+		warnings[market.Market] = market.HasWarning()
+	}
+
+	summaries = make([]*MarketSummary, 0, len(marketCodes))
+	for start := 0; start < len(marketCodes); start += tickerBatchSize {
+		end := start + tickerBatchSize
+		if end > len(marketCodes) {
+			end = len(marketCodes)
+		}
+
+		tickers, tickerErr := ex.api.Tickers(marketCodes[start:end])
+		if tickerErr != nil {
+			return nil, tickerErr
+		}
+
+		for _, ticker := range tickers {
+			summaries = append(summaries, &MarketSummary{
+				Market:     ticker.Market,
+				TradeValue: ticker.AccTradePrice24h,
+				Warning:    warnings[ticker.Market]})
+		}
+	}
+
+	return
+}
@@ -0,0 +1,55 @@
+package upbitapi
+
+import "net/url"
+
+type APIKey struct {
+	AccessKey string `json:"access_key"`
+	ExpireAt  string `json:"expire_at"`
+}
+
+type ChanceAccount struct {
+	Currency    string `json:"currency"`
+	Balance     string `json:"balance"`
+	Locked      string `json:"locked"`
+	AvgBuyPrice string `json:"avg_buy_price"`
+}
+
+type OrderChance struct {
+	BidFee string `json:"bid_fee"`
+	AskFee string `json:"ask_fee"`
+	Market struct {
+		Id         string   `json:"id"`
+		Name       string   `json:"name"`
+		OrderTypes []string `json:"order_types"`
+		OrderSides []string `json:"order_sides"`
+		Bid        struct {
+			Currency string `json:"currency"`
+			MinTotal string `json:"min_total"`
+		} `json:"bid"`
+		Ask struct {
+			Currency string `json:"currency"`
+			MinTotal string `json:"min_total"`
+		} `json:"ask"`
+		MaxTotal string `json:"max_total"`
+		State    string `json:"state"`
+	} `json:"market"`
+	BidAccount ChanceAccount `json:"bid_account"`
+	AskAccount ChanceAccount `json:"ask_account"`
+}
+
+/*
+ * API 키 목록 조회 (인증 확인용)
+ */
+func (client *Client) APIKeys() (keys []*APIKey, err error) {
+	err = client.get("/api_keys", url.Values{}, true, &keys)
+	return
+}
+
+/*
+ * 마켓별 주문 가능 정보 조회 (수수료, 최소 주문 금액)
+ */
+func (client *Client) OrderChance(market string) (chance *OrderChance, err error) {
+	chance = new(OrderChance)
+	err = client.get("/orders/chance", url.Values{"market": []string{market}}, true, chance)
+	return
+}
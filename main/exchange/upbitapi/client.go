@@ -0,0 +1,189 @@
+package upbitapi
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"raindrop/main/utils/ratelimit"
+	"strings"
+	"time"
+)
+
+/*
+ * upbit-go 에서 제공하지 않는 Upbit REST API 호출
+ * 인증이 필요한 API 는 JWT(HS256) 토큰을 만들어 Authorization 헤더에 넣는다.
+ */
+
+const BaseURL = "https://api.upbit.com/v1"
+
+// 요청 수 제한 (시세 API 초당 10회, 거래소 API 초당 8회), 프로세스 전체에서 공유한다.
+var (
+	QuotationLimiter = ratelimit.New(10)
+	ExchangeLimiter  = ratelimit.New(8)
+)
+
+type Client struct {
+	accessKey  string
+	secretKey  string
+	baseURL    string
+	httpClient *http.Client
+}
+
+type APIError struct {
+	StatusCode int
+	Name       string `json:"name"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("upbit api error (%d) %s : %s", e.StatusCode, e.Name, e.Message)
+}
+
+/*
+ * 서버 오류, 요청 수 초과는 다시 시도할 수 있다.
+ */
+func (e *APIError) Retryable() bool {
+	return e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests
+}
+
+/*
+ * 같은 identifier 의 주문이 이미 있어 거절된 경우
+ */
+func (e *APIError) DuplicateIdentifier() bool {
+	return e.StatusCode < http.StatusInternalServerError &&
+		(strings.Contains(strings.ToLower(e.Name), "identifier") || strings.Contains(strings.ToLower(e.Message), "identifier"))
+}
+
+func NewClient(accessKey string, secretKey string) *Client {
+	return &Client{
+		accessKey:  accessKey,
+		secretKey:  secretKey,
+		baseURL:    BaseURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+/*
+ * GET 요청을 보내고 응답 JSON 을 result 에 담는다.
+ */
+func (client *Client) get(path string, query url.Values, auth bool, result interface{}) (err error) {
+	rawQuery := query.Encode()
+
+	requestURL := client.baseURL + path
+	if len(rawQuery) > 0 {
+		requestURL += "?" + rawQuery
+	}
+
+	request, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return
+	}
+
+	return client.do(request, rawQuery, auth, result)
+}
+
+/*
+ * 인증이 필요한 POST 요청을 보낸다. 파라미터는 JSON 본문으로 보내고 쿼리 문자열로 서명한다.
+ */
+func (client *Client) post(path string, params url.Values, result interface{}) (err error) {
+	body := make(map[string]string)
+	for key := range params {
+		body[key] = params.Get(key)
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, client.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	return client.do(request, params.Encode(), true, result)
+}
+
+func (client *Client) do(request *http.Request, rawQuery string, auth bool, result interface{}) (err error) {
+	request.Header.Set("Accept", "application/json")
+
+	if auth {
+		ExchangeLimiter.Wait()
+
+		token, tokenErr := client.token(rawQuery)
+		if tokenErr != nil {
+			return tokenErr
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+	} else {
+		QuotationLimiter.Wait()
+	}
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return
+	}
+
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated {
+		errorBody := struct {
+			Error APIError `json:"error"`
+		}{}
+		_ = json.Unmarshal(body, &errorBody)
+		errorBody.Error.StatusCode = response.StatusCode
+
+		return &errorBody.Error
+	}
+
+	return json.Unmarshal(body, result)
+}
+
+/*
+ * 인증 토큰 생성
+ * 쿼리가 있으면 SHA512 해시를 query_hash 로 넣는다.
+ */
+func (client *Client) token(rawQuery string) (token string, err error) {
+	nonce := make([]byte, 16)
+	if _, err = rand.Read(nonce); err != nil {
+		return
+	}
+
+	payload := map[string]string{
+		"access_key": client.accessKey,
+		"nonce":      hex.EncodeToString(nonce)}
+
+	if len(rawQuery) > 0 {
+		hash := sha512.Sum512([]byte(rawQuery))
+		payload["query_hash"] = hex.EncodeToString(hash[:])
+		payload["query_hash_alg"] = "SHA512"
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	claims, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	mac := hmac.New(sha256.New, []byte(client.secretKey))
+	mac.Write([]byte(unsigned))
+
+	token = unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	return
+}
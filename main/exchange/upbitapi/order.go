@@ -0,0 +1,121 @@
+package upbitapi
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"net/url"
+	"raindrop/main/model"
+	"strconv"
+)
+
+type Trade struct {
+	Market    string `json:"market"`
+	Uuid      string `json:"uuid"`
+	Price     string `json:"price"`
+	Volume    string `json:"volume"`
+	Funds     string `json:"funds"`
+	Side      string `json:"side"`
+	CreatedAt string `json:"created_at"`
+}
+
+type OrderDetail struct {
+	Uuid            string   `json:"uuid"`
+	Side            string   `json:"side"`
+	OrdType         string   `json:"ord_type"`
+	Price           string   `json:"price"`
+	State           string   `json:"state"`
+	Market          string   `json:"market"`
+	CreatedAt       string   `json:"created_at"`
+	Volume          string   `json:"volume"`
+	RemainingVolume string   `json:"remaining_volume"`
+	ExecutedVolume  string   `json:"executed_volume"`
+	PaidFee         string   `json:"paid_fee"`
+	TradesCount     int      `json:"trades_count"`
+	Trades          []*Trade `json:"trades"`
+}
+
+/*
+ * 개별 주문 조회 (체결 내역 포함)
+ */
+func (client *Client) Order(uuid string) (order *OrderDetail, err error) {
+	order = new(OrderDetail)
+	err = client.get("/order", url.Values{"uuid": []string{uuid}}, true, order)
+	return
+}
+
+/*
+ * Identifier 로 주문 조회 (주문 응답을 받지 못했을 때 주문이 들어갔는지 확인)
+ */
+func (client *Client) OrderByIdentifier(identifier string) (order *OrderDetail, err error) {
+	order = new(OrderDetail)
+	err = client.get("/order", url.Values{"identifier": []string{identifier}}, true, order)
+	return
+}
+
+/*
+ * 주문 요청
+ * 시장가 매수(price)는 가격(총액)만, 시장가 매도(market)는 수량만 보낸다.
+ */
+func (client *Client) PlaceOrder(orderInfo types.OrderInfo) (order *OrderDetail, err error) {
+	params := url.Values{
+		"market":   []string{orderInfo.Market},
+		"side":     []string{orderInfo.Side},
+		"ord_type": []string{orderInfo.OrdType}}
+
+	if len(orderInfo.Identifier) > 0 {
+		params.Set("identifier", orderInfo.Identifier)
+	}
+	if len(orderInfo.Price) > 0 {
+		params.Set("price", orderInfo.Price)
+	}
+	if len(orderInfo.Volume) > 0 {
+		params.Set("volume", orderInfo.Volume)
+	}
+
+	order = new(OrderDetail)
+	err = client.post("/orders", params, order)
+	return
+}
+
+/*
+ * upbit-go 주문 형식으로 변환한다.
+ */
+func (order *OrderDetail) Order() *types.Order {
+	return &types.Order{
+		Uuid:            order.Uuid,
+		Side:            order.Side,
+		OrdType:         order.OrdType,
+		Price:           order.Price,
+		State:           order.State,
+		Market:          order.Market,
+		CreatedAt:       order.CreatedAt,
+		Volume:          order.Volume,
+		RemainingVolume: order.RemainingVolume,
+		ExecutedVolume:  order.ExecutedVolume}
+}
+
+/*
+ * 체결 내역으로 평균 체결가를 구해 OrderFill 로 변환한다.
+ */
+func (order *OrderDetail) OrderFill() *model.OrderFill {
+	fill := &model.OrderFill{
+		Uuid:  order.Uuid,
+		State: order.State}
+
+	fill.ExecutedVolume, _ = strconv.ParseFloat(order.ExecutedVolume, 64)
+	fill.PaidFee, _ = strconv.ParseFloat(order.PaidFee, 64)
+
+	funds := 0.0
+	volume := 0.0
+	for _, trade := range order.Trades {
+		tradeFunds, _ := strconv.ParseFloat(trade.Funds, 64)
+		tradeVolume, _ := strconv.ParseFloat(trade.Volume, 64)
+		funds += tradeFunds
+		volume += tradeVolume
+	}
+
+	if volume > 0 {
+		fill.AvgPrice = funds / volume
+	}
+
+	return fill
+}
@@ -0,0 +1,148 @@
+package upbitapi
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+/*
+ * 시세 조회 API (인증 불필요)
+ */
+
+// 캔들 API 1회 호출시 최대 개수
+const MaxCandleCount = 200
+
+type Candle struct {
+	Market               string  `json:"market"`
+	CandleDateTimeUtc    string  `json:"candle_date_time_utc"`
+	CandleDateTimeKst    string  `json:"candle_date_time_kst"`
+	OpeningPrice         float64 `json:"opening_price"`
+	HighPrice            float64 `json:"high_price"`
+	LowPrice             float64 `json:"low_price"`
+	TradePrice           float64 `json:"trade_price"`
+	Timestamp            int64   `json:"timestamp"`
+	CandleAccTradePrice  float64 `json:"candle_acc_trade_price"`
+	CandleAccTradeVolume float64 `json:"candle_acc_trade_volume"`
+}
+
+/*
+ * 일봉 조회 (최신순)
+ * to : 마지막 캔들 시각 (UTC, yyyy-MM-dd'T'HH:mm:ss), 비어 있으면 가장 최근 캔들부터
+ */
+func (client *Client) DayCandles(market string, count int, to string) (candles []*Candle, err error) {
+	err = client.get("/candles/days", candleQuery(market, count, to), false, &candles)
+	return
+}
+
+/*
+ * 주봉 조회 (최신순)
+ */
+func (client *Client) WeekCandles(market string, count int, to string) (candles []*Candle, err error) {
+	err = client.get("/candles/weeks", candleQuery(market, count, to), false, &candles)
+	return
+}
+
+/*
+ * 분봉 조회 (최신순)
+ * unit : 1, 3, 5, 10, 15, 30, 60, 240
+ */
+func (client *Client) MinuteCandles(unit int, market string, count int, to string) (candles []*Candle, err error) {
+	err = client.get("/candles/minutes/"+strconv.Itoa(unit), candleQuery(market, count, to), false, &candles)
+	return
+}
+
+func candleQuery(market string, count int, to string) url.Values {
+	if count <= 0 || count > MaxCandleCount {
+		count = MaxCandleCount
+	}
+
+	query := url.Values{}
+	query.Set("market", market)
+	query.Set("count", strconv.Itoa(count))
+	if len(to) > 0 {
+		query.Set("to", to)
+	}
+
+	return query
+}
+
+type Market struct {
+	Market        string `json:"market"`
+	KoreanName    string `json:"korean_name"`
+	EnglishName   string `json:"english_name"`
+	MarketWarning string `json:"market_warning"`
+	MarketEvent   struct {
+		Warning bool            `json:"warning"`
+		Caution map[string]bool `json:"caution"`
+	} `json:"market_event"`
+}
+
+/*
+ * 유의 종목 지정 또는 주의 항목이 하나라도 있으면 true
+ */
+func (market *Market) HasWarning() bool {
+	if market.MarketWarning == "CAUTION" || market.MarketEvent.Warning {
+		return true
+	}
+
+	for _, caution := range market.MarketEvent.Caution {
+		if caution {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Ticker struct {
+	Market            string  `json:"market"`
+	TradePrice        float64 `json:"trade_price"`
+	OpeningPrice      float64 `json:"opening_price"`
+	HighPrice         float64 `json:"high_price"`
+	LowPrice          float64 `json:"low_price"`
+	PrevClosingPrice  float64 `json:"prev_closing_price"`
+	SignedChangeRate  float64 `json:"signed_change_rate"`
+	AccTradePrice24h  float64 `json:"acc_trade_price_24h"`
+	AccTradeVolume24h float64 `json:"acc_trade_volume_24h"`
+	Timestamp         int64   `json:"timestamp"`
+}
+
+/*
+ * 거래 가능한 전체 마켓 (유의 종목 정보 포함)
+ */
+func (client *Client) Markets() (markets []*Market, err error) {
+	err = client.get("/market/all", url.Values{"isDetails": []string{"true"}}, false, &markets)
+	return
+}
+
+/*
+ * 현재가 조회 (여러 마켓을 한 번에)
+ */
+func (client *Client) Tickers(markets []string) (tickers []*Ticker, err error) {
+	err = client.get("/ticker", url.Values{"markets": []string{strings.Join(markets, ",")}}, false, &tickers)
+	return
+}
+
+type OrderbookUnit struct {
+	AskPrice float64 `json:"ask_price"`
+	BidPrice float64 `json:"bid_price"`
+	AskSize  float64 `json:"ask_size"`
+	BidSize  float64 `json:"bid_size"`
+}
+
+type Orderbook struct {
+	Market         string           `json:"market"`
+	Timestamp      int64            `json:"timestamp"`
+	TotalAskSize   float64          `json:"total_ask_size"`
+	TotalBidSize   float64          `json:"total_bid_size"`
+	OrderbookUnits []*OrderbookUnit `json:"orderbook_units"`
+}
+
+/*
+ * 호가 조회 (여러 마켓을 한 번에)
+ */
+func (client *Client) Orderbooks(markets []string) (orderbooks []*Orderbook, err error) {
+	err = client.get("/orderbook", url.Values{"markets": []string{strings.Join(markets, ",")}}, false, &orderbooks)
+	return
+}
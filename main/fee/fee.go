@@ -0,0 +1,151 @@
+package fee
+
+import (
+	"math"
+	"raindrop/main/exchange"
+	"raindrop/main/model"
+	"raindrop/main/money"
+	"sync"
+	"time"
+)
+
+/*
+ * 수수료, 슬리피지 모델
+ * 주문 수량 계산, 손절/익절 수익률, 손익 리포트, 백테스트에서 같은 값을 사용한다.
+ * 모든 비율은 0.0005 = 0.05% 형태로 가진다.
+ */
+
+// Upbit 원화 마켓 기본 수수료 0.05%
+const DefaultRate = 0.0005
+
+// 거래소 수수료 조회 결과를 다시 조회하기 전까지 사용하는 시간
+const refreshInterval = time.Hour
+
+type Model struct {
+	BidRate     float64 `json:"bid_rate"`
+	AskRate     float64 `json:"ask_rate"`
+	BidSlippage float64 `json:"bid_slippage"`
+	AskSlippage float64 `json:"ask_slippage"`
+}
+
+/*
+ * 설정값(%)으로 모델을 만든다. 수수료가 비어 있으면 기본 수수료를 사용한다.
+ */
+func FromConfig(config model.FeeConfig) *Model {
+	feeModel := &Model{
+		BidRate:     config.BidRate / 100,
+		AskRate:     config.AskRate / 100,
+		BidSlippage: config.Slippage / 100,
+		AskSlippage: config.Slippage / 100}
+
+	if config.BidRate <= 0 {
+		feeModel.BidRate = DefaultRate
+	}
+	if config.AskRate <= 0 {
+		feeModel.AskRate = DefaultRate
+	}
+
+	return feeModel
+}
+
+/*
+ * 슬리피지를 적용한 예상 매수 체결가
+ */
+func (m *Model) EntryPrice(price float64) float64 {
+	return price * (1 + m.BidSlippage)
+}
+
+/*
+ * 슬리피지를 적용한 예상 매도 체결가
+ */
+func (m *Model) ExitPrice(price float64) float64 {
+	return price * (1 - m.AskSlippage)
+}
+
+/*
+ * 수수료를 포함해 amount 를 넘지 않는 매수 수량
+ */
+func (m *Model) BidVolume(amount money.Decimal, price money.Decimal) money.Decimal {
+	return amount.Div(price.Mul(money.NewFromFloat(1 + m.BidRate)))
+}
+
+func (m *Model) BidFee(total float64) float64 {
+	return total * m.BidRate
+}
+
+func (m *Model) AskFee(total float64) float64 {
+	return total * m.AskRate
+}
+
+/*
+ * 수수료, 매도 슬리피지를 뺀 수익률(%)
+ * 매수 원가 = 평균 매수가 * (1 + 매수 수수료), 회수 금액 = 예상 매도가 * (1 - 매도 수수료)
+ */
+func (m *Model) ProfitRate(entryPrice float64, exitPrice float64) float64 {
+	cost := entryPrice * (1 + m.BidRate)
+	if cost <= 0 {
+		return 0.0
+	}
+
+	proceeds := m.ExitPrice(exitPrice) * (1 - m.AskRate)
+
+	return math.Round((proceeds/cost-1)*10000) / 100
+}
+
+/*
+ * 마켓별 수수료 모델 제공
+ * from_exchange 가 켜져 있고 거래소가 지원하면 거래소 수수료를, 아니면 설정값을 사용한다.
+ */
+type Provider struct {
+	exchange exchange.Exchange
+	config   model.FeeConfig
+
+	mutex  sync.Mutex
+	models map[string]*cachedModel
+}
+
+type cachedModel struct {
+	model     *Model
+	fetchedAt time.Time
+}
+
+func NewProvider(ex exchange.Exchange, config model.FeeConfig) *Provider {
+	return &Provider{
+		exchange: ex,
+		config:   config,
+		models:   make(map[string]*cachedModel)}
+}
+
+/*
+ * 마켓의 수수료 모델, 거래소 조회에 실패하면 설정값을 사용한다.
+ */
+func (provider *Provider) For(market string) *Model {
+	feeModel := FromConfig(provider.config)
+
+	if provider.config.FromExchange != 1 {
+		return feeModel
+	}
+
+	source, ok := exchange.FeeSourceOf(provider.exchange)
+	if !ok {
+		return feeModel
+	}
+
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if cached, exist := provider.models[market]; exist && time.Since(cached.fetchedAt) < refreshInterval {
+		return cached.model
+	}
+
+	bidRate, askRate, err := source.FeeRates(market)
+	if err != nil {
+		return feeModel
+	}
+
+	feeModel.BidRate = bidRate
+	feeModel.AskRate = askRate
+	provider.models[market] = &cachedModel{model: feeModel, fetchedAt: time.Now()}
+
+	return feeModel
+}
@@ -0,0 +1,163 @@
+package health
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"raindrop/main/exchange/bithumb"
+	"raindrop/main/exchange/upbitapi"
+	"raindrop/main/model"
+	"time"
+)
+
+/*
+ * 주문 없이 인증 및 API 응답 여부만 확인하는 HealthCheck
+ * 성공하면 heartbeat 파일을 갱신하고 systemd 에 watchdog 신호를 보낸다.
+ * 외부 감시 프로세스는 heartbeat 파일의 갱신 시각으로 봇의 생존 여부를 판단한다.
+ */
+
+const (
+	MethodAPIKeys     = "api_keys"
+	MethodOrderChance = "order_chance"
+	MethodBalance     = "balance"
+
+	defaultMarket   = "KRW-BTC"
+	defaultInterval = 60
+)
+
+type Checker struct {
+	api     *upbitapi.Client
+	bithumb *bithumb.Client
+	logger  *log.Logger
+
+	enable        bool
+	method        string
+	market        string
+	interval      time.Duration
+	heartbeatFile string
+	systemdNotify bool
+	lastCheck     time.Time
+	failCount     int
+	readyNotified bool
+}
+
+func NewChecker(config *model.Config, logger *log.Logger) (checker *Checker) {
+	healthConfig := config.HealthCheck
+
+	checker = &Checker{
+		api:           upbitapi.NewClient(config.Account.Accesskey, config.Account.SecretKey),
+		logger:        logger,
+		enable:        healthConfig.Enable == 1,
+		method:        healthConfig.Method,
+		market:        healthConfig.Market,
+		interval:      time.Duration(healthConfig.IntervalSecond) * time.Second,
+		heartbeatFile: healthConfig.HeartbeatFile,
+		systemdNotify: healthConfig.SystemdNotify == 1}
+
+	// Bithumb 은 잔고 조회로 인증 여부를 확인한다.
+	if config.Exchange == model.ExchangeBithumb {
+		checker.bithumb = bithumb.NewClient(config.Account.Accesskey, config.Account.SecretKey)
+		checker.method = MethodBalance
+	}
+
+	if len(checker.method) == 0 {
+		checker.method = MethodAPIKeys
+	}
+
+	if len(checker.market) == 0 {
+		checker.market = defaultMarket
+	}
+
+	if checker.interval <= 0 {
+		checker.interval = defaultInterval * time.Second
+	}
+
+	return
+}
+
+/*
+ * 설정된 주기가 지났으면 HealthCheck 를 수행한다.
+ */
+func (checker *Checker) Check() (err error) {
+	if !checker.enable {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(checker.lastCheck) < checker.interval {
+		return
+	}
+	checker.lastCheck = now
+
+	err = checker.probe()
+
+	if err != nil {
+		checker.failCount++
+		checker.logger.Printf("HealthCheck 실패 (%d회 연속) : %s\n", checker.failCount, err.Error())
+		checker.notify(fmt.Sprintf("STATUS=health check failed : %s", err.Error()))
+		return
+	}
+
+	if checker.failCount > 0 {
+		checker.logger.Printf("HealthCheck 복구 : %d회 실패 후 성공\n", checker.failCount)
+	}
+	checker.failCount = 0
+
+	if writeErr := checker.writeHeartbeat(now); writeErr != nil {
+		checker.logger.Printf("Heartbeat 파일 기록 실패 : %s\n", writeErr.Error())
+	}
+
+	if !checker.readyNotified {
+		checker.notify("READY=1")
+		checker.readyNotified = true
+	}
+	checker.notify("WATCHDOG=1")
+
+	return
+}
+
+/*
+ * 설정된 방식으로 인증 API 를 호출한다.
+ */
+func (checker *Checker) probe() (err error) {
+	switch checker.method {
+	case MethodBalance:
+		_, err = checker.bithumb.Balance()
+	case MethodOrderChance:
+		_, err = checker.api.OrderChance(checker.market)
+	default:
+		var keys []*upbitapi.APIKey
+		keys, err = checker.api.APIKeys()
+		if err == nil && len(keys) == 0 {
+			err = fmt.Errorf("등록된 API 키가 없음")
+		}
+	}
+
+	return
+}
+
+func (checker *Checker) writeHeartbeat(now time.Time) (err error) {
+	if len(checker.heartbeatFile) == 0 {
+		return
+	}
+
+	if err = os.MkdirAll(filepath.Dir(checker.heartbeatFile), 0755); err != nil {
+		return
+	}
+
+	content := fmt.Sprintf("%s %d %s\n", now.Format(time.RFC3339), os.Getpid(), checker.method)
+
+	return ioutil.WriteFile(checker.heartbeatFile, []byte(content), 0644)
+}
+
+func (checker *Checker) notify(state string) {
+	if !checker.systemdNotify {
+		return
+	}
+
+	if err := sdNotify(state); err != nil {
+		checker.logger.Printf("systemd notify 실패 : %s\n", err.Error())
+	}
+}
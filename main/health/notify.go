@@ -0,0 +1,32 @@
+package health
+
+import (
+	"net"
+	"os"
+)
+
+/*
+ * systemd 의 NOTIFY_SOCKET 으로 상태 메시지를 보낸다.
+ * systemd 밖에서 실행되어 소켓이 없으면 아무것도 하지 않는다.
+ */
+func sdNotify(state string) (err error) {
+	socketPath := os.Getenv("NOTIFY_SOCKET")
+	if len(socketPath) == 0 {
+		return
+	}
+
+	// '@' 로 시작하면 abstract namespace 소켓
+	if socketPath[0] == '@' {
+		socketPath = "\x00" + socketPath[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+
+	return
+}
@@ -0,0 +1,75 @@
+package marketdata
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"raindrop/main/exchange/upbitapi"
+	"sort"
+	"time"
+)
+
+/*
+ * 백테스트 및 데이터 저장에 사용하는 캔들
+ * Time 은 캔들 시작 시각 (UTC)
+ */
+type Candle struct {
+	Market string    `json:"market"`
+	Time   time.Time `json:"time"`
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+	Value  float64   `json:"value"`
+}
+
+const upbitCandleTimeLayout = "2006-01-02T15:04:05"
+
+func FromUpbit(candle *upbitapi.Candle) *Candle {
+	candleTime, _ := time.Parse(upbitCandleTimeLayout, candle.CandleDateTimeUtc)
+
+	return &Candle{
+		Market: candle.Market,
+		Time:   candleTime.UTC(),
+		Open:   candle.OpeningPrice,
+		High:   candle.HighPrice,
+		Low:    candle.LowPrice,
+		Close:  candle.TradePrice,
+		Volume: candle.CandleAccTradeVolume,
+		Value:  candle.CandleAccTradePrice}
+}
+
+/*
+ * 전략 함수에서 사용하는 upbit-go 일봉 형식으로 변환
+ */
+func (candle *Candle) DayCandle() *types.DayCandle {
+	return &types.DayCandle{
+		OpeningPrice: candle.Open,
+		HighPrice:    candle.High,
+		LowPrice:     candle.Low,
+		TradePrice:   candle.Close}
+}
+
+/*
+ * 일봉을 가져와 과거 -> 최신 순으로 돌려준다.
+ */
+func FetchDayCandles(api *upbitapi.Client, market string, count int) (candles []*Candle, err error) {
+	upbitCandles, err := api.DayCandles(market, count, "")
+	if err != nil {
+		return
+	}
+
+	candles = make([]*Candle, 0, len(upbitCandles))
+	for _, upbitCandle := range upbitCandles {
+		candles = append(candles, FromUpbit(upbitCandle))
+	}
+
+	SortByTime(candles)
+
+	return
+}
+
+func SortByTime(candles []*Candle) {
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].Time.Before(candles[j].Time)
+	})
+}
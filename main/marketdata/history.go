@@ -0,0 +1,240 @@
+package marketdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"raindrop/main/exchange/upbitapi"
+	"time"
+)
+
+/*
+ * 과거 캔들 다운로드
+ * 캔들 API 는 1회 최대 200개이므로 to 파라미터로 과거 방향으로 넘겨가며 받아 Storage 에 저장한다.
+ * 받는 구간(cursor ~ until)은 페이지마다 진행 파일에 기록해 중단되어도 이어서 받는다.
+ */
+
+// Upbit 분봉 단위
+var MinuteUnits = []int{1, 3, 5, 10, 15, 30, 60, 240}
+
+const progressDir = ".progress"
+
+/*
+ * 받는 중인 구간 : Cursor 이전 ~ Until 이후의 캔들을 받는다. (Cursor 가 0 이면 가장 최근부터)
+ */
+type progress struct {
+	Cursor time.Time `json:"cursor"`
+	Until  time.Time `json:"until"`
+}
+
+type HistoryFetcher struct {
+	storage *Storage
+	kind    string
+	period  time.Duration
+	fetch   func(market string, to string) ([]*upbitapi.Candle, error)
+
+	// 페이지를 받을 때마다 호출 (진행 상황 출력용, nil 가능)
+	OnPage func(market string, oldest time.Time, saved int)
+}
+
+/*
+ * kind : day, week, minute (minute 이면 unit 필요)
+ */
+func NewHistoryFetcher(api *upbitapi.Client, storage *Storage, kind string, unit int) (fetcher *HistoryFetcher, err error) {
+	fetcher = &HistoryFetcher{storage: storage}
+
+	switch kind {
+	case KindDay:
+		fetcher.kind = KindDay
+		fetcher.period = 24 * time.Hour
+		fetcher.fetch = func(market string, to string) ([]*upbitapi.Candle, error) {
+			return api.DayCandles(market, upbitapi.MaxCandleCount, to)
+		}
+	case KindWeek:
+		fetcher.kind = KindWeek
+		fetcher.period = 7 * 24 * time.Hour
+		fetcher.fetch = func(market string, to string) ([]*upbitapi.Candle, error) {
+			return api.WeekCandles(market, upbitapi.MaxCandleCount, to)
+		}
+	case "minute":
+		if !isMinuteUnit(unit) {
+			return nil, fmt.Errorf("지원하지 않는 분봉 단위 : %d (%v)", unit, MinuteUnits)
+		}
+		fetcher.kind = MinuteKind(unit)
+		fetcher.period = time.Duration(unit) * time.Minute
+		fetcher.fetch = func(market string, to string) ([]*upbitapi.Candle, error) {
+			return api.MinuteCandles(unit, market, upbitapi.MaxCandleCount, to)
+		}
+	default:
+		return nil, fmt.Errorf("지원하지 않는 캔들 종류 : %s", kind)
+	}
+
+	return
+}
+
+func (fetcher *HistoryFetcher) Kind() string {
+	return fetcher.kind
+}
+
+/*
+ * from 이후의 캔들을 받는다. 이미 저장된 구간은 받지 않는다.
+ * 1. 중단된 구간이 있으면 이어서 받는다.
+ * 2. 저장된 가장 최근 캔들 이후 ~ 현재
+ * 3. from ~ 저장된 가장 오래된 캔들 이전
+ */
+func (fetcher *HistoryFetcher) Fetch(market string, from time.Time, now time.Time) (saved int, err error) {
+	if pending, loadErr := fetcher.loadProgress(market); loadErr != nil {
+		return 0, loadErr
+	} else if pending != nil {
+		if saved, err = fetcher.fetchRange(market, pending, now); err != nil {
+			return
+		}
+	}
+
+	stored, err := fetcher.storage.LoadCandles(fetcher.kind, market, from, time.Time{})
+	if err != nil {
+		return
+	}
+
+	ranges := make([]*progress, 0, 2)
+	if len(stored) == 0 {
+		ranges = append(ranges, &progress{Until: from.Add(-time.Nanosecond)})
+	} else {
+		ranges = append(ranges, &progress{Until: stored[len(stored)-1].Time})
+		if oldest := stored[0].Time; oldest.After(from) {
+			ranges = append(ranges, &progress{Cursor: oldest, Until: from.Add(-time.Nanosecond)})
+		}
+	}
+
+	for _, target := range ranges {
+		count, rangeErr := fetcher.fetchRange(market, target, now)
+		saved += count
+		if rangeErr != nil {
+			return saved, rangeErr
+		}
+	}
+
+	return
+}
+
+/*
+ * target 구간을 과거 방향으로 받는다. 페이지마다 저장하고 진행 파일을 갱신한다.
+ * 진행 중인 봉(현재 시각에 끝나지 않은 봉)은 저장하지 않는다.
+ */
+func (fetcher *HistoryFetcher) fetchRange(market string, target *progress, now time.Time) (saved int, err error) {
+	for {
+		if err = fetcher.saveProgress(market, target); err != nil {
+			return
+		}
+
+		to := ""
+		if !target.Cursor.IsZero() {
+			to = target.Cursor.UTC().Format(time.RFC3339)
+		}
+
+		upbitCandles, fetchErr := fetcher.fetch(market, to)
+		if fetchErr != nil {
+			return saved, fetchErr
+		}
+
+		// 상장 이전까지 받았으면 종료
+		if len(upbitCandles) == 0 {
+			break
+		}
+
+		candles := make([]*Candle, 0, len(upbitCandles))
+		oldest := time.Time{}
+		for _, upbitCandle := range upbitCandles {
+			candle := FromUpbit(upbitCandle)
+			if oldest.IsZero() || candle.Time.Before(oldest) {
+				oldest = candle.Time
+			}
+
+			if !candle.Time.After(target.Until) || candle.Time.Add(fetcher.period).After(now) {
+				continue
+			}
+			if !target.Cursor.IsZero() && !candle.Time.Before(target.Cursor) {
+				continue
+			}
+
+			candles = append(candles, candle)
+		}
+
+		if len(candles) > 0 {
+			SortByTime(candles)
+			if err = fetcher.storage.AppendCandles(fetcher.kind, candles); err != nil {
+				return
+			}
+			saved += len(candles)
+		}
+
+		if fetcher.OnPage != nil {
+			fetcher.OnPage(market, oldest, len(candles))
+		}
+
+		if !oldest.After(target.Until) || len(upbitCandles) < upbitapi.MaxCandleCount {
+			break
+		}
+
+		// 같은 커서가 반복되면 더 받을 수 없는 것으로 본다.
+		if !target.Cursor.IsZero() && !oldest.Before(target.Cursor) {
+			break
+		}
+		target.Cursor = oldest
+	}
+
+	return saved, fetcher.removeProgress(market)
+}
+
+func (fetcher *HistoryFetcher) progressPath(market string) string {
+	return filepath.Join(fetcher.storage.Dir(), fetcher.kind, progressDir, market+".json")
+}
+
+func (fetcher *HistoryFetcher) loadProgress(market string) (pending *progress, err error) {
+	data, err := ioutil.ReadFile(fetcher.progressPath(market))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+
+	pending = new(progress)
+	err = json.Unmarshal(data, pending)
+
+	return
+}
+
+func (fetcher *HistoryFetcher) saveProgress(market string, target *progress) (err error) {
+	path := fetcher.progressPath(market)
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+
+	data, err := json.Marshal(target)
+	if err != nil {
+		return
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func (fetcher *HistoryFetcher) removeProgress(market string) error {
+	if err := os.Remove(fetcher.progressPath(market)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func isMinuteUnit(unit int) bool {
+	for _, minuteUnit := range MinuteUnits {
+		if unit == minuteUnit {
+			return true
+		}
+	}
+
+	return false
+}
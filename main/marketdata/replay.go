@@ -0,0 +1,81 @@
+package marketdata
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"sort"
+	"time"
+)
+
+/*
+ * 저장소에 기록된 캔들을 재생하는 데이터 소스
+ * 백테스트, 사후 분석에서 API 대신 사용하며, 시각 at 에서 볼 수 있었던 캔들만 돌려준다.
+ */
+type Replay struct {
+	kind   string
+	series map[string][]*Candle
+}
+
+/*
+ * markets 의 from ~ to 캔들을 읽는다. 기록이 없는 마켓은 빠진다.
+ */
+func NewReplay(storage *Storage, kind string, markets []string, from time.Time, to time.Time) (replay *Replay, err error) {
+	replay = &Replay{
+		kind:   kind,
+		series: make(map[string][]*Candle)}
+
+	for _, market := range markets {
+		candles, loadErr := storage.LoadCandles(kind, market, from, to)
+		if loadErr != nil {
+			return nil, loadErr
+		}
+
+		if len(candles) > 0 {
+			replay.series[market] = candles
+		}
+	}
+
+	return
+}
+
+func (replay *Replay) Kind() string {
+	return replay.kind
+}
+
+/*
+ * 마켓별 전체 캔들 (과거 -> 최신 순)
+ */
+func (replay *Replay) Series() map[string][]*Candle {
+	return replay.series
+}
+
+/*
+ * at 이전에 시작한 캔들 중 최근 count 개 (과거 -> 최신 순)
+ */
+func (replay *Replay) Candles(market string, at time.Time, count int) []*Candle {
+	candles := replay.series[market]
+
+	end := sort.Search(len(candles), func(i int) bool {
+		return candles[i].Time.After(at)
+	})
+
+	start := end - count
+	if start < 0 {
+		start = 0
+	}
+
+	return candles[start:end]
+}
+
+/*
+ * 전략 함수에서 사용하는 최신순 일봉 형식으로 돌려준다.
+ */
+func (replay *Replay) DayCandles(market string, at time.Time, count int) (dayCandles []*types.DayCandle) {
+	candles := replay.Candles(market, at, count)
+
+	dayCandles = make([]*types.DayCandle, 0, len(candles))
+	for index := len(candles) - 1; index >= 0; index-- {
+		dayCandles = append(dayCandles, candles[index].DayCandle())
+	}
+
+	return
+}
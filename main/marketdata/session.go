@@ -0,0 +1,53 @@
+package marketdata
+
+import "time"
+
+/*
+ * 시간봉을 startHour(UTC) 에 시작하는 일 단위 세션 봉으로 묶는다.
+ * 전략의 start_time 을 바꿔 백테스트할 때 사용하며, 처음과 마지막 세션이 중간부터 시작하거나 끝나지 않았으면 뺀다.
+ * hourCandles 는 과거 -> 최신 순
+ */
+func Sessions(hourCandles []*Candle, startHour int) (sessions []*Candle) {
+	sessions = make([]*Candle, 0, len(hourCandles)/24+1)
+	if len(hourCandles) == 0 {
+		return
+	}
+
+	offset := time.Duration(startHour) * time.Hour
+
+	var session *Candle
+	for _, candle := range hourCandles {
+		sessionTime := candle.Time.Add(-offset).Truncate(24 * time.Hour).Add(offset)
+
+		if session == nil || !session.Time.Equal(sessionTime) {
+			session = &Candle{
+				Market: candle.Market,
+				Time:   sessionTime,
+				Open:   candle.Open,
+				High:   candle.High,
+				Low:    candle.Low}
+			sessions = append(sessions, session)
+		}
+
+		if candle.High > session.High {
+			session.High = candle.High
+		}
+		if candle.Low < session.Low {
+			session.Low = candle.Low
+		}
+		session.Close = candle.Close
+		session.Volume += candle.Volume
+		session.Value += candle.Value
+	}
+
+	last := hourCandles[len(hourCandles)-1]
+	if last.Time.Add(time.Hour).Before(session.Time.Add(24 * time.Hour)) {
+		sessions = sessions[:len(sessions)-1]
+	}
+
+	if len(sessions) > 0 && hourCandles[0].Time.After(sessions[0].Time) {
+		sessions = sessions[1:]
+	}
+
+	return
+}
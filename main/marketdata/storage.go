@@ -0,0 +1,362 @@
+package marketdata
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"raindrop/main/exchange/upbitapi"
+	"sort"
+	"strconv"
+	"time"
+)
+
+/*
+ * 시세 데이터 파일 저장소
+ * <dir>/<kind>/<yyyy-mm-dd>/<market>.csv.gz 형식으로 날짜(UTC)별로 나눠 저장한다.
+ * 파일에 이어 쓸 때마다 gzip 멤버를 하나씩 덧붙이며, 읽을 때는 하나의 CSV 로 이어서 읽힌다.
+ */
+
+const (
+	KindDay       = "day"
+	KindWeek      = "week"
+	KindTicker    = "ticker"
+	KindOrderbook = "orderbook"
+
+	partitionLayout = "2006-01-02"
+	fileSuffix      = ".csv.gz"
+)
+
+var candleHeader = []string{"time", "open", "high", "low", "close", "volume", "value"}
+
+var tickerHeader = []string{"time", "trade_price", "opening_price", "high_price", "low_price",
+	"prev_closing_price", "signed_change_rate", "acc_trade_price_24h", "acc_trade_volume_24h"}
+
+/*
+ * 분봉 종류 이름 (예 : minute5)
+ */
+func MinuteKind(unit int) string {
+	return "minute" + strconv.Itoa(unit)
+}
+
+type Storage struct {
+	dir string
+}
+
+func NewStorage(dir string) *Storage {
+	return &Storage{dir: dir}
+}
+
+func (storage *Storage) Dir() string {
+	return storage.dir
+}
+
+func (storage *Storage) path(kind string, day time.Time, market string) string {
+	return filepath.Join(storage.dir, kind, day.UTC().Format(partitionLayout), market+fileSuffix)
+}
+
+/*
+ * 캔들을 날짜별 파일에 이어 쓴다.
+ */
+func (storage *Storage) AppendCandles(kind string, candles []*Candle) (err error) {
+	rowsMap := make(map[string][][]string)
+	for _, candle := range candles {
+		path := storage.path(kind, candle.Time, candle.Market)
+		rowsMap[path] = append(rowsMap[path], []string{
+			candle.Time.UTC().Format(time.RFC3339),
+			formatFloat(candle.Open),
+			formatFloat(candle.High),
+			formatFloat(candle.Low),
+			formatFloat(candle.Close),
+			formatFloat(candle.Volume),
+			formatFloat(candle.Value)})
+	}
+
+	for path, rows := range rowsMap {
+		if err = appendRows(path, candleHeader, rows); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+/*
+ * 현재가를 기록 시각 기준 날짜별 파일에 이어 쓴다.
+ */
+func (storage *Storage) AppendTickers(at time.Time, tickers []*upbitapi.Ticker) (err error) {
+	for _, ticker := range tickers {
+		row := []string{
+			at.UTC().Format(time.RFC3339),
+			formatFloat(ticker.TradePrice),
+			formatFloat(ticker.OpeningPrice),
+			formatFloat(ticker.HighPrice),
+			formatFloat(ticker.LowPrice),
+			formatFloat(ticker.PrevClosingPrice),
+			formatFloat(ticker.SignedChangeRate),
+			formatFloat(ticker.AccTradePrice24h),
+			formatFloat(ticker.AccTradeVolume24h)}
+
+		if err = appendRows(storage.path(KindTicker, at, ticker.Market), tickerHeader, [][]string{row}); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+/*
+ * 호가를 depth 단계까지 기록 시각 기준 날짜별 파일에 이어 쓴다.
+ * 컬럼 : time, total_ask_size, total_bid_size, ask_price_1, ask_size_1, bid_price_1, bid_size_1, ...
+ */
+func (storage *Storage) AppendOrderbooks(at time.Time, orderbooks []*upbitapi.Orderbook, depth int) (err error) {
+	header := []string{"time", "total_ask_size", "total_bid_size"}
+	for level := 1; level <= depth; level++ {
+		header = append(header,
+			fmt.Sprintf("ask_price_%d", level),
+			fmt.Sprintf("ask_size_%d", level),
+			fmt.Sprintf("bid_price_%d", level),
+			fmt.Sprintf("bid_size_%d", level))
+	}
+
+	for _, orderbook := range orderbooks {
+		row := []string{
+			at.UTC().Format(time.RFC3339),
+			formatFloat(orderbook.TotalAskSize),
+			formatFloat(orderbook.TotalBidSize)}
+
+		for level := 0; level < depth; level++ {
+			if level < len(orderbook.OrderbookUnits) {
+				unit := orderbook.OrderbookUnits[level]
+				row = append(row, formatFloat(unit.AskPrice), formatFloat(unit.AskSize),
+					formatFloat(unit.BidPrice), formatFloat(unit.BidSize))
+			} else {
+				row = append(row, "", "", "", "")
+			}
+		}
+
+		if err = appendRows(storage.path(KindOrderbook, at, orderbook.Market), header, [][]string{row}); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+/*
+ * from ~ to (날짜 기준, 포함) 사이에 저장된 캔들을 읽어 과거 -> 최신 순으로 돌려준다.
+ * 같은 시각의 캔들이 여러 번 기록되었으면 마지막 기록을 사용한다.
+ */
+func (storage *Storage) LoadCandles(kind string, market string, from time.Time, to time.Time) (candles []*Candle, err error) {
+	candleMap := make(map[time.Time]*Candle)
+
+	for _, day := range storage.days(kind, from, to) {
+		rows, readErr := readRows(storage.path(kind, day, market))
+		if os.IsNotExist(readErr) {
+			continue
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		for _, row := range rows {
+			candle, parseErr := parseCandle(market, row)
+			if parseErr != nil {
+				return nil, fmt.Errorf("%s : %s", storage.path(kind, day, market), parseErr.Error())
+			}
+			candleMap[candle.Time] = candle
+		}
+	}
+
+	candles = make([]*Candle, 0, len(candleMap))
+	for _, candle := range candleMap {
+		candles = append(candles, candle)
+	}
+
+	SortByTime(candles)
+
+	return
+}
+
+/*
+ * 저장된 캔들 중 가장 최근 시각 (최근 lookbackDays 일 파티션에서 찾는다.)
+ */
+func (storage *Storage) LastCandleTime(kind string, market string, now time.Time, lookbackDays int) (last time.Time) {
+	candles, err := storage.LoadCandles(kind, market, now.AddDate(0, 0, -lookbackDays), now)
+	if err != nil || len(candles) == 0 {
+		return
+	}
+
+	return candles[len(candles)-1].Time
+}
+
+/*
+ * 저장된 날짜 파티션 중 from ~ to 사이의 날짜 (오름차순)
+ * from, to 가 0 이면 제한하지 않는다.
+ */
+func (storage *Storage) days(kind string, from time.Time, to time.Time) (days []time.Time) {
+	days = make([]time.Time, 0)
+
+	entries, err := ioutil.ReadDir(filepath.Join(storage.dir, kind))
+	if err != nil {
+		return
+	}
+
+	fromDay := truncateDay(from)
+	for _, entry := range entries {
+		day, parseErr := time.Parse(partitionLayout, entry.Name())
+		if !entry.IsDir() || parseErr != nil {
+			continue
+		}
+
+		if !from.IsZero() && day.Before(fromDay) {
+			continue
+		}
+		if !to.IsZero() && day.After(to) {
+			continue
+		}
+
+		days = append(days, day)
+	}
+
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Before(days[j])
+	})
+
+	return
+}
+
+/*
+ * 저장된 마켓 목록
+ */
+func (storage *Storage) Markets(kind string) (markets []string) {
+	marketSet := make(map[string]bool)
+
+	for _, day := range storage.days(kind, time.Time{}, time.Time{}) {
+		files, _ := filepath.Glob(filepath.Join(storage.dir, kind, day.Format(partitionLayout), "*"+fileSuffix))
+		for _, file := range files {
+			name := filepath.Base(file)
+			marketSet[name[:len(name)-len(fileSuffix)]] = true
+		}
+	}
+
+	markets = make([]string, 0, len(marketSet))
+	for market := range marketSet {
+		markets = append(markets, market)
+	}
+	sort.Strings(markets)
+
+	return
+}
+
+/*
+ * 파일 끝에 gzip 멤버로 행을 덧붙인다. 새 파일이면 헤더를 먼저 쓴다.
+ */
+func appendRows(path string, header []string, rows [][]string) (err error) {
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+
+	_, statErr := os.Stat(path)
+	newFile := os.IsNotExist(statErr)
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	csvWriter := csv.NewWriter(gzipWriter)
+
+	if newFile {
+		csvWriter.Write(header)
+	}
+	csvWriter.WriteAll(rows)
+
+	if err = csvWriter.Error(); err != nil {
+		return
+	}
+
+	return gzipWriter.Close()
+}
+
+/*
+ * gzip CSV 파일의 헤더를 뺀 행을 읽는다.
+ */
+func readRows(path string) (rows [][]string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return
+	}
+	defer gzipReader.Close()
+
+	csvReader := csv.NewReader(gzipReader)
+	csvReader.FieldsPerRecord = -1
+
+	rows = make([][]string, 0)
+	for {
+		row, readErr := csvReader.Read()
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		if len(row) > 0 && row[0] == "time" {
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	return
+}
+
+func parseCandle(market string, row []string) (candle *Candle, err error) {
+	if len(row) < len(candleHeader) {
+		return nil, fmt.Errorf("캔들 컬럼 수 부족 : %v", row)
+	}
+
+	candleTime, err := time.Parse(time.RFC3339, row[0])
+	if err != nil {
+		return
+	}
+
+	values := make([]float64, len(candleHeader)-1)
+	for index := range values {
+		if values[index], err = strconv.ParseFloat(row[index+1], 64); err != nil {
+			return
+		}
+	}
+
+	candle = &Candle{
+		Market: market,
+		Time:   candleTime.UTC(),
+		Open:   values[0],
+		High:   values[1],
+		Low:    values[2],
+		Close:  values[3],
+		Volume: values[4],
+		Value:  values[5]}
+
+	return
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -4,29 +4,211 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 )
 
+const (
+	DefaultStateFile   = "./state/raindrop.json"
+	DefaultAccountName = "default"
+	defaultStateDir    = "./state"
+
+	ExchangeUpbit   = "upbit"
+	ExchangeBithumb = "bithumb"
+
+	// 전략 이름 (주문 Identifier 의 전략명과 같다)
+	// lw_advance 는 유니버스, 시장 국면, 원화 외 기준 통화 등 lw_basic 의 기능이 없어 shadow 전략으로만 실행한다.
+	StrategyLWBasic   = "lw_basic"
+	StrategyLWAdvance = "lw_advance"
+
+	// 설정 출력시 키 대신 보여줄 값
+	redactedKey = "********"
+)
+
+// 거래대금, 변동폭, 노이즈로 타겟 코인을 고르는 설정
+type UniverseConfig struct {
+	Enable int `json:"enable"`
+	// 기준 통화 (기본 KRW)
+	Quote string `json:"quote"`
+	TopN int `json:"top_n"`
+	RefreshMinute int `json:"refresh_minute"`
+	// 일봉을 조회할 거래대금 상위 후보 수 (기본 top_n * 3)
+	CandidateCount int `json:"candidate_count"`
+	// 평균 변동폭, 노이즈 계산 기간 (일)
+	RangeDays int `json:"range_days"`
+	// 24시간 최소 거래대금
+	MinTradeValue float64 `json:"min_trade_value"`
+	// 최소 평균 변동폭 (%)
+	MinAverageRange float64 `json:"min_average_range"`
+	// 최대 노이즈 비율 (0 ~ 1, 0 이면 적용하지 않음)
+	MaxNoise float64 `json:"max_noise"`
+	// 1 이면 유의 종목도 포함
+	AllowWarning int `json:"allow_warning"`
+	// 항상 포함 / 제외할 마켓
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+}
+
+// 시장 국면 필터 설정
+type RegimeConfig struct {
+	Enable int `json:"enable"`
+	// 기준 마켓 (기본 KRW-BTC)
+	Market string `json:"market"`
+	// 현재가가 N일 이동평균 아래면 약세 (0 이면 적용하지 않음)
+	MovingAverage int `json:"moving_average"`
+	// 기준 마켓 이평 스코어가 이 값보다 작으면 약세 (0 이면 적용하지 않음)
+	MinMalScore float64 `json:"min_mal_score"`
+	// 약세일 때 suppress (매수 중지, 기본) 또는 scale (주문 금액 축소)
+	Action string `json:"action"`
+	// scale 일 때 적용할 주문 금액 비율 (%, 기본 50)
+	ScaleRate float64 `json:"scale_rate"`
+}
+
+// 주문 금액 기준 설정 : 자금관리 비율(money_plan), 이평 스코어는 이 금액에 곱해진다.
+type SizingConfig struct {
+	// fixed (기본, order_amount), equity_percent (평가 금액의 equity_rate %), equity_per_coin (평가 금액 / max_coin)
+	Mode string `json:"mode"`
+	EquityRate float64 `json:"equity_rate"`
+	// 평가 금액 기준 주문 금액의 상한 (원화, 0 이면 제한 없음)
+	MaxOrderAmount float64 `json:"max_order_amount"`
+}
+
+// 포트폴리오 변동성 목표 설정 : 보유 코인과 매수 후보의 변동성, 상관계수로 신규 주문 금액을 줄인다.
+type PortfolioRiskConfig struct {
+	Enable int `json:"enable"`
+	// 평가 금액 대비 목표 일간 변동성 (%, 기본 2)
+	TargetVolatility float64 `json:"target_volatility"`
+	// 수익률 계산 기간 (일, 기본 18)
+	LookbackDays int `json:"lookback_days"`
+}
+
+// 상관계수 진입 제한 설정 : 보유 코인과 같이 움직이는 코인의 신규 매수를 막거나 줄인다.
+type CorrelationConfig struct {
+	Enable int `json:"enable"`
+	// 보유 코인과의 일간 수익률 상관계수가 이 값 이상이면 제한 (기본 0.8)
+	MaxCorrelation float64 `json:"max_correlation"`
+	// refuse (매수 안함, 기본) 또는 shrink (주문 금액 축소)
+	Action string `json:"action"`
+	// shrink 일 때 적용할 주문 금액 비율 (%, 기본 50)
+	ScaleRate float64 `json:"scale_rate"`
+	// 수익률 계산 기간 (일, 기본 18)
+	LookbackDays int `json:"lookback_days"`
+}
+
+type LarryStrategyConfig struct {
+	Enable 	int `json:"enable"`
+	KValue 	float64 	`json:"k_value"`
+	Period  int 	`json:"period"`
+	StartTime int	`json:"start_time"`
+	StopLoss float64	`json:"stop_loss"`
+	MaxProfit float64 	`json:"max_profit"`
+	MinVariability int `json:"min_variability"`
+	OrderAmount float64 `json:"order_amount"`
+	MinOrderAmountRate float64 `json:"min_order_amount_rate"`
+	MaxCoin int `json:"max_coin"`
+	AskPeriodMinute int `json:"ask_period_minute"`
+	AskOrderGap int `json:"ask_order_gap"`
+	MoneyPlan float64 `json:"money_plan"`
+	// 기준 통화별 최대 주문 금액 (예 : {"BTC" : 0.002}), 없으면 order_amount(원화)를 환산해 사용
+	OrderAmounts map[string]float64 `json:"order_amounts"`
+	Targets []string `json:"targets"`
+	// 봇이 매수, 매도하지 않는 코인 (장기 보유 코인 등)
+	ExcludedCurrencies []string `json:"excluded_currencies"`
+	// 설정하면 targets 대신 주기적으로 고른 마켓을 사용한다. (targets 는 선정 실패시 사용)
+	Universe UniverseConfig `json:"universe"`
+	// 기준 마켓(BTC) 추세가 약하면 매수를 막거나 주문 금액을 줄인다.
+	Regime RegimeConfig `json:"regime"`
+	// 계정 평가 금액에 맞춰 주문 금액을 늘리거나 줄인다.
+	Sizing SizingConfig `json:"sizing"`
+	// 매수 후 포트폴리오 일간 변동성이 목표를 넘지 않도록 주문 금액을 줄인다.
+	PortfolioRisk PortfolioRiskConfig `json:"portfolio_risk"`
+	// 보유 코인과 상관계수가 높은 코인의 매수를 막거나 주문 금액을 줄인다.
+	Correlation CorrelationConfig `json:"correlation"`
+}
+
+// 수수료, 슬리피지 설정 (단위 %)
+type FeeConfig struct {
+	BidRate float64 `json:"bid_rate"`
+	AskRate float64 `json:"ask_rate"`
+	// 1 이면 거래소 주문 가능 정보(orders/chance)의 수수료를 사용한다.
+	FromExchange int `json:"from_exchange"`
+	// 시장가 주문, 손익 추정에 적용할 예상 슬리피지
+	Slippage float64 `json:"slippage"`
+}
+
+// 거래소 잔고와 봇 보유 수량 대사 설정
+type ReconcileConfig struct {
+	Enable int `json:"enable"`
+	// 0 이면 매 주기마다 수행
+	IntervalSecond int `json:"interval_second"`
+	// 차이가 있을 때 기본 정책 (adopt : 거래소 잔고를 봇 수량으로 편입, ignore : 알림만)
+	Policy string `json:"policy"`
+	// 코인별 정책 (예 : {"BTC" : "adopt"})
+	Policies map[string]string `json:"policies"`
+	// 무시할 수량 차이 비율 (%)
+	Tolerance float64 `json:"tolerance"`
+}
+
+// 시세 기록 설정 (recorder 명령)
+type RecorderConfig struct {
+	// 저장 디렉토리 (기본 ./data)
+	Dir string `json:"dir"`
+	// 기록할 마켓, 비어 있으면 모든 계정의 targets
+	Targets []string `json:"targets"`
+	// 분봉 단위 (기본 1)
+	MinuteUnit int `json:"minute_unit"`
+	// 캔들, 현재가, 호가 기록 주기 (초, 0 이면 기본값, -1 이면 기록 안함)
+	CandleIntervalSecond int `json:"candle_interval_second"`
+	TickerIntervalSecond int `json:"ticker_interval_second"`
+	OrderbookIntervalSecond int `json:"orderbook_interval_second"`
+	// 저장할 호가 단계 수 (기본 5)
+	OrderbookDepth int `json:"orderbook_depth"`
+}
+
+// 전략별 자금 배분 설정 (한 계정에서 여러 전략이 매매할 때)
+type AllocatorConfig struct {
+	Enable int `json:"enable"`
+	// fixed (원화 고정), percent (평가 금액 %), risk_parity (거래 수익률 변동성 역수 비율)
+	Mode string `json:"mode"`
+	// 전략별 예산 (예 : {"lw_basic" : 60, "lw_advance" : 40}), fixed 는 원화, percent 는 %, risk_parity 는 대상 전략만 적는다.
+	Budgets map[string]float64 `json:"budgets"`
+	// risk_parity 에서 전략들에 나눠줄 평가 금액 비율 (%, 기본 100)
+	TotalRate float64 `json:"total_rate"`
+	// risk_parity 변동성 계산에 쓰는 전략별 최근 거래 수 (기본 20)
+	LookbackTrades int `json:"lookback_trades"`
+}
+
+// shadow 전략 설정 : 실매매와 같은 주기, 같은 시세로 가상 지갑에서 거래한다.
+type ShadowConfig struct {
+	Name string `json:"name"`
+	// lw_basic (기본), lw_advance
+	Strategy string `json:"strategy"`
+	// 가상 지갑 초기 원화 (기본 1000000)
+	InitialCash float64 `json:"initial_cash"`
+	// 계정의 larry_strategy 에서 바꿀 항목만 적는다. (예 : {"money_plan" : 3})
+	LarryStrategy json.RawMessage `json:"larry_strategy"`
+	// 비어 있으면 계정 상태 파일, 로그 파일 디렉토리에 shadow 이름으로 만든다.
+	StateFile string `json:"state_file"`
+	WalletFile string `json:"wallet_file"`
+	LogFile string `json:"log_file"`
+}
+
 type Config struct {
+	Name string `json:"name"`
+	// 거래소 (upbit, bithumb), 비어 있으면 upbit
+	Exchange string `json:"exchange"`
+	LogFile string `json:"log_file"`
+	StateFile string `json:"state_file"`
+	DryRun int `json:"dry_run"`
+	DecisionLog string `json:"decision_log"`
 	Account struct {
 		Accesskey	string `json:"access_key"`
 		SecretKey 	string `json:"secret_key"`
 	} `json:"account"`
-	LarryStrategy struct {
-		Enable 	int `json:"enable"`
-		KValue 	float64 	`json:"k_value"`
-		Period  int 	`json:"period"`
-		StartTime int	`json:"start_time"`
-		StopLoss float64	`json:"stop_loss"`
-		MaxProfit float64 	`json:"max_profit"`
-		MinVariability int `json:"min_variability"`
-		OrderAmount float64 `json:"order_amount"`
-		MinOrderAmountRate float64 `json:"min_order_amount_rate"`
-		MaxCoin int `json:"max_coin"`
-		AskPeriodMinute int `json:"ask_period_minute"`
-		AskOrderGap int `json:"ask_order_gap"`
-		MoneyPlan float64 `json:"money_plan"`
-		Targets []string `json:"targets"`
-	} `json:"larry_strategy"`
+	Fee FeeConfig `json:"fee"`
+	// 계정에서 실행할 실매매 전략 (lw_basic), 비어 있으면 lw_basic
+	// 전략마다 자기 주문과 보유 수량만 관리하고, 같은 상태 파일과 자금 배분(allocator)을 공유한다.
+	Strategies []string `json:"strategies"`
+	LarryStrategy LarryStrategyConfig `json:"larry_strategy"`
 	DayGoldStrategy struct {
 		Enable 	int `json:"enable"`
 		Period  int 	`json:"period"`
@@ -37,16 +219,212 @@ type Config struct {
 		AskOrderGap int `json:"ask_order_gap"`
 		Targets []string `json:"targets"`
 	} `json:"day_gold_strategy"`
+	HealthCheck struct {
+		Enable int `json:"enable"`
+		Method string `json:"method"`
+		Market string `json:"market"`
+		IntervalSecond int `json:"interval_second"`
+		HeartbeatFile string `json:"heartbeat_file"`
+		SystemdNotify int `json:"systemd_notify"`
+	} `json:"health_check"`
+	Reconcile ReconcileConfig `json:"reconcile"`
+	Alert struct {
+		WebhookURL string `json:"webhook_url"`
+	} `json:"alert"`
+	Recorder RecorderConfig `json:"recorder"`
+	// 전략별 예산을 정하고 예산을 넘는 매수 주문을 막는다.
+	Allocator AllocatorConfig `json:"allocator"`
+	// 실매매와 나란히 가상 지갑으로 거래하는 전략 변형 (A/B 비교용)
+	Shadows []*ShadowConfig `json:"shadows"`
+	// 여러 계정을 운영할 때 계정별 설정 (계정마다 전략, 타겟, 로그, 상태 파일을 따로 가진다)
+	Accounts []*Config `json:"accounts"`
 }
 
-func (C *Config) LoadConfiguration(file string) {
+/*
+ * 설정 파일을 읽는다. 파일이 없거나 형식이 잘못되면 에러를 돌려준다.
+ */
+func (C *Config) LoadConfiguration(file string) (err error) {
 	configFile, err := os.Open(file)
-	defer configFile.Close()
 	if err != nil {
-		fmt.Println(err.Error())
+		return
 	}
+	defer configFile.Close()
+
 	jsonParser := json.NewDecoder(configFile)
-	_ = jsonParser.Decode(C)
+	if err = jsonParser.Decode(C); err != nil {
+		return fmt.Errorf("%s : %s", file, err.Error())
+	}
+
+	if len(C.StateFile) == 0 && len(C.Accounts) == 0 {
+		C.StateFile = DefaultStateFile
+	}
+
+	return
+}
+
+/*
+ * 계정에서 실행할 실매매 전략 목록 (설정이 없으면 lw_basic)
+ */
+func (C *Config) StrategyNames() []string {
+	if len(C.Strategies) == 0 {
+		return []string{StrategyLWBasic}
+	}
+
+	return C.Strategies
+}
+
+/*
+ * 계정의 첫 번째 전략 : 전략을 알 수 없는 수량(잔고 대사 편입, 수동 청산)을 이 전략의 보유 수량으로 본다.
+ */
+func (C *Config) PrimaryStrategy() string {
+	return C.StrategyNames()[0]
+}
+
+/*
+ * 여러 전략을 실행하는 계정인지 확인한다.
+ */
+func (C *Config) IsMultiStrategy() bool {
+	return len(C.StrategyNames()) > 1
+}
+
+func validateStrategies(strategies []string) error {
+	names := make(map[string]bool)
+
+	for _, name := range strategies {
+		if name == StrategyLWAdvance {
+			return fmt.Errorf("%s 는 실매매를 지원하지 않음 : shadow 전략으로 실행", name)
+		}
+		if name != StrategyLWBasic {
+			return fmt.Errorf("지원하지 않는 전략 : %s", name)
+		}
+		if names[name] {
+			return fmt.Errorf("중복된 전략 : %s", name)
+		}
+		names[name] = true
+	}
+
+	return nil
+}
+
+/*
+ * 출력용 설정 : 계정 access key, secret key 를 가린다.
+ */
+func (C *Config) Redacted() *Config {
+	redacted := *C
+	redacted.Account.Accesskey = redactKey(C.Account.Accesskey)
+	redacted.Account.SecretKey = redactKey(C.Account.SecretKey)
+
+	redacted.Accounts = make([]*Config, 0, len(C.Accounts))
+	for _, account := range C.Accounts {
+		redacted.Accounts = append(redacted.Accounts, account.Redacted())
+	}
+
+	return &redacted
+}
+
+func redactKey(key string) string {
+	if len(key) == 0 {
+		return key
+	}
+
+	return redactedKey
+}
+
+/*
+ * 계정별 설정 목록을 만든다.
+ * accounts 가 없으면 최상위 설정을 단일 계정으로 사용한다.
+ * 계정별 로그, 상태, decision log 파일이 비어 있으면 계정 이름으로 기본 경로를 만든다.
+ */
+func (C *Config) AccountConfigs(logDir string) (configs []*Config, err error) {
+	if len(C.Accounts) == 0 {
+		if err = validateStrategies(C.Strategies); err != nil {
+			return
+		}
+
+		if len(C.Name) == 0 {
+			C.Name = DefaultAccountName
+		}
+		if len(C.LogFile) == 0 {
+			C.LogFile = filepath.Join(logDir, "raindrop.log")
+		}
+		if len(C.DecisionLog) == 0 {
+			C.DecisionLog = filepath.Join(logDir, "decisions.jsonl")
+		}
+
+		return []*Config{C}, nil
+	}
+
+	names := make(map[string]bool)
+	files := make(map[string]string)
+
+	for index, account := range C.Accounts {
+		if len(account.Name) == 0 {
+			account.Name = fmt.Sprintf("account%d", index+1)
+		}
+
+		if names[account.Name] {
+			return nil, fmt.Errorf("중복된 계정 이름 : %s", account.Name)
+		}
+		names[account.Name] = true
+
+		if len(account.LogFile) == 0 {
+			account.LogFile = filepath.Join(logDir, account.Name+".log")
+		}
+		if len(account.StateFile) == 0 {
+			account.StateFile = filepath.Join(defaultStateDir, account.Name+".json")
+		}
+		if len(account.DecisionLog) == 0 {
+			account.DecisionLog = filepath.Join(logDir, account.Name+"_decisions.jsonl")
+		}
+
+		// dry-run 은 최상위 설정이 켜져 있으면 모든 계정에 적용한다.
+		if C.DryRun == 1 {
+			account.DryRun = 1
+		}
+
+		// 계정에 HealthCheck 설정이 없으면 최상위 설정을 따른다.
+		if account.HealthCheck.Enable == 0 && len(account.HealthCheck.Method) == 0 {
+			account.HealthCheck = C.HealthCheck
+		}
+
+		// 계정에 수수료 설정이 없으면 최상위 설정을 따른다.
+		if account.Fee == (FeeConfig{}) {
+			account.Fee = C.Fee
+		}
+
+		// 계정에 대사, 알림 설정이 없으면 최상위 설정을 따른다.
+		if account.Reconcile.Enable == 0 && len(account.Reconcile.Policy) == 0 {
+			account.Reconcile = C.Reconcile
+		}
+		if len(account.Alert.WebhookURL) == 0 {
+			account.Alert = C.Alert
+		}
+
+		// 계정에 자금 배분 설정이 없으면 최상위 설정을 따른다.
+		if account.Allocator.Enable == 0 && len(account.Allocator.Mode) == 0 {
+			account.Allocator = C.Allocator
+		}
+
+		// 계정에 전략 목록이 없으면 최상위 설정을 따른다.
+		if len(account.Strategies) == 0 {
+			account.Strategies = C.Strategies
+		}
+		if err = validateStrategies(account.Strategies); err != nil {
+			return nil, fmt.Errorf("계정 %s : %s", account.Name, err.Error())
+		}
+
+		// 계정끼리 파일을 공유하면 서로의 기록을 덮어쓰므로 허용하지 않는다.
+		for _, path := range []string{account.LogFile, account.StateFile, account.DecisionLog} {
+			if owner, exist := files[path]; exist {
+				return nil, fmt.Errorf("계정 %s, %s 가 같은 파일을 사용함 : %s", owner, account.Name, path)
+			}
+			files[path] = account.Name
+		}
+
+		configs = append(configs, account)
+	}
+
+	return
 }
 
 
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ShadowStrategyBasic    = StrategyLWBasic
+	ShadowStrategyAdvanced = StrategyLWAdvance
+
+	DefaultShadowInitialCash = 1000000.0
+)
+
+/*
+ * 계정 설정으로 shadow 전략의 설정을 만든다.
+ * larry_strategy 는 계정 설정에 shadow 설정의 항목만 덮어쓰고,
+ * 비어 있는 파일 경로와 초기 자금은 shadow 설정에 기본값으로 채운다.
+ * 만든 설정은 가상 지갑에서만 사용하므로 dry-run, 대사, HealthCheck, 자금 배분은 끈다.
+ */
+func (C *Config) ShadowConfig(shadow *ShadowConfig) (config *Config, err error) {
+	if len(shadow.Name) == 0 {
+		return nil, fmt.Errorf("shadow 이름이 필요함")
+	}
+	if strings.ContainsAny(shadow.Name, `/\ `) {
+		return nil, fmt.Errorf("shadow 이름에 공백, 경로 문자를 쓸 수 없음 : %s", shadow.Name)
+	}
+
+	if len(shadow.Strategy) == 0 {
+		shadow.Strategy = ShadowStrategyBasic
+	}
+	if shadow.Strategy != ShadowStrategyBasic && shadow.Strategy != ShadowStrategyAdvanced {
+		return nil, fmt.Errorf("shadow %s : 지원하지 않는 전략 %s", shadow.Name, shadow.Strategy)
+	}
+
+	if shadow.InitialCash <= 0 {
+		shadow.InitialCash = DefaultShadowInitialCash
+	}
+
+	stateDir := filepath.Dir(C.StateFile)
+	if len(shadow.StateFile) == 0 {
+		shadow.StateFile = filepath.Join(stateDir, C.Name+"_shadow_"+shadow.Name+".json")
+	}
+	if len(shadow.WalletFile) == 0 {
+		shadow.WalletFile = filepath.Join(stateDir, C.Name+"_shadow_"+shadow.Name+"_wallet.json")
+	}
+	if len(shadow.LogFile) == 0 {
+		shadow.LogFile = filepath.Join(filepath.Dir(C.LogFile), C.Name+"_shadow_"+shadow.Name+".log")
+	}
+
+	for _, path := range []string{shadow.StateFile, shadow.WalletFile, shadow.LogFile} {
+		if path == C.StateFile || path == C.LogFile || path == C.DecisionLog {
+			return nil, fmt.Errorf("shadow %s 가 계정 파일을 사용함 : %s", shadow.Name, path)
+		}
+	}
+
+	copied := *C
+	config = &copied
+	config.Name = C.Name + "/" + shadow.Name
+	config.StateFile = shadow.StateFile
+	config.LogFile = shadow.LogFile
+	config.DryRun = 0
+	config.Reconcile = ReconcileConfig{}
+	config.HealthCheck.Enable = 0
+	config.Allocator = AllocatorConfig{}
+	// shadow 는 가상 지갑 하나에 전략 하나만 실행한다.
+	config.Strategies = []string{shadow.Strategy}
+	config.Shadows = nil
+	config.Accounts = nil
+
+	// 계정 설정의 slice, map 을 공유하지 않도록 JSON 으로 복사한 후 덮어쓴다.
+	data, err := json.Marshal(C.LarryStrategy)
+	if err != nil {
+		return
+	}
+
+	config.LarryStrategy = LarryStrategyConfig{}
+	if err = json.Unmarshal(data, &config.LarryStrategy); err != nil {
+		return
+	}
+
+	if len(shadow.LarryStrategy) > 0 {
+		if err = json.Unmarshal(shadow.LarryStrategy, &config.LarryStrategy); err != nil {
+			return nil, fmt.Errorf("shadow %s larry_strategy : %s", shadow.Name, err.Error())
+		}
+	}
+
+	return
+}
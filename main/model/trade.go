@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+/*
+ * 매수 ~ 매도 한 번의 왕복 거래
+ * 백테스트 결과와 실거래 기록이 같은 형식을 사용한다.
+ */
+type Trade struct {
+	Strategy   string    `json:"strategy"`
+	Market     string    `json:"market"`
+	EntryTime  time.Time `json:"entry_time"`
+	ExitTime   time.Time `json:"exit_time"`
+	Volume     float64   `json:"volume"`
+	EntryPrice float64   `json:"entry_price"`
+	ExitPrice  float64   `json:"exit_price"`
+	Fee        float64   `json:"fee"`
+	Profit     float64   `json:"profit"`
+	ProfitRate float64   `json:"profit_rate"`
+	KValue     float64   `json:"k_value,omitempty"`
+	MalScore   float64   `json:"mal_score,omitempty"`
+}
+
+/*
+ * 일별 평가 금액
+ */
+type EquityPoint struct {
+	Time   time.Time `json:"time"`
+	Equity float64   `json:"equity"`
+}
+
+/*
+ * 거래소에서 조회한 주문 체결 정보
+ */
+type OrderFill struct {
+	Uuid           string
+	State          string
+	ExecutedVolume float64
+	AvgPrice       float64
+	PaidFee        float64
+}
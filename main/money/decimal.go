@@ -0,0 +1,189 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+/*
+ * 가격, 수량 계산용 10진수
+ * float64 연산/포맷 오차 없이 호가 단위, 수량 자리수에 맞춰 자르기 위해 사용한다.
+ * 값은 변경되지 않으며 연산은 항상 새 값을 돌려준다.
+ */
+type Decimal struct {
+	rat *big.Rat
+}
+
+// 문자열 변환시 최대 소수점 자리수
+const maxStringPrecision = 16
+
+var Zero = NewFromInt(0)
+
+func NewFromInt(value int64) Decimal {
+	return Decimal{rat: new(big.Rat).SetInt64(value)}
+}
+
+/*
+ * float64 를 최단 10진 표현 기준으로 변환한다. (0.1 -> 0.1)
+ */
+func NewFromFloat(value float64) Decimal {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return Zero
+	}
+
+	decimal, _ := NewFromString(fmt.Sprintf("%v", value))
+	return decimal
+}
+
+func NewFromString(value string) (decimal Decimal, err error) {
+	rat, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok {
+		return Zero, fmt.Errorf("숫자 형식이 아님 : %s", value)
+	}
+
+	return Decimal{rat: rat}, nil
+}
+
+/*
+ * 형식이 잘못된 값은 0 으로 본다.
+ */
+func MustParse(value string) Decimal {
+	decimal, _ := NewFromString(value)
+	return decimal
+}
+
+/*
+ * 10^-places (places=2 -> 0.01)
+ */
+func Unit(places int) Decimal {
+	denominator := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	return Decimal{rat: new(big.Rat).SetFrac(big.NewInt(1), denominator)}
+}
+
+func (d Decimal) value() *big.Rat {
+	if d.rat == nil {
+		return new(big.Rat)
+	}
+	return d.rat
+}
+
+func (d Decimal) Add(other Decimal) Decimal {
+	return Decimal{rat: new(big.Rat).Add(d.value(), other.value())}
+}
+
+func (d Decimal) Sub(other Decimal) Decimal {
+	return Decimal{rat: new(big.Rat).Sub(d.value(), other.value())}
+}
+
+func (d Decimal) Mul(other Decimal) Decimal {
+	return Decimal{rat: new(big.Rat).Mul(d.value(), other.value())}
+}
+
+/*
+ * 0 으로 나누면 0 을 돌려준다.
+ */
+func (d Decimal) Div(other Decimal) Decimal {
+	if other.IsZero() {
+		return Zero
+	}
+	return Decimal{rat: new(big.Rat).Quo(d.value(), other.value())}
+}
+
+func (d Decimal) Cmp(other Decimal) int {
+	return d.value().Cmp(other.value())
+}
+
+func (d Decimal) Sign() int {
+	return d.value().Sign()
+}
+
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+func (d Decimal) LessThan(other Decimal) bool {
+	return d.Cmp(other) < 0
+}
+
+func (d Decimal) Float64() float64 {
+	f, _ := d.value().Float64()
+	return f
+}
+
+/*
+ * step 의 배수로 내림 (step > 0)
+ */
+func (d Decimal) FloorTo(step Decimal) Decimal {
+	return d.roundTo(step, false)
+}
+
+/*
+ * step 의 배수로 올림 (step > 0)
+ */
+func (d Decimal) CeilTo(step Decimal) Decimal {
+	return d.roundTo(step, true)
+}
+
+func (d Decimal) roundTo(step Decimal, up bool) Decimal {
+	if step.Sign() <= 0 {
+		return d
+	}
+
+	quotient := new(big.Rat).Quo(d.value(), step.value())
+
+	// 몫의 정수부 (0 방향으로 자름)
+	count := new(big.Int).Quo(quotient.Num(), quotient.Denom())
+
+	if !quotient.IsInt() {
+		if up && quotient.Sign() > 0 {
+			count.Add(count, big.NewInt(1))
+		} else if !up && quotient.Sign() < 0 {
+			count.Sub(count, big.NewInt(1))
+		}
+	}
+
+	return Decimal{rat: new(big.Rat).Mul(new(big.Rat).SetInt(count), step.value())}
+}
+
+/*
+ * 소수점 places 자리로 고정해 출력한다. (반올림)
+ */
+func (d Decimal) StringFixed(places int) string {
+	return d.value().FloatString(places)
+}
+
+/*
+ * 불필요한 0 을 뺀 10진 표현 (1.50000000 -> 1.5)
+ */
+func (d Decimal) String() string {
+	text := d.value().FloatString(maxStringPrecision)
+	if strings.Contains(text, ".") {
+		text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	}
+	if text == "-0" {
+		text = "0"
+	}
+
+	return text
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Decimal) UnmarshalJSON(data []byte) (err error) {
+	var text string
+	if err = json.Unmarshal(data, &text); err != nil {
+		var number json.Number
+		if err = json.Unmarshal(data, &number); err != nil {
+			return
+		}
+		text = number.String()
+	}
+
+	*d, err = NewFromString(text)
+	return
+}
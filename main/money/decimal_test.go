@@ -0,0 +1,137 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewFromFloat(t *testing.T) {
+	// 상수식은 컴파일 시 정확히 계산되므로 변수로 더한다.
+	a, b := 0.1, 0.2
+
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0.1, "0.1"},
+		{a + b, "0.30000000000000004"},
+		{1e-8, "0.00000001"},
+		{123456789, "123456789"},
+		{-2.5, "-2.5"},
+	}
+
+	for _, test := range tests {
+		if decimal := NewFromFloat(test.value); decimal.Cmp(MustParse(test.want)) != 0 {
+			t.Errorf("NewFromFloat(%v) = %s, want %s", test.value, decimal, test.want)
+		}
+	}
+}
+
+/*
+ * 매수 가격은 내림, 매도 가격은 올림으로 호가 단위에 맞춘다.
+ */
+func TestRoundTo(t *testing.T) {
+	tests := []struct {
+		value string
+		step  string
+		floor string
+		ceil  string
+	}{
+		{"12345.6", "10", "12340", "12350"},
+		{"12340", "10", "12340", "12340"},
+		{"0.123456789", "0.00000001", "0.12345678", "0.12345679"},
+		{"1.2345", "0.001", "1.234", "1.235"},
+		{"0.0001", "0.001", "0", "0.001"},
+		{"-1.25", "0.1", "-1.3", "-1.2"},
+		// 간격이 0 이하면 그대로
+		{"1.25", "0", "1.25", "1.25"},
+	}
+
+	for _, test := range tests {
+		value, step := MustParse(test.value), MustParse(test.step)
+
+		if floor := value.FloorTo(step); floor.Cmp(MustParse(test.floor)) != 0 {
+			t.Errorf("%s FloorTo %s = %s, want %s", test.value, test.step, floor, test.floor)
+		}
+		if ceil := value.CeilTo(step); ceil.Cmp(MustParse(test.ceil)) != 0 {
+			t.Errorf("%s CeilTo %s = %s, want %s", test.value, test.step, ceil, test.ceil)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		value  Decimal
+		text   string
+		fixed2 string
+	}{
+		{MustParse("1.50000000"), "1.5", "1.50"},
+		{MustParse("100"), "100", "100.00"},
+		{MustParse("-0.0000000000000000001"), "0", "-0.00"},
+		{Unit(8), "0.00000001", "0.00"},
+		{MustParse("1").Div(MustParse("3")), "0.3333333333333333", "0.33"},
+		{MustParse("1").Div(Zero), "0", "0.00"},
+		{MustParse("abc"), "0", "0.00"},
+		{Decimal{}, "0", "0.00"},
+	}
+
+	for _, test := range tests {
+		if text := test.value.String(); text != test.text {
+			t.Errorf("String() = %s, want %s", text, test.text)
+		}
+		if fixed := test.value.StringFixed(2); fixed != test.fixed2 {
+			t.Errorf("StringFixed(2) = %s, want %s", fixed, test.fixed2)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Decimal
+		want string
+	}{
+		{"add", MustParse("0.1").Add(MustParse("0.2")), "0.3"},
+		{"sub", MustParse("1").Sub(MustParse("0.99999999")), "0.00000001"},
+		{"mul", MustParse("91234000").Mul(MustParse("0.00123456")), "112633.84704"},
+		{"div", MustParse("10000").Div(MustParse("4")), "2500"},
+	}
+
+	for _, test := range tests {
+		if test.got.Cmp(MustParse(test.want)) != 0 {
+			t.Errorf("%s = %s, want %s", test.name, test.got, test.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`"0.00123456"`, "0.00123456"},
+		{`91234000`, "91234000"},
+		{`1.5e-3`, "0.0015"},
+	}
+
+	for _, test := range tests {
+		var decimal Decimal
+		if err := json.Unmarshal([]byte(test.data), &decimal); err != nil {
+			t.Errorf("%s : %s", test.data, err.Error())
+			continue
+		}
+		if decimal.String() != test.want {
+			t.Errorf("%s : decimal = %s, want %s", test.data, decimal, test.want)
+		}
+
+		data, err := json.Marshal(decimal)
+		if err != nil || string(data) != `"`+test.want+`"` {
+			t.Errorf("%s : marshal = %s, %v", test.data, data, err)
+		}
+	}
+
+	var decimal Decimal
+	if err := json.Unmarshal([]byte(`"abc"`), &decimal); err == nil {
+		t.Error("숫자가 아닌 값은 에러여야 함")
+	}
+}
@@ -0,0 +1,229 @@
+package montecarlo
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"raindrop/main/model"
+	"sort"
+)
+
+/*
+ * 왕복 거래 목록의 몬테카를로 재표본 분석
+ * 거래 순서 섞기, 복원 추출(부트스트랩), 무작위 거래 누락으로 자금 곡선을 여러 번 만들어
+ * 최대 낙폭, 최종 자금, 최장 연속 손실의 분포를 구한다.
+ */
+
+const (
+	MethodShuffle   = "shuffle"
+	MethodBootstrap = "bootstrap"
+	MethodSkip      = "skip"
+
+	DefaultRuns     = 1000
+	DefaultSkipRate = 0.1
+)
+
+var Methods = []string{MethodShuffle, MethodBootstrap, MethodSkip}
+
+type Options struct {
+	Method      string
+	Runs        int
+	InitialCash float64
+	// 0 보다 크면 거래 손익을 이 금액 x 수익률로 다시 계산한다. (order_amount 변경 효과 확인)
+	OrderAmount float64
+	// skip 방법에서 거래를 빼는 확률 (0 ~ 1)
+	SkipRate float64
+	Seed     int64
+}
+
+/*
+ * 한 번의 시뮬레이션 결과
+ */
+type Path struct {
+	FinalEquity         float64
+	MaxDrawdown         float64
+	MaxDrawdownAmount   float64
+	LongestLosingStreak int
+	Ruined              bool
+}
+
+/*
+ * 정렬된 시뮬레이션 값 목록
+ */
+type Distribution []float64
+
+/*
+ * rate (0 ~ 100) 백분위 값
+ */
+func (distribution Distribution) Percentile(rate float64) float64 {
+	if len(distribution) == 0 {
+		return 0
+	}
+
+	index := int(math.Ceil(rate/100*float64(len(distribution)))) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(distribution) {
+		index = len(distribution) - 1
+	}
+
+	return distribution[index]
+}
+
+type Report struct {
+	Method            string
+	Runs              int
+	TradeCount        int
+	InitialCash       float64
+	FinalEquity       Distribution
+	MaxDrawdown       Distribution
+	MaxDrawdownAmount Distribution
+	LosingStreak      Distribution
+	// 실제 거래 순서 그대로의 결과
+	Actual Path
+	// 자금이 0 이하로 떨어진 비율 (%)
+	RuinRate float64
+}
+
+/*
+ * 신뢰수준 level (%) 에서의 나쁜 쪽 값
+ * 최종 자금은 하위 (100-level) 백분위, 낙폭과 연속 손실은 상위 level 백분위
+ */
+func (report *Report) WorstFinalEquity(level float64) float64 {
+	return report.FinalEquity.Percentile(100 - level)
+}
+
+func (report *Report) WorstMaxDrawdown(level float64) float64 {
+	return report.MaxDrawdown.Percentile(level)
+}
+
+func (report *Report) WorstMaxDrawdownAmount(level float64) float64 {
+	return report.MaxDrawdownAmount.Percentile(level)
+}
+
+func (report *Report) WorstLosingStreak(level float64) float64 {
+	return report.LosingStreak.Percentile(level)
+}
+
+/*
+ * 거래 목록으로 몬테카를로 시뮬레이션을 수행한다.
+ */
+func Simulate(trades []*model.Trade, options Options) (report *Report, err error) {
+	if len(trades) == 0 {
+		return nil, fmt.Errorf("거래가 없음")
+	}
+	if options.InitialCash <= 0 {
+		return nil, fmt.Errorf("초기 자금이 필요함")
+	}
+	if options.Runs <= 0 {
+		options.Runs = DefaultRuns
+	}
+	if options.Method == MethodSkip && options.SkipRate <= 0 {
+		options.SkipRate = DefaultSkipRate
+	}
+
+	profits := make([]float64, 0, len(trades))
+	for _, trade := range trades {
+		if options.OrderAmount > 0 {
+			profits = append(profits, options.OrderAmount*trade.ProfitRate/100)
+		} else {
+			profits = append(profits, trade.Profit)
+		}
+	}
+
+	random := rand.New(rand.NewSource(options.Seed))
+
+	report = &Report{
+		Method:      options.Method,
+		Runs:        options.Runs,
+		TradeCount:  len(trades),
+		InitialCash: options.InitialCash,
+		Actual:      simulatePath(options.InitialCash, profits)}
+
+	ruinCount := 0
+	sample := make([]float64, len(profits))
+
+	for run := 0; run < options.Runs; run++ {
+		switch options.Method {
+		case MethodShuffle:
+			copy(sample, profits)
+			random.Shuffle(len(sample), func(i, j int) {
+				sample[i], sample[j] = sample[j], sample[i]
+			})
+		case MethodBootstrap:
+			for index := range sample {
+				sample[index] = profits[random.Intn(len(profits))]
+			}
+		case MethodSkip:
+			sample = sample[:0]
+			for _, profit := range profits {
+				if random.Float64() >= options.SkipRate {
+					sample = append(sample, profit)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("지원하지 않는 방법 : %s (%v)", options.Method, Methods)
+		}
+
+		path := simulatePath(options.InitialCash, sample)
+		if path.Ruined {
+			ruinCount++
+		}
+
+		report.FinalEquity = append(report.FinalEquity, path.FinalEquity)
+		report.MaxDrawdown = append(report.MaxDrawdown, path.MaxDrawdown)
+		report.MaxDrawdownAmount = append(report.MaxDrawdownAmount, path.MaxDrawdownAmount)
+		report.LosingStreak = append(report.LosingStreak, float64(path.LongestLosingStreak))
+
+		sample = sample[:cap(sample)]
+	}
+
+	for _, distribution := range []Distribution{report.FinalEquity, report.MaxDrawdown, report.MaxDrawdownAmount, report.LosingStreak} {
+		sort.Float64s(distribution)
+	}
+
+	report.RuinRate = float64(ruinCount) / float64(options.Runs) * 100
+
+	return
+}
+
+/*
+ * 손익을 순서대로 더해 자금 곡선의 지표를 구한다.
+ */
+func simulatePath(initialCash float64, profits []float64) (path Path) {
+	equity := initialCash
+	peak := initialCash
+	losingStreak := 0
+
+	for _, profit := range profits {
+		equity += profit
+
+		if equity > peak {
+			peak = equity
+		}
+
+		drawdownAmount := peak - equity
+		path.MaxDrawdownAmount = math.Max(path.MaxDrawdownAmount, drawdownAmount)
+		if peak > 0 {
+			path.MaxDrawdown = math.Max(path.MaxDrawdown, drawdownAmount/peak*100)
+		}
+
+		if profit <= 0 {
+			losingStreak++
+			if losingStreak > path.LongestLosingStreak {
+				path.LongestLosingStreak = losingStreak
+			}
+		} else {
+			losingStreak = 0
+		}
+
+		if equity <= 0 {
+			path.Ruined = true
+		}
+	}
+
+	path.FinalEquity = equity
+
+	return
+}
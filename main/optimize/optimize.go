@@ -0,0 +1,295 @@
+package optimize
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"raindrop/main/backtest"
+	"raindrop/main/marketdata"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+ * 백테스트 파라미터 탐색
+ * k_value (또는 노이즈 k 기간), money_plan, min_order_amount_rate, max_coin, start_time 조합을
+ * 격자 탐색 또는 무작위 탐색으로 여러 워커에서 백테스트하고 목적 함수로 순위를 매긴다.
+ */
+
+const (
+	ModeGrid   = "grid"
+	ModeRandom = "random"
+
+	ObjectiveCAGRMDD = "cagr_mdd"
+	ObjectiveSharpe  = "sharpe"
+	ObjectiveReturn  = "return"
+)
+
+/*
+ * 파라미터별 탐색 값, 비어 있으면 기본 파라미터 값을 사용한다.
+ */
+type Space struct {
+	KValue             []float64
+	NoiseLookback      []float64
+	MoneyPlan          []float64
+	MinOrderAmountRate []float64
+	MaxCoin            []float64
+	StartTime          []float64
+}
+
+type Options struct {
+	Mode      string
+	Samples   int
+	Workers   int
+	Objective string
+	// 거래 수가 이보다 적은 결과는 순위에서 뺀다.
+	MinTrades int
+	Seed      int64
+}
+
+type Result struct {
+	Params  backtest.Params
+	Summary backtest.Summary
+	Score   float64
+}
+
+/*
+ * 세션 시작 시각(start_time)별 일봉을 돌려준다.
+ */
+type SeriesFunc func(startTime int) (map[string][]*marketdata.Candle, error)
+
+/*
+ * 범위 문자열을 값 목록으로 바꾼다.
+ * "0.3:0.7:0.1" (최소:최대:간격) 또는 "0.4,0.5,0.6"
+ */
+func ParseRange(text string) (values []float64, err error) {
+	text = strings.TrimSpace(text)
+	if len(text) == 0 {
+		return
+	}
+
+	if parts := strings.Split(text, ":"); len(parts) == 3 {
+		bounds := make([]float64, 3)
+		for index, part := range parts {
+			if bounds[index], err = strconv.ParseFloat(strings.TrimSpace(part), 64); err != nil {
+				return nil, err
+			}
+		}
+
+		if bounds[2] <= 0 || bounds[1] < bounds[0] {
+			return nil, fmt.Errorf("잘못된 범위 : %s", text)
+		}
+
+		// 부동소수 오차로 마지막 값이 빠지지 않도록 간격의 절반만큼 여유를 둔다.
+		for value := bounds[0]; value <= bounds[1]+bounds[2]/2; value += bounds[2] {
+			values = append(values, math.Round(value*1e8)/1e8)
+		}
+
+		return
+	}
+
+	for _, part := range strings.Split(text, ",") {
+		value, parseErr := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		values = append(values, value)
+	}
+
+	return
+}
+
+/*
+ * 탐색 범위를 검사한다.
+ * 노이즈 k 를 쓰는 조합(노이즈 기간 > 0)은 k_value 를 쓰지 않으므로,
+ * k_value 범위를 주면서 노이즈 기간에 0 이 없으면 k_value 가 하나로 고정되어 탐색되지 않는다.
+ */
+func (space Space) Validate(base backtest.Params) error {
+	if len(space.KValue) == 0 {
+		return nil
+	}
+
+	for _, lookback := range valuesOr(space.NoiseLookback, float64(base.NoiseLookback)) {
+		if int(lookback) <= 0 {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("k_value 범위는 노이즈 k 를 쓰지 않는 조합에서만 탐색됨 : 노이즈 기간에 0 을 넣어야 함 (현재 %v)",
+		valuesOr(space.NoiseLookback, float64(base.NoiseLookback)))
+}
+
+/*
+ * 탐색할 파라미터 조합을 만든다.
+ * 노이즈 k 를 쓰는 조합은 k_value 를 쓰지 않으므로 k_value 를 기본값으로 고정해 중복을 없앤다.
+ */
+func Combinations(base backtest.Params, space Space) (combinations []backtest.Params) {
+	kValues := valuesOr(space.KValue, base.Strategy.KValue)
+	lookbacks := valuesOr(space.NoiseLookback, float64(base.NoiseLookback))
+	moneyPlans := valuesOr(space.MoneyPlan, base.Strategy.MoneyPlan)
+	minRates := valuesOr(space.MinOrderAmountRate, base.Strategy.MinOrderAmountRate)
+	maxCoins := valuesOr(space.MaxCoin, float64(base.Strategy.MaxCoin))
+	startTimes := valuesOr(space.StartTime, float64(base.Strategy.StartTime))
+
+	seen := make(map[string]bool)
+
+	for _, startTime := range startTimes {
+		for _, lookback := range lookbacks {
+			for _, kValue := range kValues {
+				for _, moneyPlan := range moneyPlans {
+					for _, minRate := range minRates {
+						for _, maxCoin := range maxCoins {
+							params := base
+							params.NoiseLookback = int(lookback)
+							params.Strategy.KValue = kValue
+							if params.NoiseLookback > 0 {
+								params.Strategy.KValue = base.Strategy.KValue
+							}
+							params.Strategy.MoneyPlan = moneyPlan
+							params.Strategy.MinOrderAmountRate = minRate
+							params.Strategy.MaxCoin = int(maxCoin)
+							params.Strategy.StartTime = int(startTime)
+
+							key := Describe(params)
+							if seen[key] {
+								continue
+							}
+							seen[key] = true
+
+							combinations = append(combinations, params)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return
+}
+
+/*
+ * 조합을 백테스트하고 점수 순으로 정렬해 돌려준다.
+ */
+func Run(base backtest.Params, space Space, options Options, seriesFor SeriesFunc) (results []*Result, err error) {
+	if err = space.Validate(base); err != nil {
+		return
+	}
+
+	combinations := Combinations(base, space)
+
+	if options.Mode == ModeRandom && options.Samples > 0 && options.Samples < len(combinations) {
+		random := rand.New(rand.NewSource(options.Seed))
+		random.Shuffle(len(combinations), func(i, j int) {
+			combinations[i], combinations[j] = combinations[j], combinations[i]
+		})
+		combinations = combinations[:options.Samples]
+	}
+
+	// 세션 시작 시각별 일봉은 미리 만들어 워커가 같이 읽는다.
+	seriesMap := make(map[int]map[string][]*marketdata.Candle)
+	for _, params := range combinations {
+		if _, exist := seriesMap[params.Strategy.StartTime]; exist {
+			continue
+		}
+
+		series, seriesErr := seriesFor(params.Strategy.StartTime)
+		if seriesErr != nil {
+			return nil, seriesErr
+		}
+		seriesMap[params.Strategy.StartTime] = series
+	}
+
+	workers := options.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	jobs := make(chan backtest.Params)
+	resultChan := make(chan *Result)
+
+	var waitGroup sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for params := range jobs {
+				summary := backtest.Run(params, seriesMap[params.Strategy.StartTime]).Summary
+				resultChan <- &Result{
+					Params:  params,
+					Summary: summary,
+					Score:   Score(options.Objective, summary)}
+			}
+		}()
+	}
+
+	go func() {
+		for _, params := range combinations {
+			jobs <- params
+		}
+		close(jobs)
+		waitGroup.Wait()
+		close(resultChan)
+	}()
+
+	results = make([]*Result, 0, len(combinations))
+	for result := range resultChan {
+		if result.Summary.TradeCount < options.MinTrades {
+			continue
+		}
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return Describe(results[i].Params) < Describe(results[j].Params)
+	})
+
+	return
+}
+
+/*
+ * 목적 함수 값 (클수록 좋음)
+ * cagr_mdd : CAGR / MDD (MDD 가 0 이면 CAGR), sharpe : Sharpe, return : 총 수익률
+ */
+func Score(objective string, summary backtest.Summary) float64 {
+	switch objective {
+	case ObjectiveSharpe:
+		return summary.Sharpe
+	case ObjectiveReturn:
+		return summary.TotalReturn
+	default:
+		if summary.MDD <= 0 {
+			return summary.CAGR
+		}
+		return summary.CAGR / summary.MDD
+	}
+}
+
+func ValidObjective(objective string) bool {
+	return objective == ObjectiveCAGRMDD || objective == ObjectiveSharpe || objective == ObjectiveReturn
+}
+
+/*
+ * 탐색 파라미터 요약 문자열
+ */
+func Describe(params backtest.Params) string {
+	return fmt.Sprintf("k=%g noise=%d money_plan=%g min_rate=%g max_coin=%d start_time=%d",
+		params.Strategy.KValue,
+		params.NoiseLookback,
+		params.Strategy.MoneyPlan,
+		params.Strategy.MinOrderAmountRate,
+		params.Strategy.MaxCoin,
+		params.Strategy.StartTime)
+}
+
+func valuesOr(values []float64, defaultValue float64) []float64 {
+	if len(values) > 0 {
+		return values
+	}
+
+	return []float64{defaultValue}
+}
@@ -0,0 +1,55 @@
+package optimize
+
+import (
+	"raindrop/main/backtest"
+	"raindrop/main/model"
+	"testing"
+)
+
+func baseParams(noiseLookback int) backtest.Params {
+	params := backtest.DefaultParams(model.LarryStrategyConfig{KValue: 0.5, MoneyPlan: 2, MaxCoin: 5})
+	params.NoiseLookback = noiseLookback
+
+	return params
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  backtest.Params
+		space Space
+		err   bool
+	}{
+		{"no k range", baseParams(20), Space{}, false},
+		{"k with default noise", baseParams(20), Space{KValue: []float64{0.3, 0.5}}, true},
+		{"k with noise only", baseParams(0), Space{KValue: []float64{0.3, 0.5}, NoiseLookback: []float64{10, 20}}, true},
+		{"k with noise 0", baseParams(20), Space{KValue: []float64{0.3, 0.5}, NoiseLookback: []float64{0, 20}}, false},
+		{"k with base noise 0", baseParams(0), Space{KValue: []float64{0.3, 0.5}}, false},
+	}
+
+	for _, test := range tests {
+		if err := test.space.Validate(test.base); (err != nil) != test.err {
+			t.Errorf("%s : err = %v, want error %t", test.name, err, test.err)
+		}
+	}
+}
+
+func TestCombinations(t *testing.T) {
+	tests := []struct {
+		name  string
+		space Space
+		want  int
+	}{
+		{"base only", Space{}, 1},
+		// 노이즈 k 조합은 k_value 를 기본값으로 고정하므로 k 범위만큼 늘지 않는다.
+		{"k and noise", Space{KValue: []float64{0.3, 0.4, 0.5}, NoiseLookback: []float64{0, 20}}, 3 + 1},
+		{"grid", Space{KValue: []float64{0.3, 0.5}, NoiseLookback: []float64{0}, MaxCoin: []float64{3, 5, 7}}, 6},
+	}
+
+	for _, test := range tests {
+		combinations := Combinations(baseParams(20), test.space)
+		if len(combinations) != test.want {
+			t.Errorf("%s : combinations = %d, want %d", test.name, len(combinations), test.want)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package optimize
+
+import (
+	"fmt"
+	"math"
+	"raindrop/main/backtest"
+	"raindrop/main/marketdata"
+	"raindrop/main/model"
+	"time"
+)
+
+/*
+ * Walk-forward 분석
+ * 학습 구간에서 파라미터를 탐색하고 바로 다음 검증 구간에 적용하는 것을 반복한다.
+ * 검증 구간 결과를 이어 붙인 자금 곡선과 구간별로 고른 파라미터가 얼마나 흔들리는지를 본다.
+ */
+
+type WalkForwardOptions struct {
+	TrainDays int
+	TestDays  int
+	// 다음 구간까지 이동할 일수, 0 이면 TestDays (검증 구간이 겹치지 않도록 TestDays 이상)
+	StepDays int
+}
+
+type Window struct {
+	TrainFrom time.Time
+	TrainTo   time.Time
+	TestFrom  time.Time
+	TestTo    time.Time
+
+	// 학습 구간 최적 결과, 조건을 만족하는 조합이 없으면 nil (검증 구간은 거래하지 않음)
+	Best *Result
+	// 검증 구간 결과와 점수
+	Test      backtest.Summary
+	TestScore float64
+}
+
+/*
+ * 구간별로 고른 파라미터 값의 분포
+ * Changes : 직전 구간과 값이 달라진 횟수
+ */
+type ParamStability struct {
+	Name    string
+	Values  []float64
+	Mean    float64
+	StdDev  float64
+	Min     float64
+	Max     float64
+	Changes int
+}
+
+type WalkForward struct {
+	Windows []*Window
+	// 검증 구간을 이어 붙인 결과 (구간마다 직전 구간의 최종 자금으로 시작)
+	Trades    []*model.Trade
+	Equity    []*model.EquityPoint
+	Summary   backtest.Summary
+	Stability []*ParamStability
+	// 검증 점수 평균 / 학습 점수 평균 (1 에 가까울수록 과최적화가 적음)
+	Efficiency float64
+}
+
+/*
+ * from ~ to 구간에서 walk-forward 분석을 수행한다.
+ * from, to 가 0 이면 데이터의 처음(신호 계산 기간 이후)과 끝을 사용한다.
+ */
+func RunWalkForward(base backtest.Params,
+	space Space,
+	options Options,
+	walkOptions WalkForwardOptions,
+	seriesFor SeriesFunc) (walkForward *WalkForward, err error) {
+
+	if walkOptions.TrainDays <= 0 || walkOptions.TestDays <= 0 {
+		return nil, fmt.Errorf("학습, 검증 구간 일수가 필요함")
+	}
+	if walkOptions.StepDays <= 0 {
+		walkOptions.StepDays = walkOptions.TestDays
+	}
+	// 검증 구간이 겹치면 같은 날의 거래와 자금 곡선이 이어 붙인 결과에 두 번 들어간다.
+	if walkOptions.StepDays < walkOptions.TestDays {
+		return nil, fmt.Errorf("구간 이동 일수(%d)는 검증 구간 일수(%d)보다 작을 수 없음",
+			walkOptions.StepDays, walkOptions.TestDays)
+	}
+	if err = space.Validate(base); err != nil {
+		return
+	}
+
+	// 구간마다 같은 데이터를 다시 읽지 않도록 세션 시작 시각별로 저장해 둔다.
+	seriesFor = cachedSeries(seriesFor)
+
+	from, to, err := dataRange(base, space, seriesFor)
+	if err != nil {
+		return
+	}
+
+	walkForward = &WalkForward{
+		Windows: make([]*Window, 0),
+		Trades:  make([]*model.Trade, 0),
+		Equity:  make([]*model.EquityPoint, 0)}
+
+	cash := base.InitialCash
+	trainScoreSum := 0.0
+	testScoreSum := 0.0
+
+	for trainFrom := from; ; trainFrom = trainFrom.AddDate(0, 0, walkOptions.StepDays) {
+		window := &Window{
+			TrainFrom: trainFrom,
+			TrainTo:   trainFrom.AddDate(0, 0, walkOptions.TrainDays-1),
+			TestFrom:  trainFrom.AddDate(0, 0, walkOptions.TrainDays)}
+		window.TestTo = window.TestFrom.AddDate(0, 0, walkOptions.TestDays-1)
+
+		if window.TestFrom.After(to) {
+			break
+		}
+		if window.TestTo.After(to) {
+			window.TestTo = to
+		}
+
+		train := base
+		train.From = window.TrainFrom
+		train.To = window.TrainTo
+
+		results, runErr := Run(train, space, options, seriesFor)
+		if runErr != nil {
+			return nil, runErr
+		}
+
+		walkForward.Windows = append(walkForward.Windows, window)
+
+		if len(results) == 0 {
+			continue
+		}
+		window.Best = results[0]
+
+		test := window.Best.Params
+		test.From = window.TestFrom
+		test.To = window.TestTo
+		test.InitialCash = cash
+
+		series, seriesErr := seriesFor(test.Strategy.StartTime)
+		if seriesErr != nil {
+			return nil, seriesErr
+		}
+
+		result := backtest.Run(test, series)
+		window.Test = result.Summary
+		window.TestScore = Score(options.Objective, result.Summary)
+
+		walkForward.Trades = append(walkForward.Trades, result.Trades...)
+		walkForward.Equity = append(walkForward.Equity, result.Equity...)
+		cash = result.Summary.FinalEquity
+
+		trainScoreSum += window.Best.Score
+		testScoreSum += window.TestScore
+	}
+
+	walkForward.Summary = backtest.Summarize(base.InitialCash, walkForward.Trades, walkForward.Equity)
+	walkForward.Stability = stability(walkForward.Windows)
+
+	if trainScoreSum != 0 {
+		walkForward.Efficiency = testScoreSum / trainScoreSum
+	}
+
+	return
+}
+
+/*
+ * 분석 구간 : 지정하지 않은 쪽은 데이터의 처음(신호 계산 기간 이후), 끝을 사용한다.
+ */
+func dataRange(base backtest.Params, space Space, seriesFor SeriesFunc) (from time.Time, to time.Time, err error) {
+	from, to = base.From, base.To
+	if !from.IsZero() && !to.IsZero() {
+		return
+	}
+
+	series, err := seriesFor(base.Strategy.StartTime)
+	if err != nil {
+		return
+	}
+
+	first, last := time.Time{}, time.Time{}
+	for _, candles := range series {
+		if len(candles) == 0 {
+			continue
+		}
+		if first.IsZero() || candles[0].Time.Before(first) {
+			first = candles[0].Time
+		}
+		if candles[len(candles)-1].Time.After(last) {
+			last = candles[len(candles)-1].Time
+		}
+	}
+
+	if first.IsZero() {
+		return from, to, fmt.Errorf("백테스트 데이터가 없음")
+	}
+
+	if from.IsZero() {
+		// 탐색하는 노이즈 k 기간 중 가장 긴 기간만큼 지난 후부터
+		warmup := base
+		for _, lookback := range space.NoiseLookback {
+			if int(lookback) > warmup.NoiseLookback {
+				warmup.NoiseLookback = int(lookback)
+			}
+		}
+		from = first.AddDate(0, 0, backtest.MinHistory(warmup))
+	}
+	if to.IsZero() {
+		to = last
+	}
+
+	return
+}
+
+func cachedSeries(seriesFor SeriesFunc) SeriesFunc {
+	cache := make(map[int]map[string][]*marketdata.Candle)
+
+	return func(startTime int) (map[string][]*marketdata.Candle, error) {
+		if series, exist := cache[startTime]; exist {
+			return series, nil
+		}
+
+		series, err := seriesFor(startTime)
+		if err == nil {
+			cache[startTime] = series
+		}
+
+		return series, err
+	}
+}
+
+func stability(windows []*Window) (stabilities []*ParamStability) {
+	getters := []struct {
+		name  string
+		value func(params backtest.Params) float64
+	}{
+		{"k_value", func(params backtest.Params) float64 { return params.Strategy.KValue }},
+		{"noise_lookback", func(params backtest.Params) float64 { return float64(params.NoiseLookback) }},
+		{"money_plan", func(params backtest.Params) float64 { return params.Strategy.MoneyPlan }},
+		{"min_order_amount_rate", func(params backtest.Params) float64 { return params.Strategy.MinOrderAmountRate }},
+		{"max_coin", func(params backtest.Params) float64 { return float64(params.Strategy.MaxCoin) }},
+		{"start_time", func(params backtest.Params) float64 { return float64(params.Strategy.StartTime) }},
+	}
+
+	stabilities = make([]*ParamStability, 0, len(getters))
+
+	for _, getter := range getters {
+		stat := &ParamStability{Name: getter.name, Values: make([]float64, 0, len(windows))}
+
+		for _, window := range windows {
+			if window.Best == nil {
+				continue
+			}
+
+			value := getter.value(window.Best.Params)
+			if len(stat.Values) > 0 && stat.Values[len(stat.Values)-1] != value {
+				stat.Changes++
+			}
+			stat.Values = append(stat.Values, value)
+		}
+
+		if len(stat.Values) > 0 {
+			stat.Min, stat.Max = stat.Values[0], stat.Values[0]
+			for _, value := range stat.Values {
+				stat.Mean += value
+				stat.Min = math.Min(stat.Min, value)
+				stat.Max = math.Max(stat.Max, value)
+			}
+			stat.Mean /= float64(len(stat.Values))
+
+			for _, value := range stat.Values {
+				stat.StdDev += (value - stat.Mean) * (value - stat.Mean)
+			}
+			stat.StdDev = math.Sqrt(stat.StdDev / float64(len(stat.Values)))
+		}
+
+		stabilities = append(stabilities, stat)
+	}
+
+	return
+}
@@ -0,0 +1,39 @@
+package optimize
+
+import (
+	"errors"
+	"raindrop/main/marketdata"
+	"testing"
+)
+
+func TestWalkForwardOptions(t *testing.T) {
+	errNoData := errors.New("no data")
+
+	tests := []struct {
+		name    string
+		options WalkForwardOptions
+		// 검사를 통과하면 데이터 조회까지 진행한다.
+		valid bool
+	}{
+		{"missing train", WalkForwardOptions{TestDays: 30}, false},
+		{"missing test", WalkForwardOptions{TrainDays: 180}, false},
+		{"overlapping test windows", WalkForwardOptions{TrainDays: 180, TestDays: 30, StepDays: 10}, false},
+		{"default step", WalkForwardOptions{TrainDays: 180, TestDays: 30}, true},
+		{"step equals test", WalkForwardOptions{TrainDays: 180, TestDays: 30, StepDays: 30}, true},
+		{"step longer than test", WalkForwardOptions{TrainDays: 180, TestDays: 30, StepDays: 60}, true},
+	}
+
+	for _, test := range tests {
+		seriesFor := func(startTime int) (map[string][]*marketdata.Candle, error) {
+			return nil, errNoData
+		}
+
+		_, err := RunWalkForward(baseParams(20), Space{}, Options{}, test.options, seriesFor)
+		if test.valid && err != errNoData {
+			t.Errorf("%s : err = %v, want %v", test.name, err, errNoData)
+		}
+		if !test.valid && (err == nil || err == errNoData) {
+			t.Errorf("%s : err = %v, want option error", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package quote
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"raindrop/main/exchange"
+	"strconv"
+	"strings"
+)
+
+/*
+ * 마켓 코드(KRW-BTC, BTC-ETH, USDT-XRP)의 앞부분을 기준 통화(quote)로 사용한다.
+ * 잔고, 주문 금액은 기준 통화 단위로 다루고 평가 금액은 원화로 환산한다.
+ */
+
+const KRW = "KRW"
+
+/*
+ * 마켓의 기준 통화 : BTC-ETH -> BTC
+ */
+func Of(market string) string {
+	if index := strings.Index(market, "-"); index > 0 {
+		return market[:index]
+	}
+
+	return KRW
+}
+
+/*
+ * 마켓의 거래 코인 : BTC-ETH -> ETH
+ */
+func Base(market string) string {
+	if index := strings.Index(market, "-"); index >= 0 {
+		return market[index+1:]
+	}
+
+	return market
+}
+
+func Market(quoteCurrency string, currency string) string {
+	return quoteCurrency + "-" + currency
+}
+
+/*
+ * 타겟 마켓들이 사용하는 기준 통화 목록 (중복 제거, 타겟 순서)
+ */
+func Currencies(markets []string) (currencies []string) {
+	currencies = make([]string, 0)
+	exist := make(map[string]bool)
+
+	for _, market := range markets {
+		currency := Of(market)
+		if !exist[currency] {
+			exist[currency] = true
+			currencies = append(currencies, currency)
+		}
+	}
+
+	return
+}
+
+func IsQuote(currency string, markets []string) bool {
+	if currency == KRW {
+		return true
+	}
+
+	for _, market := range markets {
+		if Of(market) == currency {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+ * 잔고의 코인을 마켓 코드로 바꾼다.
+ * 타겟에 있는 코인이면 타겟의 기준 통화를, 없으면 원화 마켓을 사용한다.
+ */
+func MarketOf(currency string, targets []string) string {
+	for _, target := range targets {
+		if Base(target) == currency {
+			return target
+		}
+	}
+
+	return Market(KRW, currency)
+}
+
+/*
+ * 주문 가능 잔고 (currency 단위)
+ */
+func Available(balances []*types.Balance, currency string) float64 {
+	for _, balance := range balances {
+		if balance.Currency == currency {
+			f, _ := strconv.ParseFloat(balance.Balance, 64)
+			return f
+		}
+	}
+
+	return 0.0
+}
+
+/*
+ * 기준 통화가 아닌 보유 코인의 잔고를 마켓 코드 기준 Map 으로 만든다.
+ * 타겟 마켓의 기준 통화로 쓰이는 코인(BTC-XXX 의 BTC 등)은 보유 코인으로 보지 않는다.
+ */
+func HoldingMap(balances []*types.Balance, targets []string) (balanceMap map[string]*types.Balance) {
+	balanceMap = make(map[string]*types.Balance)
+
+	for _, balance := range balances {
+		if IsQuote(balance.Currency, targets) {
+			continue
+		}
+
+		balanceMap[MarketOf(balance.Currency, targets)] = balance
+	}
+
+	return
+}
+
+/*
+ * 기준 통화의 원화 환산 비율 (KRW 는 1)
+ * candleMap 에 원화 마켓 캔들이 있으면 재사용하고, 없으면 조회한다.
+ */
+func Rates(ex exchange.Exchange, currencies []string, candleMap map[string][]*types.DayCandle) (rates map[string]float64) {
+	rates = map[string]float64{KRW: 1.0}
+
+	for _, currency := range currencies {
+		if currency == KRW {
+			continue
+		}
+
+		market := Market(KRW, currency)
+
+		candles, exist := candleMap[market]
+		if !exist || len(candles) == 0 {
+			var err error
+			if candles, err = ex.DayCandles(market, 1); err != nil || len(candles) == 0 {
+				continue
+			}
+		}
+
+		rates[currency] = candles[0].TradePrice
+	}
+
+	return
+}
+
+/*
+ * 기준 통화 금액을 원화로 환산한다. 환산 비율이 없으면 false
+ */
+func ToKRW(amount float64, currency string, rates map[string]float64) (float64, bool) {
+	rate, exist := rates[currency]
+	if !exist {
+		return amount, false
+	}
+
+	return amount * rate, true
+}
+
+/*
+ * 평가 금액 (원화) : 잔고(주문 대기 포함)를 원화 마켓 현재가로 환산해 더한다.
+ * excluded 코인(장기 보유 코인 등)은 빼고, 환산 비율을 구하지 못한 코인은 missing 으로 돌려준다.
+ */
+func Equity(ex exchange.Exchange, balances []*types.Balance, candleMap map[string][]*types.DayCandle,
+	excluded []string) (equity float64, missing []string) {
+
+	amounts := make(map[string]float64)
+	currencies := make([]string, 0)
+
+	for _, balance := range balances {
+		if isExcludedCurrency(balance.Currency, excluded) {
+			continue
+		}
+
+		available, _ := strconv.ParseFloat(balance.Balance, 64)
+		locked, _ := strconv.ParseFloat(balance.Locked, 64)
+		if available+locked <= 0 {
+			continue
+		}
+
+		if _, exist := amounts[balance.Currency]; !exist {
+			currencies = append(currencies, balance.Currency)
+		}
+		amounts[balance.Currency] += available + locked
+	}
+
+	rates := Rates(ex, currencies, candleMap)
+	for _, currency := range currencies {
+		value, ok := ToKRW(amounts[currency], currency, rates)
+		if !ok {
+			missing = append(missing, currency)
+			continue
+		}
+
+		equity += value
+	}
+
+	return
+}
+
+func isExcludedCurrency(currency string, excluded []string) bool {
+	for _, value := range excluded {
+		if value == currency {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,247 @@
+package reconcile
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"raindrop/main/alert"
+	"raindrop/main/exchange"
+	"raindrop/main/model"
+	"raindrop/main/quote"
+	"raindrop/main/state"
+	"sort"
+	"strconv"
+	"time"
+)
+
+/*
+ * 거래소 잔고와 봇 보유 수량(봇 주문 체결량 + 편입 수량) 대사
+ * 수동 매매, 입출금, 에어드랍, 부분 체결 등으로 생긴 차이를 찾아 알림을 보내고
+ * 코인별 정책에 따라 편입(adopt)하거나 무시(ignore)한다.
+ */
+
+const (
+	PolicyAdopt  = "adopt"
+	PolicyIgnore = "ignore"
+)
+
+const (
+	// 체결 진행 중인 봇 주문이 있어 생긴 차이 (다음 대사에서 다시 확인)
+	KindPartialFill = "partial_fill"
+	// 거래소 잔고가 더 많음 (수동 매수, 입금, 에어드랍)
+	KindIncrease = "increase"
+	// 거래소 잔고가 더 적음 (수동 매도, 출금)
+	KindDecrease = "decrease"
+)
+
+// 수량 비교 최소 허용 오차
+const minTolerance = 1e-8
+
+type Difference struct {
+	Currency string
+	Market   string
+	Exchange float64
+	Bot      float64
+	Diff     float64
+	Kind     string
+	Policy   string
+}
+
+func (diff *Difference) String() string {
+	return fmt.Sprintf("%s 거래소 %.8f, 봇 %.8f, 차이 %+.8f (%s, 정책 %s)",
+		diff.Market, diff.Exchange, diff.Bot, diff.Diff, diff.Kind, diff.Policy)
+}
+
+type Reconciler struct {
+	exchange exchange.Exchange
+	store    *state.Store
+	config   model.ReconcileConfig
+	// 유니버스 선정으로 targets 가 바뀔 수 있어 설정을 참조한다.
+	strategy *model.LarryStrategyConfig
+	logger   *log.Logger
+	notifier *alert.Notifier
+
+	interval  time.Duration
+	lastCheck time.Time
+	// 이미 알린 차이 (같은 차이는 다시 알리지 않는다)
+	reported map[string]float64
+}
+
+func NewReconciler(config *model.Config, logger *log.Logger, store *state.Store, ex exchange.Exchange) *Reconciler {
+	return &Reconciler{
+		exchange: ex,
+		store:    store,
+		config:   config.Reconcile,
+		strategy: &config.LarryStrategy,
+		logger:   logger,
+		notifier: alert.NewNotifier(config.Name, config.Alert.WebhookURL, logger),
+		interval: time.Duration(config.Reconcile.IntervalSecond) * time.Second,
+		reported: make(map[string]float64)}
+}
+
+/*
+ * 코인의 대사 정책, 설정이 없으면 ignore
+ */
+func (reconciler *Reconciler) PolicyOf(currency string) string {
+	if policy, exist := reconciler.config.Policies[currency]; exist {
+		return policy
+	}
+
+	if len(reconciler.config.Policy) > 0 {
+		return reconciler.config.Policy
+	}
+
+	return PolicyIgnore
+}
+
+/*
+ * 설정된 주기가 지났으면 대사를 수행하고 새로 생긴 차이를 알린다.
+ * adopt 정책인 차이는 봇 수량에 편입한다.
+ */
+func (reconciler *Reconciler) Check() (diffs []*Difference, err error) {
+	if reconciler.config.Enable != 1 {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(reconciler.lastCheck) < reconciler.interval {
+		return
+	}
+	reconciler.lastCheck = now
+
+	diffs, err = reconciler.Compare()
+	if err != nil {
+		reconciler.logger.Printf("잔고 대사 실패 : %s\n", err.Error())
+		return
+	}
+
+	current := make(map[string]float64)
+
+	for _, diff := range diffs {
+		current[diff.Market] = diff.Diff
+
+		if reported, exist := reconciler.reported[diff.Market]; !exist || reported != diff.Diff {
+			reconciler.notifier.Notify("잔고 불일치", diff.String())
+		}
+
+		if diff.Policy == PolicyAdopt && diff.Kind != KindPartialFill {
+			if adoptErr := reconciler.Adopt(diff); adoptErr != nil {
+				reconciler.logger.Printf("봇 수량 편입 실패 : %s, %s\n", diff.Market, adoptErr.Error())
+				continue
+			}
+			delete(current, diff.Market)
+		}
+	}
+
+	for market := range reconciler.reported {
+		if _, exist := current[market]; !exist {
+			reconciler.logger.Printf("잔고 불일치 해소 : %s\n", market)
+		}
+	}
+	reconciler.reported = current
+
+	return
+}
+
+/*
+ * 체결 정보를 갱신한 후 거래소 잔고(주문중 포함)와 봇 수량을 비교한다.
+ */
+func (reconciler *Reconciler) Compare() (diffs []*Difference, err error) {
+	if _, syncErr := reconciler.store.SyncFills(reconciler.exchange.OrderFill); syncErr != nil {
+		reconciler.logger.Printf("체결 내역 동기화 실패 : %s\n", syncErr.Error())
+	}
+
+	balances, err := reconciler.exchange.Accounts()
+	if err != nil {
+		return
+	}
+
+	exchangeVolumes := make(map[string]float64)
+	for _, balance := range balances {
+		if quote.IsQuote(balance.Currency, reconciler.strategy.Targets) {
+			continue
+		}
+
+		available, _ := strconv.ParseFloat(balance.Balance, 64)
+		locked, _ := strconv.ParseFloat(balance.Locked, 64)
+		exchangeVolumes[quote.MarketOf(balance.Currency, reconciler.strategy.Targets)] = available + locked
+	}
+
+	positions := reconciler.store.Positions()
+
+	markets := make([]string, 0)
+	for market := range exchangeVolumes {
+		markets = append(markets, market)
+	}
+	for market := range positions {
+		if _, exist := exchangeVolumes[market]; !exist {
+			markets = append(markets, market)
+		}
+	}
+	sort.Strings(markets)
+
+	diffs = make([]*Difference, 0)
+
+	for _, market := range markets {
+		exchangeVolume := exchangeVolumes[market]
+		botVolume := positions[market]
+		diff := exchangeVolume - botVolume
+
+		if math.Abs(diff) <= reconciler.tolerance(exchangeVolume, botVolume) {
+			continue
+		}
+
+		currency := quote.Base(market)
+
+		// 봇이 다루지 않는 코인은 대사하지 않는다.
+		if isExcluded(currency, reconciler.strategy.ExcludedCurrencies) {
+			continue
+		}
+
+		difference := &Difference{
+			Currency: currency,
+			Market:   market,
+			Exchange: exchangeVolume,
+			Bot:      botVolume,
+			Diff:     diff,
+			Kind:     KindIncrease,
+			Policy:   reconciler.PolicyOf(currency)}
+
+		if reconciler.store.HasPendingOrder(market) {
+			difference.Kind = KindPartialFill
+		} else if diff < 0 {
+			difference.Kind = KindDecrease
+		}
+
+		diffs = append(diffs, difference)
+	}
+
+	return
+}
+
+/*
+ * 차이만큼 봇 수량을 조정해 거래소 잔고와 맞춘다.
+ */
+func (reconciler *Reconciler) Adopt(diff *Difference) (err error) {
+	if err = reconciler.store.Adjust(diff.Market, diff.Diff); err != nil {
+		return
+	}
+
+	reconciler.logger.Printf("봇 수량 편입 : %s, %+.8f\n", diff.Market, diff.Diff)
+
+	return
+}
+
+func (reconciler *Reconciler) tolerance(exchangeVolume float64, botVolume float64) float64 {
+	return math.Max(minTolerance, math.Max(exchangeVolume, botVolume)*reconciler.config.Tolerance/100)
+}
+
+func isExcluded(currency string, excluded []string) bool {
+	for _, value := range excluded {
+		if value == currency {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,247 @@
+package recorder
+
+import (
+	"log"
+	"raindrop/main/exchange/upbitapi"
+	"raindrop/main/marketdata"
+	"raindrop/main/model"
+	"time"
+)
+
+/*
+ * 시세 기록기
+ * 타겟 코인의 일봉, 분봉, 현재가, 호가를 주기적으로 조회해 marketdata.Storage 에 저장한다.
+ * 캔들은 완성된 봉만, 이미 저장한 봉 이후부터 기록한다.
+ * API 요청은 upbitapi 의 시세 요청 제한(QuotationLimiter)을 함께 사용한다.
+ */
+
+const (
+	defaultDir                     = "./data"
+	defaultMinuteUnit              = 1
+	defaultCandleIntervalSecond    = 60
+	defaultTickerIntervalSecond    = 10
+	defaultOrderbookIntervalSecond = 10
+	defaultOrderbookDepth          = 5
+
+	// 시작할 때 마지막 기록 시각을 찾을 파티션 기간 (일)
+	lastTimeLookbackDays = 2
+	// 시세 API 한 번에 조회할 마켓 수
+	marketChunkSize = 50
+)
+
+type Recorder struct {
+	config  model.RecorderConfig
+	targets []string
+	api     *upbitapi.Client
+	storage *marketdata.Storage
+	logger  *log.Logger
+
+	// 종류, 마켓별 마지막으로 저장한 캔들 시각
+	lastTimes map[string]map[string]time.Time
+
+	nextCandle    time.Time
+	nextTicker    time.Time
+	nextOrderbook time.Time
+}
+
+func NewRecorder(config model.RecorderConfig, targets []string, logger *log.Logger) *Recorder {
+	if len(config.Dir) == 0 {
+		config.Dir = defaultDir
+	}
+	if config.MinuteUnit <= 0 {
+		config.MinuteUnit = defaultMinuteUnit
+	}
+	if config.CandleIntervalSecond == 0 {
+		config.CandleIntervalSecond = defaultCandleIntervalSecond
+	}
+	if config.TickerIntervalSecond == 0 {
+		config.TickerIntervalSecond = defaultTickerIntervalSecond
+	}
+	if config.OrderbookIntervalSecond == 0 {
+		config.OrderbookIntervalSecond = defaultOrderbookIntervalSecond
+	}
+	if config.OrderbookDepth <= 0 {
+		config.OrderbookDepth = defaultOrderbookDepth
+	}
+
+	return &Recorder{
+		config:    config,
+		targets:   targets,
+		api:       upbitapi.NewClient("", ""),
+		storage:   marketdata.NewStorage(config.Dir),
+		logger:    logger,
+		lastTimes: make(map[string]map[string]time.Time)}
+}
+
+func (recorder *Recorder) Config() model.RecorderConfig {
+	return recorder.config
+}
+
+/*
+ * 종료될 때까지 주기마다 기록한다.
+ */
+func (recorder *Recorder) Run() {
+	recorder.logger.Printf("시세 기록 시작 : %s, 마켓 %v\n", recorder.config.Dir, recorder.targets)
+
+	for {
+		recorder.Tick(time.Now().UTC())
+		time.Sleep(time.Second)
+	}
+}
+
+/*
+ * 기록 주기가 된 데이터를 기록한다.
+ */
+func (recorder *Recorder) Tick(now time.Time) {
+	if due(now, &recorder.nextCandle, recorder.config.CandleIntervalSecond) {
+		recorder.recordCandles(now)
+	}
+
+	if due(now, &recorder.nextTicker, recorder.config.TickerIntervalSecond) {
+		recorder.recordTickers(now)
+	}
+
+	if due(now, &recorder.nextOrderbook, recorder.config.OrderbookIntervalSecond) {
+		recorder.recordOrderbooks(now)
+	}
+}
+
+func due(now time.Time, next *time.Time, intervalSecond int) bool {
+	if intervalSecond < 0 || now.Before(*next) {
+		return false
+	}
+
+	*next = now.Add(time.Duration(intervalSecond) * time.Second)
+
+	return true
+}
+
+/*
+ * 일봉, 분봉 중 새로 완성된 봉을 기록한다.
+ */
+func (recorder *Recorder) recordCandles(now time.Time) {
+	minuteKind := marketdata.MinuteKind(recorder.config.MinuteUnit)
+	minutePeriod := time.Duration(recorder.config.MinuteUnit) * time.Minute
+
+	for _, market := range recorder.targets {
+		recorder.recordNewCandles(marketdata.KindDay, market, 24*time.Hour, now, func(count int) ([]*upbitapi.Candle, error) {
+			return recorder.api.DayCandles(market, count, "")
+		})
+
+		recorder.recordNewCandles(minuteKind, market, minutePeriod, now, func(count int) ([]*upbitapi.Candle, error) {
+			return recorder.api.MinuteCandles(recorder.config.MinuteUnit, market, count, "")
+		})
+	}
+}
+
+/*
+ * 최근 캔들을 조회해 마지막 기록 이후의 완성된 봉만 저장한다.
+ * 가장 최근 봉은 진행 중이므로 저장하지 않는다.
+ * 조회 개수는 마지막 기록 이후 경과 시간으로 정한다. (기록이 없으면 최대 개수)
+ */
+func (recorder *Recorder) recordNewCandles(kind string,
+	market string,
+	period time.Duration,
+	now time.Time,
+	fetch func(count int) ([]*upbitapi.Candle, error)) {
+
+	last := recorder.lastTime(kind, market, now)
+
+	count := upbitapi.MaxCandleCount
+	if !last.IsZero() {
+		count = int(now.Sub(last)/period) + 2
+		if count > upbitapi.MaxCandleCount {
+			count = upbitapi.MaxCandleCount
+		}
+	}
+
+	upbitCandles, err := fetch(count)
+	if err != nil {
+		recorder.logger.Printf("캔들 조회 실패 : %s %s, %s\n", kind, market, err.Error())
+		return
+	}
+
+	candles := make([]*marketdata.Candle, 0, len(upbitCandles))
+	for index, upbitCandle := range upbitCandles {
+		// 최신순 응답의 첫 번째 봉은 진행 중
+		if index == 0 {
+			continue
+		}
+
+		candle := marketdata.FromUpbit(upbitCandle)
+		if candle.Time.After(last) {
+			candles = append(candles, candle)
+		}
+	}
+
+	if len(candles) == 0 {
+		return
+	}
+
+	marketdata.SortByTime(candles)
+
+	if err = recorder.storage.AppendCandles(kind, candles); err != nil {
+		recorder.logger.Printf("캔들 저장 실패 : %s %s, %s\n", kind, market, err.Error())
+		return
+	}
+
+	recorder.lastTimes[kind][market] = candles[len(candles)-1].Time
+	recorder.logger.Printf("캔들 저장 : %s %s, %d 개\n", kind, market, len(candles))
+}
+
+/*
+ * 마지막으로 저장한 캔들 시각, 처음이면 저장소에서 찾는다.
+ */
+func (recorder *Recorder) lastTime(kind string, market string, now time.Time) time.Time {
+	if _, exist := recorder.lastTimes[kind]; !exist {
+		recorder.lastTimes[kind] = make(map[string]time.Time)
+	}
+
+	last, exist := recorder.lastTimes[kind][market]
+	if !exist {
+		last = recorder.storage.LastCandleTime(kind, market, now, lastTimeLookbackDays)
+		recorder.lastTimes[kind][market] = last
+	}
+
+	return last
+}
+
+func (recorder *Recorder) recordTickers(now time.Time) {
+	for _, markets := range chunk(recorder.targets, marketChunkSize) {
+		tickers, err := recorder.api.Tickers(markets)
+		if err != nil {
+			recorder.logger.Printf("현재가 조회 실패 : %s\n", err.Error())
+			continue
+		}
+
+		if err = recorder.storage.AppendTickers(now, tickers); err != nil {
+			recorder.logger.Printf("현재가 저장 실패 : %s\n", err.Error())
+		}
+	}
+}
+
+func (recorder *Recorder) recordOrderbooks(now time.Time) {
+	for _, markets := range chunk(recorder.targets, marketChunkSize) {
+		orderbooks, err := recorder.api.Orderbooks(markets)
+		if err != nil {
+			recorder.logger.Printf("호가 조회 실패 : %s\n", err.Error())
+			continue
+		}
+
+		if err = recorder.storage.AppendOrderbooks(now, orderbooks, recorder.config.OrderbookDepth); err != nil {
+			recorder.logger.Printf("호가 저장 실패 : %s\n", err.Error())
+		}
+	}
+}
+
+func chunk(markets []string, size int) (chunks [][]string) {
+	for start := 0; start < len(markets); start += size {
+		end := start + size
+		if end > len(markets) {
+			end = len(markets)
+		}
+		chunks = append(chunks, markets[start:end])
+	}
+
+	return
+}
@@ -0,0 +1,591 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"math"
+	"raindrop/main/marketdata"
+	"raindrop/main/model"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+ * 외부 리소스 없이 열리는 HTML 성과 리포트
+ * 차트는 SVG 로 직접 그려 한 파일에 넣는다. 백테스트 결과와 실거래 기록에 같이 사용한다.
+ */
+
+type HTMLInput struct {
+	Title       string
+	InitialCash float64
+	Trades      []*model.Trade
+	// 일별 평가 금액, 비어 있으면 거래 손익으로 만든다.
+	Equity []*model.EquityPoint
+	// 단순 보유 비교용 마켓별 일봉 (과거 -> 최신 순)
+	Benchmarks map[string][]*marketdata.Candle
+}
+
+type Series struct {
+	Name   string
+	Points []*model.EquityPoint
+}
+
+type coinContribution struct {
+	Market  string
+	Count   int
+	WinRate float64
+	Profit  float64
+	Share   float64
+}
+
+type monthReturn struct {
+	Valid  bool
+	Return float64
+	Color  template.CSS
+}
+
+type yearReturns struct {
+	Year   int
+	Months [12]monthReturn
+	Total  float64
+}
+
+var chartColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"}
+
+const (
+	chartWidth  = 900
+	chartHeight = 300
+	chartMargin = 50
+)
+
+/*
+ * 거래 손익을 매도일 기준으로 더해 일별 평가 금액을 만든다.
+ */
+func EquityFromTrades(initialCash float64, trades []*model.Trade) (equity []*model.EquityPoint) {
+	profitMap := make(map[time.Time]float64)
+	for _, trade := range trades {
+		day := truncateDay(trade.ExitTime)
+		profitMap[day] += trade.Profit
+	}
+
+	days := make([]time.Time, 0, len(profitMap))
+	for day := range profitMap {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Before(days[j])
+	})
+
+	equity = make([]*model.EquityPoint, 0, len(days))
+	cash := initialCash
+	for _, day := range days {
+		cash += profitMap[day]
+		equity = append(equity, &model.EquityPoint{Time: day, Equity: cash})
+	}
+
+	return
+}
+
+/*
+ * from ~ to 동안 초기 자금으로 단순 보유했을 때의 평가 금액
+ */
+func BuyAndHold(initialCash float64, candles []*marketdata.Candle, from time.Time, to time.Time) (equity []*model.EquityPoint) {
+	equity = make([]*model.EquityPoint, 0)
+
+	basePrice := 0.0
+	for _, candle := range candles {
+		day := truncateDay(candle.Time)
+		if day.Before(truncateDay(from)) || day.After(to) {
+			continue
+		}
+
+		if basePrice <= 0 {
+			basePrice = candle.Open
+			if basePrice <= 0 {
+				continue
+			}
+		}
+
+		equity = append(equity, &model.EquityPoint{Time: day, Equity: initialCash * candle.Close / basePrice})
+	}
+
+	return
+}
+
+/*
+ * 평가 금액의 고점 대비 낙폭 (%, 0 이하)
+ */
+func Drawdowns(initialCash float64, equity []*model.EquityPoint) (drawdowns []*model.EquityPoint) {
+	drawdowns = make([]*model.EquityPoint, 0, len(equity))
+
+	peak := initialCash
+	for _, point := range equity {
+		peak = math.Max(peak, point.Equity)
+
+		drawdown := 0.0
+		if peak > 0 {
+			drawdown = (point.Equity/peak - 1) * 100
+		}
+		drawdowns = append(drawdowns, &model.EquityPoint{Time: point.Time, Equity: drawdown})
+	}
+
+	return
+}
+
+/*
+ * HTML 리포트를 w 에 쓴다.
+ */
+func WriteHTML(w io.Writer, input HTMLInput) error {
+	equity := input.Equity
+	if len(equity) == 0 {
+		equity = EquityFromTrades(input.InitialCash, input.Trades)
+	}
+
+	trades := append([]*model.Trade{}, input.Trades...)
+	sort.Slice(trades, func(i, j int) bool {
+		return trades[i].EntryTime.Before(trades[j].EntryTime)
+	})
+
+	data := map[string]interface{}{
+		"Title":       input.Title,
+		"GeneratedAt": time.Now().Format("2006-01-02 15:04"),
+		"Trades":      trades,
+		"HasEquity":   len(equity) > 0,
+	}
+
+	if len(equity) > 0 {
+		from, to := equity[0].Time, equity[len(equity)-1].Time
+		data["From"] = from.Format("2006-01-02")
+		data["To"] = to.Format("2006-01-02")
+
+		// 전략 평가 금액과 마켓별 단순 보유 비교
+		comparison := []Series{{Name: "전략", Points: equity}}
+		markets := make([]string, 0, len(input.Benchmarks))
+		for market := range input.Benchmarks {
+			markets = append(markets, market)
+		}
+		sort.Strings(markets)
+
+		benchmarkRows := make([][]string, 0, len(markets))
+		for _, market := range markets {
+			points := BuyAndHold(input.InitialCash, input.Benchmarks[market], from, to)
+			if len(points) == 0 {
+				continue
+			}
+
+			comparison = append(comparison, Series{Name: market + " 보유", Points: points})
+			benchmarkRows = append(benchmarkRows, []string{market,
+				fmt.Sprintf("%.2f", (points[len(points)-1].Equity/input.InitialCash-1)*100),
+				fmt.Sprintf("%.2f", maxDrawdownOf(input.InitialCash, points))})
+		}
+
+		data["EquityChart"] = lineChart([]Series{{Name: "평가 금액", Points: equity}}, "%.0f")
+		data["DrawdownChart"] = lineChart([]Series{{Name: "낙폭(%)", Points: Drawdowns(input.InitialCash, equity)}}, "%.1f")
+		data["ComparisonChart"] = lineChart(comparison, "%.0f")
+		data["Benchmarks"] = benchmarkRows
+		data["Strategy"] = []string{
+			fmt.Sprintf("%.2f", (equity[len(equity)-1].Equity/input.InitialCash-1)*100),
+			fmt.Sprintf("%.2f", maxDrawdownOf(input.InitialCash, equity))}
+		data["Months"] = monthlyReturns(input.InitialCash, equity)
+	}
+
+	contributions := coinContributions(trades)
+	data["Contributions"] = contributions
+	data["ContributionChart"] = contributionChart(contributions)
+
+	kValues := make([]float64, 0, len(trades))
+	malScores := make([]float64, 0, len(trades))
+	for _, trade := range trades {
+		if trade.KValue > 0 {
+			kValues = append(kValues, trade.KValue)
+		}
+		if trade.MalScore > 0 {
+			malScores = append(malScores, trade.MalScore)
+		}
+	}
+	data["KChart"] = histogram(kValues, 10)
+	data["MalChart"] = histogram(malScores, 10)
+
+	winCount := 0
+	totalProfit := 0.0
+	for _, trade := range trades {
+		totalProfit += trade.Profit
+		if trade.Profit > 0 {
+			winCount++
+		}
+	}
+	data["TradeCount"] = len(trades)
+	data["TotalProfit"] = fmt.Sprintf("%.0f", totalProfit)
+	if len(trades) > 0 {
+		data["WinRate"] = fmt.Sprintf("%.1f", float64(winCount)/float64(len(trades))*100)
+	}
+
+	return htmlTemplate.Execute(w, data)
+}
+
+func coinContributions(trades []*model.Trade) (contributions []*coinContribution) {
+	contributionMap := make(map[string]*coinContribution)
+	totalProfit := 0.0
+	wins := make(map[string]int)
+
+	for _, trade := range trades {
+		contribution, exist := contributionMap[trade.Market]
+		if !exist {
+			contribution = &coinContribution{Market: trade.Market}
+			contributionMap[trade.Market] = contribution
+		}
+
+		contribution.Count++
+		contribution.Profit += trade.Profit
+		if trade.Profit > 0 {
+			wins[trade.Market]++
+		}
+		totalProfit += math.Abs(trade.Profit)
+	}
+
+	contributions = make([]*coinContribution, 0, len(contributionMap))
+	for market, contribution := range contributionMap {
+		contribution.WinRate = float64(wins[market]) / float64(contribution.Count) * 100
+		if totalProfit > 0 {
+			contribution.Share = contribution.Profit / totalProfit * 100
+		}
+		contributions = append(contributions, contribution)
+	}
+
+	sort.Slice(contributions, func(i, j int) bool {
+		return contributions[i].Profit > contributions[j].Profit
+	})
+
+	return
+}
+
+/*
+ * 월별 수익률 (직전 월말 평가 금액 대비, 첫 달은 초기 자금 대비)
+ */
+func monthlyReturns(initialCash float64, equity []*model.EquityPoint) (years []*yearReturns) {
+	years = make([]*yearReturns, 0)
+
+	prevMonthEnd := initialCash
+	yearStart := initialCash
+	for index, point := range equity {
+		lastOfMonth := index == len(equity)-1 ||
+			equity[index+1].Time.Month() != point.Time.Month() ||
+			equity[index+1].Time.Year() != point.Time.Year()
+		if !lastOfMonth {
+			continue
+		}
+
+		if len(years) == 0 || years[len(years)-1].Year != point.Time.Year() {
+			if len(years) > 0 {
+				yearStart = prevMonthEnd
+			}
+			years = append(years, &yearReturns{Year: point.Time.Year()})
+		}
+
+		year := years[len(years)-1]
+		rate := 0.0
+		if prevMonthEnd > 0 {
+			rate = (point.Equity/prevMonthEnd - 1) * 100
+		}
+
+		year.Months[point.Time.Month()-1] = monthReturn{Valid: true, Return: rate, Color: heatColor(rate)}
+		if yearStart > 0 {
+			year.Total = (point.Equity/yearStart - 1) * 100
+		}
+
+		prevMonthEnd = point.Equity
+	}
+
+	return
+}
+
+/*
+ * 수익률에 따른 배경색 (손실 빨강, 이익 초록, ±10% 에서 가장 진함)
+ */
+func heatColor(rate float64) template.CSS {
+	intensity := math.Min(math.Abs(rate)/10, 1)
+	fade := int(255 - intensity*155)
+
+	if rate < 0 {
+		return template.CSS(fmt.Sprintf("background-color: rgb(255,%d,%d)", fade, fade))
+	}
+
+	return template.CSS(fmt.Sprintf("background-color: rgb(%d,255,%d)", fade, fade))
+}
+
+func maxDrawdownOf(initialCash float64, equity []*model.EquityPoint) (mdd float64) {
+	for _, point := range Drawdowns(initialCash, equity) {
+		mdd = math.Max(mdd, -point.Equity)
+	}
+
+	return
+}
+
+/*
+ * 시계열 선 차트 (SVG)
+ */
+func lineChart(seriesList []Series, valueFormat string) template.HTML {
+	first, last := time.Time{}, time.Time{}
+	minValue, maxValue := math.Inf(1), math.Inf(-1)
+
+	for _, series := range seriesList {
+		for _, point := range series.Points {
+			if first.IsZero() || point.Time.Before(first) {
+				first = point.Time
+			}
+			if point.Time.After(last) {
+				last = point.Time
+			}
+			minValue = math.Min(minValue, point.Equity)
+			maxValue = math.Max(maxValue, point.Equity)
+		}
+	}
+
+	if first.IsZero() {
+		return template.HTML("<p>데이터 없음</p>")
+	}
+	if maxValue == minValue {
+		maxValue = minValue + 1
+	}
+
+	span := last.Sub(first).Seconds()
+	if span <= 0 {
+		span = 1
+	}
+
+	plotWidth := float64(chartWidth - chartMargin*2)
+	plotHeight := float64(chartHeight - chartMargin*2)
+
+	x := func(t time.Time) float64 {
+		return chartMargin + t.Sub(first).Seconds()/span*plotWidth
+	}
+	y := func(value float64) float64 {
+		return chartMargin + (maxValue-value)/(maxValue-minValue)*plotHeight
+	}
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, `<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">`, chartWidth, chartHeight)
+	writeAxes(&buffer)
+
+	fmt.Fprintf(&buffer, `<text x="%d" y="%d" font-size="11" text-anchor="end">`+valueFormat+`</text>`, chartMargin-4, chartMargin+4, maxValue)
+	fmt.Fprintf(&buffer, `<text x="%d" y="%d" font-size="11" text-anchor="end">`+valueFormat+`</text>`, chartMargin-4, chartHeight-chartMargin, minValue)
+	fmt.Fprintf(&buffer, `<text x="%d" y="%d" font-size="11">%s</text>`, chartMargin, chartHeight-chartMargin+16, first.Format("2006-01-02"))
+	fmt.Fprintf(&buffer, `<text x="%d" y="%d" font-size="11" text-anchor="end">%s</text>`, chartWidth-chartMargin, chartHeight-chartMargin+16, last.Format("2006-01-02"))
+
+	if minValue < 0 && maxValue > 0 {
+		fmt.Fprintf(&buffer, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ccc" stroke-dasharray="4"/>`,
+			chartMargin, y(0), chartWidth-chartMargin, y(0))
+	}
+
+	for index, series := range seriesList {
+		color := chartColors[index%len(chartColors)]
+
+		points := make([]string, 0, len(series.Points))
+		for _, point := range series.Points {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x(point.Time), y(point.Equity)))
+		}
+
+		width := 1.2
+		if index == 0 {
+			width = 2.2
+		}
+		fmt.Fprintf(&buffer, `<polyline fill="none" stroke="%s" stroke-width="%.1f" points="%s"/>`, color, width, strings.Join(points, " "))
+
+		// 범례
+		legendY := 14 + index*14
+		fmt.Fprintf(&buffer, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`, chartMargin+10, legendY-9, color)
+		fmt.Fprintf(&buffer, `<text x="%d" y="%d" font-size="11">%s</text>`, chartMargin+24, legendY, html.EscapeString(series.Name))
+	}
+
+	buffer.WriteString(`</svg>`)
+
+	return template.HTML(buffer.String())
+}
+
+/*
+ * 코인별 손익 막대 차트 (SVG, 이익 초록, 손실 빨강)
+ */
+func contributionChart(contributions []*coinContribution) template.HTML {
+	if len(contributions) == 0 {
+		return template.HTML("<p>거래 없음</p>")
+	}
+
+	maxAbs := 0.0
+	for _, contribution := range contributions {
+		maxAbs = math.Max(maxAbs, math.Abs(contribution.Profit))
+	}
+	if maxAbs == 0 {
+		maxAbs = 1
+	}
+
+	const barHeight = 18
+	const labelWidth = 110
+	height := len(contributions)*(barHeight+4) + 10
+	center := float64(labelWidth) + float64(chartWidth-labelWidth)/2
+	halfWidth := float64(chartWidth-labelWidth)/2 - 60
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, `<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">`, chartWidth, height)
+	fmt.Fprintf(&buffer, `<line x1="%.1f" y1="0" x2="%.1f" y2="%d" stroke="#999"/>`, center, center, height)
+
+	for index, contribution := range contributions {
+		top := 5 + index*(barHeight+4)
+		width := math.Abs(contribution.Profit) / maxAbs * halfWidth
+		left, color, anchor, labelX := center, "#2ca02c", "start", center+width+4
+		if contribution.Profit < 0 {
+			left, color, anchor, labelX = center-width, "#d62728", "end", center-width-4
+		}
+
+		fmt.Fprintf(&buffer, `<text x="4" y="%d" font-size="12">%s</text>`, top+13, html.EscapeString(contribution.Market))
+		fmt.Fprintf(&buffer, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s"/>`, left, top, width, barHeight, color)
+		fmt.Fprintf(&buffer, `<text x="%.1f" y="%d" font-size="11" text-anchor="%s">%.0f</text>`, labelX, top+13, anchor, contribution.Profit)
+	}
+
+	buffer.WriteString(`</svg>`)
+
+	return template.HTML(buffer.String())
+}
+
+/*
+ * 값 분포 히스토그램 (SVG)
+ */
+func histogram(values []float64, binCount int) template.HTML {
+	if len(values) == 0 {
+		return template.HTML("<p>기록 없음</p>")
+	}
+
+	minValue, maxValue := values[0], values[0]
+	for _, value := range values {
+		minValue = math.Min(minValue, value)
+		maxValue = math.Max(maxValue, value)
+	}
+	if maxValue == minValue {
+		maxValue = minValue + 1
+	}
+
+	bins := make([]int, binCount)
+	binWidth := (maxValue - minValue) / float64(binCount)
+	for _, value := range values {
+		index := int((value - minValue) / binWidth)
+		if index >= binCount {
+			index = binCount - 1
+		}
+		bins[index]++
+	}
+
+	maxCount := 0
+	for _, count := range bins {
+		if count > maxCount {
+			maxCount = count
+		}
+	}
+
+	const width, height, margin = 440, 220, 30
+	barWidth := float64(width-margin*2) / float64(binCount)
+	plotHeight := float64(height - margin*2)
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, `<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">`, width, height)
+	fmt.Fprintf(&buffer, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#999"/>`, margin, height-margin, width-margin, height-margin)
+
+	for index, count := range bins {
+		barHeight := float64(count) / float64(maxCount) * plotHeight
+		left := float64(margin) + float64(index)*barWidth
+		fmt.Fprintf(&buffer, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="#1f77b4"/>`,
+			left+1, float64(height-margin)-barHeight, barWidth-2, barHeight)
+		if count > 0 {
+			fmt.Fprintf(&buffer, `<text x="%.1f" y="%.1f" font-size="10" text-anchor="middle">%d</text>`,
+				left+barWidth/2, float64(height-margin)-barHeight-3, count)
+		}
+	}
+
+	fmt.Fprintf(&buffer, `<text x="%d" y="%d" font-size="11">%.2f</text>`, margin, height-margin+14, minValue)
+	fmt.Fprintf(&buffer, `<text x="%d" y="%d" font-size="11" text-anchor="end">%.2f</text>`, width-margin, height-margin+14, maxValue)
+	buffer.WriteString(`</svg>`)
+
+	return template.HTML(buffer.String())
+}
+
+func writeAxes(buffer *bytes.Buffer) {
+	fmt.Fprintf(buffer, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#999"/>`,
+		chartMargin, chartMargin, chartMargin, chartHeight-chartMargin)
+	fmt.Fprintf(buffer, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#999"/>`,
+		chartMargin, chartHeight-chartMargin, chartWidth-chartMargin, chartHeight-chartMargin)
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.Local().Format("2006-01-02 15:04") },
+	"num":  func(format string, value float64) string { return fmt.Sprintf(format, value) },
+}).Parse(`<!DOCTYPE html>
+<html lang="ko">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 24px; color: #222; }
+h1 { font-size: 22px; }
+h2 { font-size: 17px; margin-top: 32px; border-bottom: 1px solid #ddd; padding-bottom: 4px; }
+table { border-collapse: collapse; font-size: 12px; }
+th, td { border: 1px solid #ddd; padding: 3px 8px; text-align: right; }
+th { background: #f4f4f4; }
+td.left { text-align: left; }
+.loss { color: #d62728; }
+.charts { display: flex; gap: 24px; flex-wrap: wrap; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>생성 : {{.GeneratedAt}}{{if .HasEquity}}, 기간 : {{.From}} ~ {{.To}}{{end}}, 거래 {{.TradeCount}} 건{{if .WinRate}}, 승률 {{.WinRate}}%{{end}}, 손익 {{.TotalProfit}}</p>
+
+{{if .HasEquity}}
+<h2>평가 금액</h2>
+{{.EquityChart}}
+
+<h2>낙폭</h2>
+{{.DrawdownChart}}
+
+<h2>월별 수익률 (%)</h2>
+<table>
+<tr><th>연도</th><th>1</th><th>2</th><th>3</th><th>4</th><th>5</th><th>6</th><th>7</th><th>8</th><th>9</th><th>10</th><th>11</th><th>12</th><th>연간</th></tr>
+{{range .Months}}<tr><th>{{.Year}}</th>{{range .Months}}{{if .Valid}}<td style="{{.Color}}">{{num "%.2f" .Return}}</td>{{else}}<td></td>{{end}}{{end}}<td>{{num "%.2f" .Total}}</td></tr>
+{{end}}</table>
+
+<h2>단순 보유 비교</h2>
+{{.ComparisonChart}}
+<table>
+<tr><th>대상</th><th>수익률(%)</th><th>MDD(%)</th></tr>
+<tr><td class="left">전략</td><td>{{index .Strategy 0}}</td><td>{{index .Strategy 1}}</td></tr>
+{{range .Benchmarks}}<tr><td class="left">{{index . 0}} 보유</td><td>{{index . 1}}</td><td>{{index . 2}}</td></tr>
+{{end}}</table>
+{{end}}
+
+<h2>코인별 기여</h2>
+{{.ContributionChart}}
+<table>
+<tr><th>코인</th><th>거래수</th><th>승률(%)</th><th>손익</th><th>비중(%)</th></tr>
+{{range .Contributions}}<tr><td class="left">{{.Market}}</td><td>{{.Count}}</td><td>{{num "%.1f" .WinRate}}</td><td{{if lt .Profit 0.0}} class="loss"{{end}}>{{num "%.0f" .Profit}}</td><td>{{num "%.1f" .Share}}</td></tr>
+{{end}}</table>
+
+<h2>진입 분포</h2>
+<div class="charts">
+<div><h3>k</h3>{{.KChart}}</div>
+<div><h3>이평 스코어</h3>{{.MalChart}}</div>
+</div>
+
+<h2>거래 목록</h2>
+<table>
+<tr><th>전략</th><th>코인</th><th>매수시각</th><th>매도시각</th><th>수량</th><th>매수가</th><th>매도가</th><th>수수료</th><th>손익</th><th>수익률(%)</th><th>k</th><th>이평 스코어</th></tr>
+{{range .Trades}}<tr><td class="left">{{.Strategy}}</td><td class="left">{{.Market}}</td><td>{{date .EntryTime}}</td><td>{{date .ExitTime}}</td><td>{{num "%.8f" .Volume}}</td><td>{{num "%.4f" .EntryPrice}}</td><td>{{num "%.4f" .ExitPrice}}</td><td>{{num "%.0f" .Fee}}</td><td{{if lt .Profit 0.0}} class="loss"{{end}}>{{num "%.0f" .Profit}}</td><td>{{num "%.2f" .ProfitRate}}</td><td>{{num "%.3f" .KValue}}</td><td>{{num "%.2f" .MalScore}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,150 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"raindrop/main/model"
+	"raindrop/main/quote"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+type StrategySummary struct {
+	Strategy    string
+	TradeCount  int
+	WinCount    int
+	TotalProfit float64
+	TotalFee    float64
+	AvgRate     float64
+}
+
+/*
+ * 전략별 성과 요약
+ */
+func SummarizeByStrategy(trades []*model.Trade) (summaries []*StrategySummary) {
+	summaryMap := make(map[string]*StrategySummary)
+
+	for _, trade := range trades {
+		summary, exist := summaryMap[trade.Strategy]
+		if !exist {
+			summary = &StrategySummary{Strategy: trade.Strategy}
+			summaryMap[trade.Strategy] = summary
+		}
+
+		summary.TradeCount++
+		if trade.Profit > 0 {
+			summary.WinCount++
+		}
+		summary.TotalProfit += trade.Profit
+		summary.TotalFee += trade.Fee
+		summary.AvgRate += trade.ProfitRate
+	}
+
+	summaries = make([]*StrategySummary, 0, len(summaryMap))
+	for _, summary := range summaryMap {
+		summary.AvgRate /= float64(summary.TradeCount)
+		summaries = append(summaries, summary)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Strategy < summaries[j].Strategy
+	})
+
+	return
+}
+
+/*
+ * 기간과 전략으로 거래를 거른다. 비어 있는 조건은 적용하지 않는다.
+ */
+func FilterTrades(trades []*model.Trade, strategy string, since time.Time) (filtered []*model.Trade) {
+	filtered = make([]*model.Trade, 0, len(trades))
+
+	for _, trade := range trades {
+		if len(strategy) > 0 && trade.Strategy != strategy {
+			continue
+		}
+		if !since.IsZero() && trade.ExitTime.Before(since) {
+			continue
+		}
+		filtered = append(filtered, trade)
+	}
+
+	return
+}
+
+/*
+ * 거래의 수수료, 손익을 원화로 환산한다. (가격은 기준 통화 그대로 둔다)
+ * 환산 비율이 없는 기준 통화의 거래는 제외하고 해당 기준 통화 목록을 돌려준다.
+ */
+func ConvertToKRW(trades []*model.Trade, rates map[string]float64) (converted []*model.Trade, missing []string) {
+	converted = make([]*model.Trade, 0, len(trades))
+	missingMap := make(map[string]bool)
+
+	for _, trade := range trades {
+		currency := quote.Of(trade.Market)
+
+		rate, exist := rates[currency]
+		if !exist {
+			if !missingMap[currency] {
+				missingMap[currency] = true
+				missing = append(missing, currency)
+			}
+			continue
+		}
+
+		krwTrade := *trade
+		krwTrade.Fee *= rate
+		krwTrade.Profit *= rate
+		converted = append(converted, &krwTrade)
+	}
+
+	return
+}
+
+/*
+ * 전략별 요약 출력, 수수료와 손익은 원화로 환산된 거래를 받는다.
+ */
+func PrintSummary(w io.Writer, trades []*model.Trade) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "전략\t거래수\t승률(%)\t평균수익률(%)\t수수료(KRW)\t손익(KRW)\t")
+
+	for _, summary := range SummarizeByStrategy(trades) {
+		fmt.Fprintf(table, "%s\t%d\t%.1f\t%.2f\t%.0f\t%.0f\t\n",
+			summary.Strategy,
+			summary.TradeCount,
+			float64(summary.WinCount)/float64(summary.TradeCount)*100,
+			summary.AvgRate,
+			summary.TotalFee,
+			summary.TotalProfit)
+	}
+
+	table.Flush()
+}
+
+func PrintTrades(w io.Writer, trades []*model.Trade) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "전략\t코인\t매수시각\t매도시각\t수량\t매수가\t매도가\t수수료\t손익\t수익률(%)\t")
+
+	for _, trade := range trades {
+		format := "%s\t%s\t%s\t%s\t%.8f\t%.4f\t%.4f\t%.0f\t%.0f\t%.2f\t\n"
+		if quote.Of(trade.Market) != quote.KRW {
+			// 원화 외 마켓은 가격, 손익을 기준 통화 단위로 출력한다.
+			format = "%s\t%s\t%s\t%s\t%.8f\t%.8f\t%.8f\t%.8f\t%.8f\t%.2f\t\n"
+		}
+
+		fmt.Fprintf(table, format,
+			trade.Strategy,
+			trade.Market,
+			trade.EntryTime.Local().Format("2006-01-02 15:04"),
+			trade.ExitTime.Local().Format("2006-01-02 15:04"),
+			trade.Volume,
+			trade.EntryPrice,
+			trade.ExitPrice,
+			trade.Fee,
+			trade.Profit,
+			trade.ProfitRate)
+	}
+
+	table.Flush()
+}
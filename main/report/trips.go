@@ -0,0 +1,99 @@
+package report
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"math"
+	"raindrop/main/fee"
+	"raindrop/main/model"
+	"raindrop/main/state"
+)
+
+/*
+ * 봇이 낸 주문의 체결 기록으로 왕복 거래 목록을 만든다.
+ * 전략 + 코인별로 매수 체결량을 먼저 들어온 순서대로 매도 체결량과 짝짓는다.
+ * 체결 기록에 수수료가 없으면 수수료 모델로 추정한다.
+ */
+
+// 마켓별 수수료 모델
+type FeeModelFunc func(market string) *fee.Model
+
+type lot struct {
+	record *state.OrderRecord
+	volume float64
+	fee    float64
+}
+
+func RoundTrips(store *state.Store, feeModelOf FeeModelFunc) (trades []*model.Trade) {
+	trades = make([]*model.Trade, 0)
+
+	openLots := make(map[string][]*lot)
+
+	for _, record := range store.FilledOrders() {
+		key := record.Strategy + "|" + record.Market
+		paidFee := estimateFee(record, feeModelOf)
+
+		switch record.Side {
+		case types.ORDERSIDE_BID:
+			openLots[key] = append(openLots[key], &lot{
+				record: record,
+				volume: record.ExecutedVolume,
+				fee:    paidFee})
+
+		case types.ORDERSIDE_ASK:
+			remaining := record.ExecutedVolume
+			exitFeePerVolume := paidFee / record.ExecutedVolume
+
+			for remaining > 0 && len(openLots[key]) > 0 {
+				entry := openLots[key][0]
+				volume := math.Min(entry.volume, remaining)
+
+				entryFee := entry.fee * volume / entry.volume
+				exitFee := exitFeePerVolume * volume
+				cost := entry.record.AvgPrice*volume + entryFee
+				profit := record.AvgPrice*volume - exitFee - cost
+
+				trade := &model.Trade{
+					Strategy:   record.Strategy,
+					Market:     record.Market,
+					EntryTime:  entry.record.CreatedAt,
+					ExitTime:   record.CreatedAt,
+					Volume:     volume,
+					EntryPrice: entry.record.AvgPrice,
+					ExitPrice:  record.AvgPrice,
+					Fee:        entryFee + exitFee,
+					Profit:     profit,
+					KValue:     entry.record.KValue,
+					MalScore:   entry.record.MalScore}
+
+				if cost > 0 {
+					trade.ProfitRate = profit / cost * 100
+				}
+
+				trades = append(trades, trade)
+
+				entry.fee -= entryFee
+				entry.volume -= volume
+				remaining -= volume
+
+				if entry.volume <= 0 {
+					openLots[key] = openLots[key][1:]
+				}
+			}
+		}
+	}
+
+	return
+}
+
+func estimateFee(record *state.OrderRecord, feeModelOf FeeModelFunc) float64 {
+	if record.PaidFee > 0 || feeModelOf == nil {
+		return record.PaidFee
+	}
+
+	total := record.AvgPrice * record.ExecutedVolume
+	if record.Side == types.ORDERSIDE_BID {
+		return feeModelOf(record.Market).BidFee(total)
+	}
+
+	return feeModelOf(record.Market).AskFee(total)
+}
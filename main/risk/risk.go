@@ -0,0 +1,167 @@
+package risk
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"math"
+)
+
+/*
+ * 일봉 수익률로 보유 포지션의 변동성과 상관계수를 추정한다.
+ * 일봉은 최신순이고, 같은 인덱스는 같은 날로 본다. (원화 마켓은 같은 시각에 일봉이 바뀐다.)
+ * 기간이 다른 두 코인은 겹치는 최근 기간만 사용한다.
+ */
+
+/*
+ * 완료된 일봉(candles[1:])의 일간 수익률 (최신순, 최대 lookback 개)
+ */
+func Returns(candles []*types.DayCandle, lookback int) (returns []float64) {
+	returns = make([]float64, 0, lookback)
+
+	for index := 1; index+1 < len(candles) && len(returns) < lookback; index++ {
+		prev := candles[index+1].TradePrice
+		if prev <= 0 {
+			break
+		}
+		returns = append(returns, candles[index].TradePrice/prev-1)
+	}
+
+	return
+}
+
+/*
+ * 공분산 (겹치는 최근 기간, 표본이 2개 미만이면 0)
+ */
+func Covariance(a []float64, b []float64) float64 {
+	count := len(a)
+	if len(b) < count {
+		count = len(b)
+	}
+	if count < 2 {
+		return 0
+	}
+
+	meanA, meanB := mean(a[:count]), mean(b[:count])
+
+	sum := 0.0
+	for index := 0; index < count; index++ {
+		sum += (a[index] - meanA) * (b[index] - meanB)
+	}
+
+	return sum / float64(count-1)
+}
+
+/*
+ * 상관계수 (-1 ~ 1), 한쪽 변동이 없거나 표본이 모자라면 0
+ */
+func Correlation(a []float64, b []float64) float64 {
+	count := len(a)
+	if len(b) < count {
+		count = len(b)
+	}
+
+	varianceA := Covariance(a[:count], a[:count])
+	varianceB := Covariance(b[:count], b[:count])
+	if varianceA <= 0 || varianceB <= 0 {
+		return 0
+	}
+
+	return Covariance(a[:count], b[:count]) / math.Sqrt(varianceA*varianceB)
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+
+	return sum / float64(len(values))
+}
+
+/*
+ * 보유 포지션 (원화 평가 금액)과 일간 수익률
+ * Equity : 변동성의 기준 금액 (평가 금액)
+ */
+type Portfolio struct {
+	Equity  float64
+	Values  map[string]float64
+	Returns map[string][]float64
+}
+
+func NewPortfolio(equity float64) *Portfolio {
+	return &Portfolio{
+		Equity:  equity,
+		Values:  make(map[string]float64),
+		Returns: make(map[string][]float64)}
+}
+
+func (portfolio *Portfolio) Add(market string, value float64) {
+	portfolio.Values[market] += value
+}
+
+/*
+ * 평가 금액 대비 일간 변동성 (비율)
+ */
+func (portfolio *Portfolio) Volatility() float64 {
+	return portfolio.VolatilityWith("", 0)
+}
+
+/*
+ * market 을 amount (원화) 만큼 더 샀을 때 평가 금액 대비 일간 변동성 (비율)
+ */
+func (portfolio *Portfolio) VolatilityWith(market string, amount float64) float64 {
+	if portfolio.Equity <= 0 {
+		return 0
+	}
+
+	values := make(map[string]float64)
+	for key, value := range portfolio.Values {
+		values[key] = value
+	}
+	if len(market) > 0 {
+		values[market] += amount
+	}
+
+	variance := 0.0
+	for marketA, valueA := range values {
+		for marketB, valueB := range values {
+			variance += valueA * valueB * Covariance(portfolio.Returns[marketA], portfolio.Returns[marketB])
+		}
+	}
+
+	return math.Sqrt(math.Max(variance, 0)) / portfolio.Equity
+}
+
+/*
+ * 일간 변동성이 target (비율) 을 넘지 않는 market 의 최대 추가 매수 금액 (원화)
+ * 분산 = a x^2 + b x + c 가 (target * 평가 금액)^2 이 되는 x 를 구한다.
+ * 이미 목표를 넘었으면 0, market 의 변동이 없으면 math.Inf(1)
+ */
+func (portfolio *Portfolio) MaxAmount(market string, target float64) float64 {
+	if portfolio.Equity <= 0 || target <= 0 {
+		return 0
+	}
+
+	candidate := portfolio.Returns[market]
+
+	a := Covariance(candidate, candidate)
+	b := 0.0
+	for other, value := range portfolio.Values {
+		b += 2 * value * Covariance(portfolio.Returns[other], candidate)
+	}
+
+	current := portfolio.Volatility() * portfolio.Equity
+	limit := target * portfolio.Equity
+	c := current*current - limit*limit
+	if c >= 0 {
+		return 0
+	}
+
+	if a <= 0 {
+		if b <= 0 {
+			return math.Inf(1)
+		}
+		return -c / b
+	}
+
+	return (-b + math.Sqrt(b*b-4*a*c)) / (2 * a)
+}
@@ -0,0 +1,171 @@
+package risk
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"math"
+	"testing"
+)
+
+func almostEqual(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+/*
+ * 최신순 종가로 일봉을 만든다. (첫 봉은 진행 중인 오늘)
+ */
+func dayCandles(prices ...float64) (candles []*types.DayCandle) {
+	for _, price := range prices {
+		candles = append(candles, &types.DayCandle{TradePrice: price})
+	}
+
+	return
+}
+
+func TestReturns(t *testing.T) {
+	tests := []struct {
+		name     string
+		candles  []*types.DayCandle
+		lookback int
+		want     []float64
+	}{
+		// 오늘 봉(200)은 제외한다.
+		{"completed only", dayCandles(200, 121, 110, 100), 5, []float64{0.1, 0.1}},
+		{"lookback", dayCandles(200, 121, 110, 100), 1, []float64{0.1}},
+		{"zero price", dayCandles(200, 121, 110, 0, 100), 5, []float64{0.1}},
+		{"too few", dayCandles(200, 121), 5, []float64{}},
+	}
+
+	for _, test := range tests {
+		returns := Returns(test.candles, test.lookback)
+		if len(returns) != len(test.want) {
+			t.Errorf("%s : returns = %v, want %v", test.name, returns, test.want)
+			continue
+		}
+		for index := range returns {
+			if !almostEqual(returns[index], test.want[index]) {
+				t.Errorf("%s : returns = %v, want %v", test.name, returns, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestCorrelation(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []float64
+		b    []float64
+		want float64
+	}{
+		{"same direction", []float64{0.01, 0.02, 0.03}, []float64{0.02, 0.04, 0.06}, 1},
+		{"opposite", []float64{0.01, 0.02, 0.03}, []float64{0.03, 0.02, 0.01}, -1},
+		{"uncorrelated", []float64{1, -1, 1, -1}, []float64{1, 1, -1, -1}, 0},
+		// 겹치는 최근 기간만 사용한다.
+		{"overlap", []float64{0.01, 0.02, 0.03, 0.5}, []float64{0.02, 0.04, 0.06}, 1},
+		{"flat", []float64{0.01, 0.02, 0.03}, []float64{0, 0, 0}, 0},
+		{"one sample", []float64{0.01}, []float64{0.02}, 0},
+		{"empty", nil, []float64{0.02, 0.01}, 0},
+	}
+
+	for _, test := range tests {
+		if correlation := Correlation(test.a, test.b); !almostEqual(correlation, test.want) {
+			t.Errorf("%s : correlation = %v, want %v", test.name, correlation, test.want)
+		}
+	}
+}
+
+func TestVolatility(t *testing.T) {
+	// 분산 0.0002, 표준편차 약 1.414%
+	swing := []float64{0.01, -0.01}
+
+	tests := []struct {
+		name    string
+		equity  float64
+		values  map[string]float64
+		returns map[string][]float64
+		want    float64
+	}{
+		{"empty", 10000, nil, nil, 0},
+		{"single", 10000, map[string]float64{"KRW-BTC": 5000}, map[string][]float64{"KRW-BTC": swing},
+			0.5 * math.Sqrt(0.0002)},
+		// 같이 움직이면 변동성이 더해진다.
+		{"correlated", 10000, map[string]float64{"KRW-BTC": 5000, "KRW-ETH": 5000},
+			map[string][]float64{"KRW-BTC": swing, "KRW-ETH": swing}, math.Sqrt(0.0002)},
+		// 반대로 움직이면 상쇄된다.
+		{"hedged", 10000, map[string]float64{"KRW-BTC": 5000, "KRW-ETH": 5000},
+			map[string][]float64{"KRW-BTC": swing, "KRW-ETH": {-0.01, 0.01}}, 0},
+		{"no returns", 10000, map[string]float64{"KRW-BTC": 5000}, nil, 0},
+		{"no equity", 0, map[string]float64{"KRW-BTC": 5000}, map[string][]float64{"KRW-BTC": swing}, 0},
+	}
+
+	for _, test := range tests {
+		portfolio := NewPortfolio(test.equity)
+		for market, value := range test.values {
+			portfolio.Add(market, value)
+		}
+		for market, returns := range test.returns {
+			portfolio.Returns[market] = returns
+		}
+
+		if volatility := portfolio.Volatility(); !almostEqual(volatility, test.want) {
+			t.Errorf("%s : volatility = %v, want %v", test.name, volatility, test.want)
+		}
+	}
+}
+
+func TestMaxAmount(t *testing.T) {
+	swing := []float64{0.01, -0.01}
+	reverse := []float64{-0.01, 0.01}
+
+	tests := []struct {
+		name    string
+		equity  float64
+		values  map[string]float64
+		returns map[string][]float64
+		target  float64
+		want    float64
+	}{
+		// 빈 포트폴리오 : 금액 x 의 변동성 x * 1.414% = 1% x 10000
+		{"empty", 10000, nil, map[string][]float64{"KRW-XRP": swing}, 0.01, 100 / math.Sqrt(0.0002)},
+		// 이미 보유한 같은 방향 코인만큼 덜 산다.
+		{"correlated", 10000, map[string]float64{"KRW-BTC": 3000}, map[string][]float64{"KRW-BTC": swing, "KRW-XRP": swing},
+			0.01, 100/math.Sqrt(0.0002) - 3000},
+		// 반대로 움직이는 코인은 보유분을 상쇄한 뒤부터 센다.
+		{"hedge", 10000, map[string]float64{"KRW-BTC": 3000}, map[string][]float64{"KRW-BTC": swing, "KRW-XRP": reverse},
+			0.01, 100/math.Sqrt(0.0002) + 3000},
+		{"over target", 10000, map[string]float64{"KRW-BTC": 10000}, map[string][]float64{"KRW-BTC": swing, "KRW-XRP": swing},
+			0.01, 0},
+		{"flat candidate", 10000, nil, map[string][]float64{"KRW-XRP": {0, 0}}, 0.01, math.Inf(1)},
+		{"no target", 10000, nil, map[string][]float64{"KRW-XRP": swing}, 0, 0},
+		{"no equity", 0, nil, map[string][]float64{"KRW-XRP": swing}, 0.01, 0},
+	}
+
+	for _, test := range tests {
+		portfolio := NewPortfolio(test.equity)
+		for market, value := range test.values {
+			portfolio.Add(market, value)
+		}
+		for market, returns := range test.returns {
+			portfolio.Returns[market] = returns
+		}
+
+		amount := portfolio.MaxAmount("KRW-XRP", test.target)
+		if math.IsInf(test.want, 1) {
+			if !math.IsInf(amount, 1) {
+				t.Errorf("%s : amount = %v, want +Inf", test.name, amount)
+			}
+			continue
+		}
+		if math.Abs(amount-test.want) > 1e-6 {
+			t.Errorf("%s : amount = %v, want %v", test.name, amount, test.want)
+			continue
+		}
+
+		// 최대 금액만큼 사면 변동성이 목표와 같아진다.
+		if amount > 0 {
+			if volatility := portfolio.VolatilityWith("KRW-XRP", amount); !almostEqual(volatility, test.target) {
+				t.Errorf("%s : volatility = %v, want %v", test.name, volatility, test.target)
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package rules
+
+import (
+	"fmt"
+	"github.com/jekeun/upbit-go/types"
+	"raindrop/main/money"
+	"raindrop/main/quote"
+	"strings"
+)
+
+/*
+ * 거래소 마켓별 주문 규칙 (호가 단위, 최소 주문 금액, 수량 자리수)
+ * 주문 가격은 매수는 내림, 매도는 올림으로 호가 단위에 맞춘다. (설정한 가격보다 불리하게 체결되지 않도록)
+ * 주문 수량은 항상 내림한다. (보유량, 주문 가능 금액을 넘지 않도록)
+ */
+
+type tick struct {
+	minPrice money.Decimal
+	size     money.Decimal
+}
+
+type Rules struct {
+	Exchange        string
+	Market          string
+	MinTotal        money.Decimal
+	VolumePrecision int
+
+	ticks []tick
+}
+
+func newTicks(table [][2]string) (ticks []tick) {
+	for _, row := range table {
+		ticks = append(ticks, tick{minPrice: money.MustParse(row[0]), size: money.MustParse(row[1])})
+	}
+	return
+}
+
+// Upbit 원화 마켓 호가 단위 (가격 이상 -> 호가 단위), 높은 가격부터
+var upbitKRWTicks = newTicks([][2]string{
+	{"2000000", "1000"},
+	{"1000000", "500"},
+	{"500000", "100"},
+	{"100000", "50"},
+	{"10000", "10"},
+	{"1000", "1"},
+	{"100", "0.1"},
+	{"10", "0.01"},
+	{"1", "0.001"},
+	{"0.1", "0.0001"},
+	{"0.01", "0.00001"},
+	{"0.001", "0.000001"},
+	{"0.0001", "0.0000001"},
+	{"0", "0.00000001"},
+})
+
+// Bithumb 원화 마켓 호가 단위
+var bithumbKRWTicks = newTicks([][2]string{
+	{"1000000", "1000"},
+	{"500000", "500"},
+	{"100000", "100"},
+	{"50000", "50"},
+	{"10000", "10"},
+	{"5000", "5"},
+	{"1000", "1"},
+	{"100", "0.1"},
+	{"10", "0.01"},
+	{"1", "0.001"},
+	{"0", "0.0001"},
+})
+
+// BTC, USDT 마켓 등 원화 외 마켓은 소수점 8자리
+var satoshiTicks = newTicks([][2]string{
+	{"0", "0.00000001"},
+})
+
+// 기준 통화별 최소 주문 금액
+var upbitMinTotals = map[string]string{
+	quote.KRW: "5000",
+	"BTC":     "0.00005",
+	"USDT":    "0.5",
+}
+
+var bithumbMinTotals = map[string]string{
+	quote.KRW: "500",
+	"BTC":     "0.0005",
+}
+
+/*
+ * 거래소 이름(exchange.Exchange.Name)과 마켓으로 주문 규칙을 찾는다.
+ * 모르는 거래소는 Upbit 규칙을 사용한다.
+ */
+func For(exchangeName string, market string) *Rules {
+	quoteCurrency := quote.Of(market)
+
+	rules := &Rules{
+		Exchange:        exchangeName,
+		Market:          market,
+		VolumePrecision: 8,
+		ticks:           satoshiTicks}
+
+	minTotals := upbitMinTotals
+
+	// dry-run 은 "bithumb(dry-run)" 과 같이 원래 거래소 이름으로 시작한다.
+	switch {
+	case strings.HasPrefix(exchangeName, "bithumb"):
+		rules.VolumePrecision = 4
+		minTotals = bithumbMinTotals
+		if quoteCurrency == quote.KRW {
+			rules.ticks = bithumbKRWTicks
+		}
+	default:
+		if quoteCurrency == quote.KRW {
+			rules.ticks = upbitKRWTicks
+		}
+	}
+
+	rules.MinTotal = money.MustParse(minTotals[quoteCurrency])
+
+	return rules
+}
+
+/*
+ * 가격에 해당하는 호가 단위
+ */
+func (rules *Rules) TickSize(price money.Decimal) money.Decimal {
+	for _, tick := range rules.ticks {
+		if price.Cmp(tick.minPrice) >= 0 {
+			return tick.size
+		}
+	}
+
+	return rules.ticks[len(rules.ticks)-1].size
+}
+
+/*
+ * 호가 단위에 맞춘 주문 가격 (매수 내림, 매도 올림)
+ */
+func (rules *Rules) Price(side string, price money.Decimal) money.Decimal {
+	if side == types.ORDERSIDE_ASK {
+		rounded := price.CeilTo(rules.TickSize(price))
+		// 올림으로 가격대가 바뀌면 바뀐 가격대의 호가 단위로 다시 맞춘다.
+		return rounded.CeilTo(rules.TickSize(rounded))
+	}
+
+	rounded := price.FloorTo(rules.TickSize(price))
+	return rounded.FloorTo(rules.TickSize(rounded))
+}
+
+/*
+ * 수량 자리수에 맞춘 주문 수량 (내림)
+ */
+func (rules *Rules) Volume(volume money.Decimal) money.Decimal {
+	return volume.FloorTo(money.Unit(rules.VolumePrecision))
+}
+
+/*
+ * 가격, 수량을 규칙에 맞추고 최소 주문 금액을 확인한다.
+ */
+func (rules *Rules) Normalize(side string, price money.Decimal, volume money.Decimal) (
+	orderPrice money.Decimal,
+	orderVolume money.Decimal,
+	err error) {
+
+	orderPrice = rules.Price(side, price)
+	orderVolume = rules.Volume(volume)
+
+	if orderPrice.Sign() <= 0 || orderVolume.Sign() <= 0 {
+		err = fmt.Errorf("%s : 주문 가격, 수량이 0 이하 (가격 %s, 수량 %s)", rules.Market, orderPrice, orderVolume)
+		return
+	}
+
+	if total := orderPrice.Mul(orderVolume); total.LessThan(rules.MinTotal) {
+		err = fmt.Errorf("%s : 최소 주문 금액 미만 (주문 금액 %s, 최소 %s)", rules.Market, total, rules.MinTotal)
+	}
+
+	return
+}
+
+/*
+ * 지정가 주문 정보를 만든다. (Identifier 는 호출하는 쪽에서 채운다)
+ */
+func (rules *Rules) LimitOrder(side string, price money.Decimal, volume money.Decimal) (
+	orderInfo types.OrderInfo,
+	err error) {
+
+	orderPrice, orderVolume, err := rules.Normalize(side, price, volume)
+	if err != nil {
+		return
+	}
+
+	orderInfo = types.OrderInfo{
+		Side:    side,
+		Market:  rules.Market,
+		Price:   orderPrice.String(),
+		Volume:  orderVolume.String(),
+		OrdType: types.ORDERTYPE_LIMIT}
+
+	return
+}
@@ -0,0 +1,130 @@
+package rules
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"raindrop/main/money"
+	"testing"
+)
+
+func TestTickSize(t *testing.T) {
+	tests := []struct {
+		exchange string
+		market   string
+		price    string
+		want     string
+	}{
+		{"upbit", "KRW-BTC", "2500000", "1000"},
+		{"upbit", "KRW-BTC", "2000000", "1000"},
+		{"upbit", "KRW-BTC", "1999999", "500"},
+		{"upbit", "KRW-ETH", "150000", "50"},
+		{"upbit", "KRW-XRP", "5000", "1"},
+		{"upbit", "KRW-XRP", "999", "0.1"},
+		{"upbit", "KRW-DOGE", "150", "0.1"},
+		{"upbit", "KRW-ABC", "5", "0.001"},
+		{"upbit", "KRW-ABC", "0.05", "0.00001"},
+		{"upbit", "KRW-ABC", "0.00005", "0.00000001"},
+		{"upbit", "BTC-ETH", "0.05", "0.00000001"},
+		{"bithumb", "KRW-BTC", "1500000", "1000"},
+		{"bithumb", "KRW-ETH", "150000", "100"},
+		{"bithumb", "KRW-XRP", "7000", "5"},
+		{"bithumb", "KRW-ABC", "5", "0.001"},
+		{"bithumb", "KRW-ABC", "0.5", "0.0001"},
+		// dry-run 은 원래 거래소 규칙을 따른다.
+		{"bithumb(dry-run)", "KRW-XRP", "7000", "5"},
+		// 모르는 거래소는 Upbit 규칙
+		{"unknown", "KRW-XRP", "7000", "1"},
+	}
+
+	for _, test := range tests {
+		size := For(test.exchange, test.market).TickSize(money.MustParse(test.price))
+		if size.Cmp(money.MustParse(test.want)) != 0 {
+			t.Errorf("%s %s %s : tick = %s, want %s", test.exchange, test.market, test.price, size, test.want)
+		}
+	}
+}
+
+func TestPrice(t *testing.T) {
+	tests := []struct {
+		name     string
+		exchange string
+		side     string
+		price    string
+		want     string
+	}{
+		{"bid floors", "upbit", types.ORDERSIDE_BID, "12345.6", "12340"},
+		{"ask ceils", "upbit", types.ORDERSIDE_ASK, "12345.6", "12350"},
+		{"on tick", "upbit", types.ORDERSIDE_ASK, "12340", "12340"},
+		{"ask crosses band", "upbit", types.ORDERSIDE_ASK, "99995", "100000"},
+		{"bid below band", "upbit", types.ORDERSIDE_BID, "100049", "100000"},
+		{"small price bid", "upbit", types.ORDERSIDE_BID, "1.23456", "1.234"},
+		{"small price ask", "upbit", types.ORDERSIDE_ASK, "1.23456", "1.235"},
+		{"bithumb bid", "bithumb", types.ORDERSIDE_BID, "7003", "7000"},
+		{"bithumb ask", "bithumb", types.ORDERSIDE_ASK, "7003", "7005"},
+	}
+
+	for _, test := range tests {
+		price := For(test.exchange, "KRW-XRP").Price(test.side, money.MustParse(test.price))
+		if price.Cmp(money.MustParse(test.want)) != 0 {
+			t.Errorf("%s : price = %s, want %s", test.name, price, test.want)
+		}
+	}
+}
+
+func TestVolume(t *testing.T) {
+	tests := []struct {
+		exchange string
+		volume   string
+		want     string
+	}{
+		{"upbit", "0.123456789", "0.12345678"},
+		{"upbit", "1.5", "1.5"},
+		{"bithumb", "0.00209876", "0.002"},
+		{"bithumb", "0.00009", "0"},
+	}
+
+	for _, test := range tests {
+		volume := For(test.exchange, "KRW-BTC").Volume(money.MustParse(test.volume))
+		if volume.Cmp(money.MustParse(test.want)) != 0 {
+			t.Errorf("%s %s : volume = %s, want %s", test.exchange, test.volume, volume, test.want)
+		}
+	}
+}
+
+func TestLimitOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		exchange string
+		market   string
+		side     string
+		price    string
+		volume   string
+		want     types.OrderInfo
+		err      bool
+	}{
+		{"upbit bid", "upbit", "KRW-BTC", types.ORDERSIDE_BID, "91234567", "0.001234567", types.OrderInfo{
+			Side: types.ORDERSIDE_BID, Market: "KRW-BTC", Price: "91234000", Volume: "0.00123456", OrdType: types.ORDERTYPE_LIMIT}, false},
+		{"upbit below min total", "upbit", "KRW-XRP", types.ORDERSIDE_BID, "1000", "4.9", types.OrderInfo{}, true},
+		{"bithumb min total", "bithumb", "KRW-XRP", types.ORDERSIDE_ASK, "1000", "0.5", types.OrderInfo{
+			Side: types.ORDERSIDE_ASK, Market: "KRW-XRP", Price: "1000", Volume: "0.5", OrdType: types.ORDERTYPE_LIMIT}, false},
+		{"btc market", "upbit", "BTC-ETH", types.ORDERSIDE_BID, "0.052345678", "0.01", types.OrderInfo{
+			Side: types.ORDERSIDE_BID, Market: "BTC-ETH", Price: "0.05234567", Volume: "0.01", OrdType: types.ORDERTYPE_LIMIT}, false},
+		{"volume rounds to zero", "bithumb", "KRW-BTC", types.ORDERSIDE_BID, "91000000", "0.00001", types.OrderInfo{}, true},
+	}
+
+	for _, test := range tests {
+		orderInfo, err := For(test.exchange, test.market).LimitOrder(test.side, money.MustParse(test.price), money.MustParse(test.volume))
+		if test.err {
+			if err == nil {
+				t.Errorf("%s : 에러여야 함, order = %+v", test.name, orderInfo)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s : %s", test.name, err.Error())
+			continue
+		}
+		if orderInfo != test.want {
+			t.Errorf("%s : order = %+v, want %+v", test.name, orderInfo, test.want)
+		}
+	}
+}
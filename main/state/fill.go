@@ -0,0 +1,121 @@
+package state
+
+import (
+	"raindrop/main/model"
+	"sort"
+)
+
+/*
+ * 체결 정보 동기화
+ * 아직 완료되지 않은 주문만 거래소에 조회해 체결량, 평균가, 수수료를 갱신한다.
+ */
+
+const (
+	OrderStateWait   = "wait"
+	OrderStateDone   = "done"
+	OrderStateCancel = "cancel"
+)
+
+type FillFetcher func(uuid string) (*model.OrderFill, error)
+
+/*
+ * 완료(done, cancel) 되지 않은 주문의 체결 정보를 갱신한다.
+ * 조회에 실패한 주문은 건너뛰고 마지막 에러를 돌려준다.
+ */
+func (store *Store) SyncFills(fetch FillFetcher) (updated []*OrderRecord, err error) {
+	updated = make([]*OrderRecord, 0)
+
+	for _, record := range store.PendingOrders() {
+		fill, fetchErr := fetch(record.Uuid)
+		if fetchErr != nil {
+			err = fetchErr
+			continue
+		}
+
+		if store.updateFill(record, fill) {
+			updated = append(updated, record)
+		}
+	}
+
+	if len(updated) > 0 {
+		if saveErr := store.Save(); saveErr != nil {
+			err = saveErr
+		}
+	}
+
+	return
+}
+
+/*
+ * 체결이 완료되지 않은 주문 목록 (주문 시각순)
+ */
+func (store *Store) PendingOrders() (records []*OrderRecord) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	records = make([]*OrderRecord, 0)
+	for _, record := range store.Orders {
+		if !isFinalState(record.State) {
+			records = append(records, record)
+		}
+	}
+
+	sortByCreatedAt(records)
+
+	return
+}
+
+/*
+ * 체결된 주문 목록 (주문 시각순)
+ */
+func (store *Store) FilledOrders() (records []*OrderRecord) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	records = make([]*OrderRecord, 0)
+	for _, record := range store.Orders {
+		if record.ExecutedVolume > 0 {
+			records = append(records, record)
+		}
+	}
+
+	sortByCreatedAt(records)
+
+	return
+}
+
+func (store *Store) updateFill(record *OrderRecord, fill *model.OrderFill) (changed bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	changed = record.State != fill.State || record.ExecutedVolume != fill.ExecutedVolume
+
+	record.State = fill.State
+	record.ExecutedVolume = fill.ExecutedVolume
+	record.AvgPrice = fill.AvgPrice
+	record.PaidFee = fill.PaidFee
+
+	return
+}
+
+/*
+ * 주문의 최종 상태(done, cancel)가 기록되어 더 조회할 필요가 없는지 확인한다.
+ */
+func (store *Store) IsSynced(uuid string) bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	record, exist := store.Orders[uuid]
+
+	return exist && isFinalState(record.State)
+}
+
+func isFinalState(orderState string) bool {
+	return orderState == OrderStateDone || orderState == OrderStateCancel
+}
+
+func sortByCreatedAt(records []*OrderRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+}
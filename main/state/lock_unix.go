@@ -0,0 +1,32 @@
+//go:build !windows
+// +build !windows
+
+package state
+
+import (
+	"os"
+	"syscall"
+)
+
+/*
+ * path 에 배타적 flock 을 건다. 다른 프로세스(또는 같은 파일을 연 다른 Store)가 잠금을 풀 때까지 기다린다.
+ * 반환된 unlock 으로 잠금을 풀고 파일을 닫는다.
+ */
+func lockFile(path string) (unlock func(), err error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return
+	}
+
+	if err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		return
+	}
+
+	unlock = func() {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}
+
+	return
+}
@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package state
+
+/*
+ * Windows 에서는 프로세스 간 잠금을 하지 않는다. (run 과 CLI 명령을 동시에 실행하지 않아야 함)
+ */
+func lockFile(path string) (unlock func(), err error) {
+	return func() {}, nil
+}
@@ -0,0 +1,211 @@
+package state
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"raindrop/main/money"
+)
+
+/*
+ * 봇 보유 수량
+ * 봇이 낸 주문의 체결량(매수 +, 매도 -)에 잔고 대사에서 편입한 수량을 더한다.
+ */
+
+// 이 값 이하의 수량은 0 으로 본다.
+const positionEpsilon = 1e-10
+
+func (store *Store) Positions() (positions map[string]float64) {
+	return store.positions("", true)
+}
+
+/*
+ * strategy 전략의 보유 수량 (그 전략이 낸 주문의 체결량)
+ * 대사 편입 수량은 전략을 알 수 없으므로 withAdjustments 인 전략(계정의 첫 번째 전략)에만 더한다.
+ */
+func (store *Store) StrategyPositions(strategy string, withAdjustments bool) (positions map[string]float64) {
+	return store.positions(strategy, withAdjustments)
+}
+
+func (store *Store) positions(strategy string, withAdjustments bool) (positions map[string]float64) {
+	positions = make(map[string]float64)
+
+	for _, record := range store.FilledOrders() {
+		if len(strategy) > 0 && record.Strategy != strategy {
+			continue
+		}
+
+		switch record.Side {
+		case types.ORDERSIDE_BID:
+			positions[record.Market] += record.ExecutedVolume
+		case types.ORDERSIDE_ASK:
+			positions[record.Market] -= record.ExecutedVolume
+		}
+	}
+
+	if withAdjustments {
+		store.mutex.Lock()
+		for market, volume := range store.Adjustments {
+			positions[market] += volume
+		}
+		store.mutex.Unlock()
+	}
+
+	for market, volume := range positions {
+		if volume < positionEpsilon && volume > -positionEpsilon {
+			delete(positions, market)
+		}
+	}
+
+	return
+}
+
+func (store *Store) Position(market string) float64 {
+	return store.Positions()[market]
+}
+
+/*
+ * 봇 주문 체결로 구한 평균 매수가 (수수료 제외)
+ * 매도는 평균가를 바꾸지 않고 수량만 줄인다. 보유 수량이 없으면 0
+ * 평균 매수가를 알려주지 않는 거래소(Bithumb)의 손익률 계산에 사용한다.
+ */
+func (store *Store) AvgPrice(market string) (avgPrice float64) {
+	volume := 0.0
+
+	for _, record := range store.FilledOrders() {
+		if record.Market != market {
+			continue
+		}
+
+		switch record.Side {
+		case types.ORDERSIDE_BID:
+			if volume+record.ExecutedVolume > positionEpsilon {
+				avgPrice = (avgPrice*volume + record.AvgPrice*record.ExecutedVolume) / (volume + record.ExecutedVolume)
+			}
+			volume += record.ExecutedVolume
+		case types.ORDERSIDE_ASK:
+			volume -= record.ExecutedVolume
+		}
+
+		if volume < positionEpsilon {
+			volume = 0
+			avgPrice = 0
+		}
+	}
+
+	return
+}
+
+/*
+ * 봇 수량을 delta 만큼 조정하고 저장한다.
+ */
+func (store *Store) Adjust(market string, delta float64) (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.Adjustments[market] += delta
+	store.pendingAdjustments[market] += delta
+
+	return store.saveLocked()
+}
+
+/*
+ * 체결이 진행 중인(완료되지 않은) 봇 주문이 있는 마켓인지 확인한다.
+ */
+func (store *Store) HasPendingOrder(market string) bool {
+	for _, record := range store.PendingOrders() {
+		if record.Market == market {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+ * 전략 하나가 보는 봇 주문과 보유 수량
+ * 계정에서 전략 하나만 실행하면 봇 주문 전체(수동 청산, 대사 편입 포함)를 그 전략의 것으로 보고,
+ * 여러 전략을 실행하면 그 전략이 낸 주문만 본다. 대사 편입 수량은 첫 번째 전략(primary)에 더한다.
+ */
+type StrategyView struct {
+	store    *Store
+	strategy string
+	shared   bool
+	primary  bool
+}
+
+/*
+ * strategies : 계정에서 실행하는 전략 목록 (설정 순서)
+ */
+func (store *Store) View(strategy string, strategies []string) *StrategyView {
+	return &StrategyView{
+		store:    store,
+		strategy: strategy,
+		shared:   len(strategies) > 1,
+		primary:  len(strategies) == 0 || strategies[0] == strategy}
+}
+
+func (view *StrategyView) Positions() map[string]float64 {
+	if !view.shared {
+		return view.store.Positions()
+	}
+
+	return view.store.StrategyPositions(view.strategy, view.primary)
+}
+
+func (view *StrategyView) FilterOrders(orders []*types.Order) []*types.Order {
+	if !view.shared {
+		return view.store.FilterOwnOrders(orders)
+	}
+
+	return view.store.FilterStrategyOrders(orders, view.strategy)
+}
+
+func (view *StrategyView) FilterOrdersMap(ordersMap map[string][]*types.Order) map[string][]*types.Order {
+	if !view.shared {
+		return view.store.FilterOwnOrdersMap(ordersMap)
+	}
+
+	return view.store.FilterStrategyOrdersMap(ordersMap, view.strategy)
+}
+
+/*
+ * 전략 하나에 돌아가는 매도 수량
+ */
+type VolumeShare struct {
+	Strategy string
+	Volume   money.Decimal
+}
+
+/*
+ * 코인 잔고 volume 을 매도할 때 전략별 수량으로 나눈다. (수동 청산을 보유 전략의 매도로 기록)
+ * 전략 목록 순서대로 각 전략이 보는 보유 수량만큼 나누고, 남은 수량(수동 매매 등)은 other 에 돌린다.
+ * 수량은 places 자리로 내림한다.
+ */
+func (store *Store) SplitVolume(market string,
+	strategies []string,
+	volume money.Decimal,
+	places int,
+	other string) (shares []VolumeShare) {
+
+	step := money.Unit(places)
+	remaining := volume.FloorTo(step)
+
+	for _, strategy := range strategies {
+		position := money.NewFromFloat(store.View(strategy, strategies).Positions()[market]).FloorTo(step)
+		if position.Sign() <= 0 || remaining.Sign() <= 0 {
+			continue
+		}
+
+		if remaining.LessThan(position) {
+			position = remaining
+		}
+
+		shares = append(shares, VolumeShare{Strategy: strategy, Volume: position})
+		remaining = remaining.Sub(position)
+	}
+
+	if remaining.Sign() > 0 {
+		shares = append(shares, VolumeShare{Strategy: other, Volume: remaining})
+	}
+
+	return
+}
@@ -0,0 +1,394 @@
+package state
+
+import (
+	"encoding/json"
+	"github.com/jekeun/upbit-go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"raindrop/main/utils/identifier"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/*
+ * 봇이 직접 낸 주문 기록을 파일로 관리한다.
+ * 주문 uuid 를 기준으로 Identifier, 전략명을 저장해
+ * 체결 내역을 전략별로 구분하고 수동 주문과 구분하는데 사용한다.
+ *
+ * run 이 실행 중일 때 flatten, cancel-all, report, reconcile 명령도 같은 파일을 쓰므로
+ * 저장할 때마다 <상태 파일>.lock 에 flock 을 건 채로 파일을 다시 읽어 합친 후 쓴다. (mergeFileLocked)
+ */
+
+type OrderRecord struct {
+	Uuid       string    `json:"uuid"`
+	Identifier string    `json:"identifier"`
+	Strategy   string    `json:"strategy"`
+	Market     string    `json:"market"`
+	Side       string    `json:"side"`
+	Price      string    `json:"price"`
+	Volume     string    `json:"volume"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// 체결 정보 (SyncFills 로 갱신)
+	State          string  `json:"state,omitempty"`
+	ExecutedVolume float64 `json:"executed_volume,omitempty"`
+	AvgPrice       float64 `json:"avg_price,omitempty"`
+	PaidFee        float64 `json:"paid_fee,omitempty"`
+
+	// 매수 신호 정보 (리포트의 진입 k, 이평 스코어 분포에 사용)
+	KValue   float64 `json:"k_value,omitempty"`
+	MalScore float64 `json:"mal_score,omitempty"`
+}
+
+type Store struct {
+	path     string
+	readOnly bool
+	mutex    sync.Mutex
+
+	Orders map[string]*OrderRecord `json:"orders"`
+	// 잔고 대사에서 봇 수량으로 편입(adopt)한 마켓별 수량
+	Adjustments map[string]float64 `json:"adjustments,omitempty"`
+
+	// 이 Store 에서 조정했지만 아직 파일에 저장하지 않은 수량 (다른 프로세스의 조정과 합치기 위해 따로 둔다)
+	pendingAdjustments map[string]float64
+}
+
+// 상태 파일 형식
+type storeFile struct {
+	Orders      map[string]*OrderRecord `json:"orders"`
+	Adjustments map[string]float64      `json:"adjustments,omitempty"`
+}
+
+/*
+ * 상태 파일을 읽어온다. 파일이 없으면 빈 Store 를 돌려준다.
+ */
+func Load(path string) (store *Store, err error) {
+	store = &Store{
+		path:               path,
+		Orders:             make(map[string]*OrderRecord),
+		Adjustments:        make(map[string]float64),
+		pendingAdjustments: make(map[string]float64)}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+
+	err = json.Unmarshal(data, store)
+	if store.Orders == nil {
+		store.Orders = make(map[string]*OrderRecord)
+	}
+	if store.Adjustments == nil {
+		store.Adjustments = make(map[string]float64)
+	}
+
+	return
+}
+
+func (store *Store) Save() (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.saveLocked()
+}
+
+/*
+ * 파일에 저장하지 않도록 설정한다. (dry-run 에서 실제 기록을 보존하기 위해 사용)
+ */
+func (store *Store) SetReadOnly() {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.readOnly = true
+}
+
+/*
+ * 다른 프로세스가 저장한 내용을 다시 읽어 합친다.
+ * 실행 중인 전략이 CLI 명령으로 낸 주문(flatten 등)과 갱신된 체결 정보를 보도록 주기마다 호출한다.
+ */
+func (store *Store) Refresh() (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.mergeFileLocked()
+}
+
+/*
+ * 파일의 주문 기록과 수량 조정을 합친다.
+ * 주문 기록은 uuid 별로 더 진행된(완료, 체결량이 많은) 체결 정보를 쓰고,
+ * 수량 조정은 파일 값에 이 Store 에서 저장하지 않은 조정만 더한다.
+ */
+func (store *Store) mergeFileLocked() (err error) {
+	data, err := ioutil.ReadFile(store.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+
+	saved := new(storeFile)
+	if err = json.Unmarshal(data, saved); err != nil {
+		return
+	}
+
+	for uuid, record := range saved.Orders {
+		current, exist := store.Orders[uuid]
+		if !exist {
+			store.Orders[uuid] = record
+			continue
+		}
+
+		mergeRecord(current, record)
+	}
+
+	adjustments := make(map[string]float64)
+	for market, volume := range saved.Adjustments {
+		adjustments[market] = volume
+	}
+	for market, delta := range store.pendingAdjustments {
+		adjustments[market] += delta
+	}
+	store.Adjustments = adjustments
+
+	return
+}
+
+/*
+ * saved 의 체결 정보가 더 진행됐으면 current 에 반영한다.
+ * SyncFills 가 들고 있는 기록이 바뀌지 않도록 current 를 그대로 두고 값만 바꾼다.
+ */
+func mergeRecord(current *OrderRecord, saved *OrderRecord) {
+	ahead := saved.ExecutedVolume > current.ExecutedVolume
+	if isFinalState(saved.State) != isFinalState(current.State) {
+		ahead = isFinalState(saved.State)
+	}
+
+	if ahead {
+		current.State = saved.State
+		current.ExecutedVolume = saved.ExecutedVolume
+		current.AvgPrice = saved.AvgPrice
+		current.PaidFee = saved.PaidFee
+	}
+
+	if current.KValue == 0 && current.MalScore == 0 {
+		current.KValue = saved.KValue
+		current.MalScore = saved.MalScore
+	}
+}
+
+func (store *Store) saveLocked() (err error) {
+	if store.readOnly {
+		return
+	}
+
+	if dir := filepath.Dir(store.path); dir != "" {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return
+		}
+	}
+
+	// 읽고 합쳐서 쓰는 동안 다른 프로세스가 저장하면 그 사이의 기록을 덮어쓰므로 lock 파일로 막는다.
+	unlock, err := lockFile(store.path + ".lock")
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	if err = store.mergeFileLocked(); err != nil {
+		return
+	}
+
+	data, err := json.MarshalIndent(&storeFile{Orders: store.Orders, Adjustments: store.Adjustments}, "", "\t")
+	if err != nil {
+		return
+	}
+
+	// 저장 도중 종료되어도 기존 파일이 깨지지 않도록 임시 파일에 쓴 후 교체한다.
+	// 다른 프로세스와 임시 파일이 겹치지 않도록 pid 를 붙인다.
+	tmpPath := store.path + "." + strconv.Itoa(os.Getpid()) + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return
+	}
+
+	if err = os.Rename(tmpPath, store.path); err != nil {
+		return
+	}
+
+	store.pendingAdjustments = make(map[string]float64)
+
+	return
+}
+
+/*
+ * 봇이 낸 주문을 기록하고 파일에 저장한다.
+ */
+func (store *Store) RecordOrder(id string, order *types.Order) (err error) {
+	if order == nil || len(order.Uuid) == 0 {
+		return
+	}
+
+	record := &OrderRecord{
+		Uuid:       order.Uuid,
+		Identifier: id,
+		Market:     order.Market,
+		Side:       order.Side,
+		Price:      order.Price,
+		Volume:     order.Volume,
+		CreatedAt:  time.Now()}
+
+	if tag, ok := identifier.Parse(id); ok {
+		record.Strategy = tag.Strategy
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.Orders[order.Uuid] = record
+
+	return store.saveLocked()
+}
+
+/*
+ * 기록된 주문에 매수 신호 정보를 남긴다.
+ */
+func (store *Store) SetSignal(uuid string, kValue float64, malScore float64) (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	record, exist := store.Orders[uuid]
+	if !exist {
+		return
+	}
+
+	record.KValue = kValue
+	record.MalScore = malScore
+
+	return store.saveLocked()
+}
+
+func (store *Store) IsOwnOrder(uuid string) bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	_, exist := store.Orders[uuid]
+	return exist
+}
+
+/*
+ * 주문을 낸 전략명을 가져온다.
+ */
+func (store *Store) StrategyOf(uuid string) (strategy string, exist bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if record, found := store.Orders[uuid]; found {
+		strategy = record.Strategy
+		exist = true
+	}
+
+	return
+}
+
+/*
+ * 기록된 주문의 마켓, 매수/매도 구분을 가져온다.
+ * 주문 번호만으로 조회할 수 없는 거래소(Bithumb)가 재시작 후에도 체결 조회, 취소를 할 수 있도록 사용한다.
+ */
+func (store *Store) OrderKey(uuid string) (market string, side string, exist bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if record, found := store.Orders[uuid]; found && len(record.Market) > 0 && len(record.Side) > 0 {
+		market = record.Market
+		side = record.Side
+		exist = true
+	}
+
+	return
+}
+
+/*
+ * 체결이 진행 중인(완료되지 않은) 봇 주문이 있는 마켓 (정렬)
+ */
+func (store *Store) PendingMarkets() (markets []string) {
+	exist := make(map[string]bool)
+	for _, record := range store.PendingOrders() {
+		if len(record.Market) > 0 && !exist[record.Market] {
+			exist[record.Market] = true
+			markets = append(markets, record.Market)
+		}
+	}
+	sort.Strings(markets)
+
+	return
+}
+
+/*
+ * strategy 전략이 낸 주문인지 확인한다.
+ */
+func (store *Store) IsStrategyOrder(uuid string, strategy string) bool {
+	owner, exist := store.StrategyOf(uuid)
+	return exist && owner == strategy
+}
+
+/*
+ * 주문 목록에서 strategy 전략이 낸 주문만 추려낸다. (한 계정에서 여러 전략을 실행할 때 사용)
+ */
+func (store *Store) FilterStrategyOrders(orders []*types.Order, strategy string) (strategyOrders []*types.Order) {
+	strategyOrders = make([]*types.Order, 0)
+
+	for _, order := range orders {
+		if store.IsStrategyOrder(order.Uuid, strategy) {
+			strategyOrders = append(strategyOrders, order)
+		}
+	}
+
+	return
+}
+
+/*
+ * 주문 Map 에서 strategy 전략이 낸 주문만 추려낸다.
+ */
+func (store *Store) FilterStrategyOrdersMap(ordersMap map[string][]*types.Order, strategy string) (strategyOrdersMap map[string][]*types.Order) {
+	strategyOrdersMap = make(map[string][]*types.Order)
+
+	for side, orders := range ordersMap {
+		strategyOrdersMap[side] = store.FilterStrategyOrders(orders, strategy)
+	}
+
+	return
+}
+
+/*
+ * 주문 목록에서 봇이 낸 주문만 추려낸다.
+ */
+func (store *Store) FilterOwnOrders(orders []*types.Order) (ownOrders []*types.Order) {
+	ownOrders = make([]*types.Order, 0)
+
+	for _, order := range orders {
+		if store.IsOwnOrder(order.Uuid) {
+			ownOrders = append(ownOrders, order)
+		}
+	}
+
+	return
+}
+
+/*
+ * 주문 Map 에서 봇이 낸 주문만 추려낸다.
+ */
+func (store *Store) FilterOwnOrdersMap(ordersMap map[string][]*types.Order) (ownOrdersMap map[string][]*types.Order) {
+	ownOrdersMap = make(map[string][]*types.Order)
+
+	for side, orders := range ordersMap {
+		ownOrdersMap[side] = store.FilterOwnOrders(orders)
+	}
+
+	return
+}
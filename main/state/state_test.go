@@ -0,0 +1,285 @@
+package state
+
+import (
+	"fmt"
+	"github.com/jekeun/upbit-go/types"
+	"path/filepath"
+	"raindrop/main/model"
+	"raindrop/main/money"
+	"sync"
+	"testing"
+)
+
+func order(uuid string, side string) *types.Order {
+	return &types.Order{Uuid: uuid, Side: side, Market: "KRW-BTC", Price: "100", Volume: "1"}
+}
+
+func load(t *testing.T, path string) *Store {
+	t.Helper()
+
+	store, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+/*
+ * run 과 CLI 명령이 같은 상태 파일을 쓰는 경우
+ */
+func TestSaveMergesOtherProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	runner := load(t, path)
+	if err := runner.RecordOrder("rd:lw_basic:KRW-BTC:s:1", order("bid-1", types.ORDERSIDE_BID)); err != nil {
+		t.Fatal(err)
+	}
+
+	// run 이 실행 중일 때 flatten 이 매도 주문을 기록하고 reconcile 이 수량을 조정한다.
+	command := load(t, path)
+	if err := command.RecordOrder("rd:cli:KRW-BTC:s:1", order("ask-1", types.ORDERSIDE_ASK)); err != nil {
+		t.Fatal(err)
+	}
+	if err := command.Adjust("KRW-ETH", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// run 이 체결을 동기화하고 다른 코인의 수량을 조정한다.
+	fills := map[string]*model.OrderFill{
+		"bid-1": {Uuid: "bid-1", State: OrderStateDone, ExecutedVolume: 1, AvgPrice: 100}}
+	if _, err := runner.SyncFills(func(uuid string) (*model.OrderFill, error) {
+		if fill, exist := fills[uuid]; exist {
+			return fill, nil
+		}
+		return &model.OrderFill{Uuid: uuid, State: OrderStateWait}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.Adjust("KRW-ETH", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := load(t, path)
+
+	tests := []struct {
+		uuid     string
+		strategy string
+		state    string
+		executed float64
+	}{
+		{"bid-1", "lw_basic", OrderStateDone, 1},
+		// run 이 아직 다시 읽기 전에 기록된 주문은 동기화되지 않은 채로 남는다.
+		{"ask-1", "cli", "", 0},
+	}
+
+	for _, test := range tests {
+		record, exist := saved.Orders[test.uuid]
+		if !exist {
+			t.Errorf("%s : 기록 없음", test.uuid)
+			continue
+		}
+		if record.Strategy != test.strategy || record.State != test.state || record.ExecutedVolume != test.executed {
+			t.Errorf("%s : record = %+v", test.uuid, record)
+		}
+	}
+
+	if adjustment := saved.Adjustments["KRW-ETH"]; adjustment != 3 {
+		t.Errorf("KRW-ETH 조정 = %v, want 3", adjustment)
+	}
+}
+
+/*
+ * run 과 CLI 명령이 동시에 저장해도 서로의 기록을 잃지 않는다.
+ */
+func TestConcurrentSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	tests := []struct {
+		strategy string
+		count    int
+	}{
+		{"lw_basic", 30},
+		{"cli", 30},
+	}
+
+	stores := make([]*Store, len(tests))
+	for index := range tests {
+		stores[index] = load(t, path)
+	}
+
+	var waitGroup sync.WaitGroup
+	errs := make(chan error, len(tests))
+
+	for index, test := range tests {
+		waitGroup.Add(1)
+		go func(store *Store, strategy string, count int) {
+			defer waitGroup.Done()
+
+			for number := 0; number < count; number++ {
+				id := fmt.Sprintf("rd:%s:KRW-BTC:s:%d", strategy, number)
+				if err := store.RecordOrder(id, order(fmt.Sprintf("%s-%d", strategy, number), types.ORDERSIDE_BID)); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(stores[index], test.strategy, test.count)
+	}
+
+	waitGroup.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	saved := load(t, path)
+
+	for _, test := range tests {
+		for number := 0; number < test.count; number++ {
+			uuid := fmt.Sprintf("%s-%d", test.strategy, number)
+			if record, exist := saved.Orders[uuid]; !exist || record.Strategy != test.strategy {
+				t.Errorf("%s : record = %+v", uuid, record)
+			}
+		}
+	}
+}
+
+/*
+ * 다른 프로세스가 먼저 동기화한 체결 정보는 되돌리지 않는다.
+ */
+func TestRefreshKeepsFurtherFill(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	runner := load(t, path)
+	if err := runner.RecordOrder("rd:lw_basic:KRW-BTC:s:1", order("bid-1", types.ORDERSIDE_BID)); err != nil {
+		t.Fatal(err)
+	}
+	pending := runner.PendingOrders()
+
+	command := load(t, path)
+	if _, err := command.SyncFills(func(uuid string) (*model.OrderFill, error) {
+		return &model.OrderFill{Uuid: uuid, State: OrderStateDone, ExecutedVolume: 1, AvgPrice: 100}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runner.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	// SyncFills 가 들고 있던 기록에도 반영된다.
+	if len(pending) != 1 || pending[0].State != OrderStateDone {
+		t.Errorf("pending record = %+v", pending[0])
+	}
+	if position := runner.Position("KRW-BTC"); position != 1 {
+		t.Errorf("position = %v, want 1", position)
+	}
+
+	// 더 뒤처진 기록으로 저장해도 파일의 체결 정보는 유지된다.
+	runner.Orders["bid-1"].State = OrderStateWait
+	runner.Orders["bid-1"].ExecutedVolume = 0
+	if err := runner.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if record := load(t, path).Orders["bid-1"]; record.State != OrderStateDone || record.ExecutedVolume != 1 {
+		t.Errorf("saved record = %+v", record)
+	}
+}
+
+func TestAvgPrice(t *testing.T) {
+	tests := []struct {
+		name   string
+		fills  []OrderRecord
+		market string
+		want   float64
+	}{
+		{"no fills", nil, "KRW-BTC", 0},
+		{"two bids", []OrderRecord{
+			{Side: types.ORDERSIDE_BID, ExecutedVolume: 1, AvgPrice: 100},
+			{Side: types.ORDERSIDE_BID, ExecutedVolume: 3, AvgPrice: 200}}, "KRW-BTC", 175},
+		{"partial ask keeps average", []OrderRecord{
+			{Side: types.ORDERSIDE_BID, ExecutedVolume: 2, AvgPrice: 100},
+			{Side: types.ORDERSIDE_ASK, ExecutedVolume: 1, AvgPrice: 150}}, "KRW-BTC", 100},
+		{"closed then reopened", []OrderRecord{
+			{Side: types.ORDERSIDE_BID, ExecutedVolume: 1, AvgPrice: 100},
+			{Side: types.ORDERSIDE_ASK, ExecutedVolume: 1, AvgPrice: 150},
+			{Side: types.ORDERSIDE_BID, ExecutedVolume: 1, AvgPrice: 300}}, "KRW-BTC", 300},
+		{"other market", []OrderRecord{
+			{Side: types.ORDERSIDE_BID, ExecutedVolume: 1, AvgPrice: 100}}, "KRW-ETH", 0},
+	}
+
+	for _, test := range tests {
+		store := load(t, filepath.Join(t.TempDir(), "state.json"))
+		for index, fill := range test.fills {
+			record := fill
+			record.Uuid = string(rune('a' + index))
+			record.Market = "KRW-BTC"
+			record.State = OrderStateDone
+			record.CreatedAt = record.CreatedAt.AddDate(0, 0, index)
+			store.Orders[record.Uuid] = &record
+		}
+
+		if avgPrice := store.AvgPrice(test.market); avgPrice != test.want {
+			t.Errorf("%s : AvgPrice = %v, want %v", test.name, avgPrice, test.want)
+		}
+	}
+}
+
+/*
+ * flatten 매도 수량을 보유 전략별로 나눈다.
+ */
+func TestSplitVolume(t *testing.T) {
+	fills := []OrderRecord{
+		{Strategy: "lw_basic", Side: types.ORDERSIDE_BID, ExecutedVolume: 0.3},
+		{Strategy: "lw_advance", Side: types.ORDERSIDE_BID, ExecutedVolume: 0.5},
+		{Strategy: "lw_advance", Side: types.ORDERSIDE_ASK, ExecutedVolume: 0.1},
+	}
+
+	tests := []struct {
+		name       string
+		strategies []string
+		adjustment float64
+		volume     string
+		want       []VolumeShare
+	}{
+		{"owners and manual", []string{"lw_basic", "lw_advance"}, 0, "1", []VolumeShare{
+			{"lw_basic", money.MustParse("0.3")}, {"lw_advance", money.MustParse("0.4")}, {"cli", money.MustParse("0.3")}}},
+		{"adjustment to primary", []string{"lw_basic", "lw_advance"}, 0.2, "1", []VolumeShare{
+			{"lw_basic", money.MustParse("0.5")}, {"lw_advance", money.MustParse("0.4")}, {"cli", money.MustParse("0.1")}}},
+		{"balance below positions", []string{"lw_basic", "lw_advance"}, 0, "0.5", []VolumeShare{
+			{"lw_basic", money.MustParse("0.3")}, {"lw_advance", money.MustParse("0.2")}}},
+		// 전략이 하나면 봇 주문 전체가 그 전략의 보유 수량이다.
+		{"single strategy", []string{"lw_basic"}, 0, "0.7", []VolumeShare{
+			{"lw_basic", money.MustParse("0.7")}}},
+		{"no strategy", nil, 0, "0.123456789", []VolumeShare{
+			{"cli", money.MustParse("0.12345678")}}},
+	}
+
+	for _, test := range tests {
+		store := load(t, filepath.Join(t.TempDir(), "state.json"))
+		for index, fill := range fills {
+			record := fill
+			record.Uuid = string(rune('a' + index))
+			record.Market = "KRW-BTC"
+			record.State = OrderStateDone
+			store.Orders[record.Uuid] = &record
+		}
+		if test.adjustment != 0 {
+			if err := store.Adjust("KRW-BTC", test.adjustment); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		shares := store.SplitVolume("KRW-BTC", test.strategies, money.MustParse(test.volume), 8, "cli")
+		if len(shares) != len(test.want) {
+			t.Errorf("%s : shares = %+v, want %+v", test.name, shares, test.want)
+			continue
+		}
+		for index, want := range test.want {
+			if shares[index].Strategy != want.Strategy || shares[index].Volume.Cmp(want.Volume) != 0 {
+				t.Errorf("%s : share[%d] = %s %s, want %s %s", test.name, index,
+					shares[index].Strategy, shares[index].Volume.String(), want.Strategy, want.Volume.String())
+			}
+		}
+	}
+}
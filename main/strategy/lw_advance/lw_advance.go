@@ -2,13 +2,21 @@ package lw_advance
 
 import (
 	"fmt"
-	"github.com/jekeun/upbit-go"
 	upbitTool "github.com/jekeun/upbit-go/tool"
 	"github.com/jekeun/upbit-go/types"
 	upbitUtil "github.com/jekeun/upbit-go/util"
 	"log"
 	"math"
+	"raindrop/main/allocator"
+	"raindrop/main/exchange"
+	"raindrop/main/fee"
 	"raindrop/main/model"
+	"raindrop/main/money"
+	"raindrop/main/quote"
+	"raindrop/main/rules"
+	"raindrop/main/state"
+	"raindrop/main/utils/identifier"
+	"sort"
 	"strconv"
 	"time"
 )
@@ -17,19 +25,41 @@ import (
  * Larry Williams Advanced 변동성 전략 수행
  */
 
-var gLogger *log.Logger
-var gConfig *model.Config
-
 type LarryRunner struct {
-	client *upbit.Client
+	exchange    exchange.Exchange
+	idGenerator *identifier.Generator
+	store       *state.Store
+	// 이 전략의 주문과 보유 수량 (계정에서 여러 전략을 실행하면 다른 전략의 것은 빠진다)
+	own         *state.StrategyView
+	config      *model.Config
+	logger      *log.Logger
+
+	// 전략별 자금 배분 (설정하지 않으면 nil)
+	allocator *allocator.Allocator
+	// 마켓별 수수료 모델 (자금 배분 risk_parity 에서 체결 기록에 수수료가 없을 때 사용)
+	fees *fee.Provider
 }
 
-const healthCheckCoin = "KRW-ETC"
+const strategyName = "lw_advance"
+
+// 네트워크, 서버 오류로 주문이 실패하면 같은 Identifier 로 재시도한다. 이미 접수된 주문이면 거래소가 중복으로 거절한다.
+const (
+	orderRetryCount = 3
+	orderRetryDelay = 500 * time.Millisecond
+)
 
-func (runner *LarryRunner) Init(config *model.Config, logger *log.Logger) {
-	runner.client = upbit.NewClient(config.Account.Accesskey, config.Account.SecretKey)
-	gConfig = config
-	gLogger = logger
+func (runner *LarryRunner) Init(config *model.Config, logger *log.Logger, store *state.Store, ex exchange.Exchange) {
+	runner.exchange = ex
+	runner.idGenerator = identifier.NewGenerator(strategyName)
+	runner.store = store
+	runner.own = store.View(strategyName, config.StrategyNames())
+	runner.config = config
+	runner.logger = logger
+	runner.fees = fee.NewProvider(ex, config.Fee)
+
+	if config.Allocator.Enable == 1 {
+		runner.allocator = allocator.New(config.Allocator, logger)
+	}
 }
 
 func (runner *LarryRunner) RunLWAdvancedStrategy() {
@@ -40,12 +70,16 @@ func (runner *LarryRunner) RunLWAdvancedStrategy() {
 
 	balances, ordersMap, _ := runner.getBalanceAndWaitOrders()
 
-	runner.healthCheck(ordersMap)
+	// 보유 수량 계산을 위해 봇 주문의 체결 정보를 갱신한다.
+	if _, err := runner.store.SyncFills(runner.exchange.OrderFill); err != nil {
+		runner.logger.Printf("체결 내역 동기화 실패 : %s\n", err.Error())
+	}
+	positions := runner.own.Positions()
 
-	candleMap := upbitTool.GetDayCandlesByCoins(runner.client, gConfig.LarryStrategy.Targets, 20)
+	candleMap := exchange.GetDayCandlesByCoins(runner.exchange, runner.config.LarryStrategy.Targets, 20)
 
 	// 스탑로스 or 익절 체크
-	// runner.processProfit(gConfig.LarryStrategy.StopLoss, balances, ordersMap, candleMap)
+	// runner.processProfit(runner.config.LarryStrategy.StopLoss, balances, ordersMap, candleMap)
 
 	kMap := getKNoiseValueByDay(candleMap)
 	malMap := getMovingAverageLineByDay(candleMap)
@@ -56,68 +90,24 @@ func (runner *LarryRunner) RunLWAdvancedStrategy() {
 	//fmt.Println(malScoreMap)
 
 	// 주어진 시각, 최대 5분간 잔고 매도만 수행한다.
-	if now.Hour() == gConfig.LarryStrategy.StartTime &&
-		now.Minute() <= gConfig.LarryStrategy.AskPeriodMinute {
-		runner.runLarryAskStrategy(balances, ordersMap, candleMap)
-	} else {
-		runner.runLarryBidStrategy(balances, ordersMap, candleMap, kMap, malScoreMap)
-	}
-}
-
-/*
- * 실제 주문으로 HealthCheck
- * 안전하게 ETC 100원으로 주문
- */
-func (runner *LarryRunner) healthCheck(ordersMap map[string][]*types.Order) {
-
-	var etcOrder *types.Order = nil
-
-	bidOrders := ordersMap[types.ORDERSIDE_BID]
-
-	for _, order := range bidOrders {
-		if order.Market == healthCheckCoin {
-			etcOrder = order
-			break
-		}
-	}
-
-	//매수 주문이 있다면 취소
-	if etcOrder != nil {
-		runner.client.CancelOrder(etcOrder.Uuid)
+	if now.Hour() == runner.config.LarryStrategy.StartTime &&
+		now.Minute() <= runner.config.LarryStrategy.AskPeriodMinute {
+		runner.runLarryAskStrategy(balances, positions, ordersMap, candleMap)
 	} else {
-		// 매수
-		bidOrder := types.OrderInfo{
-			Identifier: strconv.Itoa(int(upbitUtil.TimeStamp())),
-			Side:       types.ORDERSIDE_BID,
-			Market:     healthCheckCoin,
-			Price:      "100",
-			Volume:     "100",
-			OrdType:    types.ORDERTYPE_LIMIT}
-
-		order, err := runner.client.OrderByInfo(bidOrder)
-
-		if err != nil {
-			gLogger.Println("주문 에러 ")
-		} else {
-			if len(order.Uuid) > 0 {
-				gLogger.Println("헬스체크 매수 성공 ")
-			}
-		}
-
-
-
+		runner.runLarryBidStrategy(balances, positions, ordersMap, candleMap, kMap, malScoreMap)
 	}
 }
 
 // 매도 전략
 func (runner *LarryRunner)runLarryAskStrategy(balances []*types.Balance,
+	positions map[string]float64,
 	ordersMap map[string][]*types.Order,
 	candleMap map[string][]*types.DayCandle) {
 
-	gLogger.Println("[매도 전략 수행중]")
+	runner.logger.Println("[매도 전략 수행중]")
 
-	logBalance(balances)
-	logWaitOrders(ordersMap[types.ORDERSIDE_ASK])
+	runner.logBalance(balances)
+	runner.logWaitOrders(ordersMap[types.ORDERSIDE_ASK])
 
 	//util.PrintBalance(balances)
 	//util.PrintOrdersMap(ordersMap)
@@ -126,85 +116,97 @@ func (runner *LarryRunner)runLarryAskStrategy(balances []*types.Balance,
 	// askCoins := getCanAskCoins(balances, ordersMap)
 
 	// 이 시간대 매수 오더는 전일 오더이므로 모두 취소시킨다.
-	bidOrders := ordersMap[types.ORDERSIDE_BID]
+	// 수동으로 낸 주문은 건드리지 않는다.
+	bidOrders := runner.own.FilterOrders(ordersMap[types.ORDERSIDE_BID])
 	if len(bidOrders) > 0 {
 		runner.cancelAllOrder(bidOrders)
 	}
 
-	// 매도 가능 잔고를 구함 ,
-	askCoins := upbitTool.GetBalanceCoinsCanAsk(balances)
+	// 매도 가능 잔고를 구함 , 이 전략이 매수한 수량만 매도한다. (수동 매수, 다른 전략 코인 제외)
+	sellableVolumes := getSellableVolumes(positions, balances)
+	askCoins := make([]string, 0, len(sellableVolumes))
+	for market := range sellableVolumes {
+		askCoins = append(askCoins, market)
+	}
+	sort.Strings(askCoins)
 
 	if len(askCoins) > 0 {
-		gLogger.Printf("매도 가능 잔고 : %v\n", askCoins)
+		runner.logger.Printf("매도 가능 잔고 : %v\n", askCoins)
 	} else {
-		gLogger.Println("매도 가능 잔고 없음")
+		runner.logger.Println("매도 가능 잔고 없음")
 	}
 
 	// 매도가능 잔고에서  Target으로 잡은 코인만 추출한다.
-	targetAskCoins := checkTargetCoins(gConfig, askCoins)
+	targetAskCoins := checkTargetCoins(runner.config, askCoins)
 
 	if len(targetAskCoins) <= 0 {
-		gLogger.Println("매도 가능 타겟 잔고 없음")
+		runner.logger.Println("매도 가능 타겟 잔고 없음")
 	}
 
 	if len(targetAskCoins) > 0 {
 		// Candle 정보 및 현재가 정보를 가져온다.
-		gLogger.Printf("잔고 매도 전략 수행 %v\n", targetAskCoins)
+		runner.logger.Printf("잔고 매도 전략 수행 %v\n", targetAskCoins)
 
 		// candleMap := runner.getDayCandlesByCoins(targetAskCoins)
 
-		runner.askOrder(targetAskCoins, balances, candleMap)
+		runner.askOrder(targetAskCoins, sellableVolumes, candleMap)
 
 	} else if len(ordersMap[types.ORDERSIDE_ASK]) > 0 {
 
 		waitOrderCoins := upbitTool.GetCoinsFromOrders(ordersMap[types.ORDERSIDE_ASK])
-		waitTargetCoins := checkTargetCoins(gConfig, waitOrderCoins)
+		waitTargetCoins := checkTargetCoins(runner.config, waitOrderCoins)
 
-		gLogger.Println("미체결 오더 확인 ")
+		runner.logger.Println("미체결 오더 확인 ")
 		if len(waitTargetCoins) > 0 {
 			// candleMap := runner.getDayCandlesByCoins(waitTargetCoins)
 
-			runner.forceAskOrder(gConfig, ordersMap[types.ORDERSIDE_ASK], waitTargetCoins, candleMap)
+			runner.forceAskOrder(runner.config, runner.own.FilterOrders(ordersMap[types.ORDERSIDE_ASK]), waitTargetCoins, candleMap, positions)
 		}
 
 	} else {
 
 	}
 
-	gLogger.Println("[매도 전략 수행 종료]")
+	runner.logger.Println("[매도 전략 수행 종료]")
 
 }
 
 // 매수 전략
 func (runner *LarryRunner) runLarryBidStrategy(balances []*types.Balance,
+	positions map[string]float64,
 	ordersMap map[string][]*types.Order,
 	candleMap map[string][]*types.DayCandle,
 	kMap map[string]float64,
 	malScoreMap  map[string]float64) {
 
-	gLogger.Println()
-	gLogger.Println("[매수 전략 수행중]")
+	runner.logger.Println()
+	runner.logger.Println("[매수 전략 수행중]")
 
-	logBalance(balances)
-	logWaitOrders(ordersMap[types.ORDERSIDE_BID])
+	runner.logBalance(balances)
+	runner.logWaitOrders(ordersMap[types.ORDERSIDE_BID])
 
 	// 잔고에 없고 WaitingOrder가 없는 코인으로 탐색 대상을 잡는다.
-	availableCoins := getAvailableCoins(gConfig, balances, ordersMap)
+	// 이 전략의 보유 코인, 미체결 매수 주문만 본다.
+	availableCoins := getAvailableCoins(runner.config, positions, runner.own.FilterOrdersMap(ordersMap))
 
 	// targetBidCoins := checkTargetCoins(config, availableCoins)
 
 	// 전략 수행
-	runner.doStrategy(balances, availableCoins, candleMap, ordersMap, kMap, malScoreMap)
+	runner.doStrategy(balances, positions, availableCoins, candleMap, ordersMap, kMap, malScoreMap)
 
-	gLogger.Println("[매수 전략 수행 종료] ")
+	runner.logger.Println("[매수 전략 수행 종료] ")
 }
 
 
 func (runner *LarryRunner) cancelAllOrder(orders []*types.Order) {
 
 	for _, value := range orders {
-		order, _ := runner.client.CancelOrder(value.Uuid)
-		gLogger.Printf("주문 취소 : %s, %s", order.Uuid, order.Side)
+		order, err := runner.exchange.CancelOrder(value.Uuid)
+		if err != nil {
+			runner.logger.Printf("주문 취소 실패 : %s, %s\n", value.Uuid, err.Error())
+			continue
+		}
+		runner.logger.Printf("주문 취소 : %s, %s\n", order.Uuid, order.Side)
 	}
 }
 
@@ -355,7 +357,7 @@ func (runner *LarryRunner) getBalanceAndWaitOrders()(
 	ordersMap map[string][]*types.Order,
 	err error ) {
 	// check balance
-	balances, err = runner.client.Accounts()
+	balances, err = runner.exchange.Accounts()
 
 	if err != nil {
 		log.Println(err)
@@ -363,7 +365,7 @@ func (runner *LarryRunner) getBalanceAndWaitOrders()(
 	}
 
 	// 미체결 Order 확인
-	ordersMap, err = runner.client.OrdersMap("", types.ORDERSTATE_WAIT, 1, types.ORDERBY_DESC)
+	ordersMap, err = runner.exchange.OrdersMap("", types.ORDERSTATE_WAIT, 1, types.ORDERBY_DESC)
 	if err != nil {
 		log.Println(err)
 		return
@@ -372,6 +374,22 @@ func (runner *LarryRunner) getBalanceAndWaitOrders()(
 	return
 }
 
+/*
+ * 보유 코인의 매도 가능 수량 : 전략 보유 수량과 거래소 주문 가능 잔고 중 작은 값
+ */
+func getSellableVolumes(positions map[string]float64, balances []*types.Balance) (volumes map[string]float64) {
+	volumes = make(map[string]float64)
+
+	for market, volume := range positions {
+		sellable := math.Min(volume, quote.Available(balances, quote.Base(market)))
+		if sellable > 0 {
+			volumes[market] = sellable
+		}
+	}
+
+	return
+}
+
 /*
  * 주문 가능 원화 잔고를 가져온다.
  */
@@ -391,6 +409,26 @@ func getAvailableKrwBalance(balances []*types.Balance) (krwBalance float64) {
 	return
 }
 
+/*
+ * 자금 배분기로 이번 주기의 전략 예산을 구한다. 자금 배분을 쓰지 않으면 nil
+ */
+func (runner *LarryRunner) getAllocation(balances []*types.Balance,
+	candleMap map[string][]*types.DayCandle) (allocation *allocator.Allocation) {
+
+	if runner.allocator == nil {
+		return
+	}
+
+	equity, missing := quote.Equity(runner.exchange, balances, candleMap, runner.config.LarryStrategy.ExcludedCurrencies)
+	if len(missing) > 0 {
+		runner.logger.Printf("[자금 배분] 원화 환산 실패로 평가 금액에서 빠진 코인 : %v\n", missing)
+	}
+
+	rates := quote.Rates(runner.exchange, quote.Currencies(runner.config.LarryStrategy.Targets), candleMap)
+
+	return runner.allocator.Allocate(strategyName, runner.store, equity, rates, runner.fees.For)
+}
+
 /*
  * 전략 실행
  * param
@@ -401,6 +439,7 @@ func getAvailableKrwBalance(balances []*types.Balance) (krwBalance float64) {
  */
 func (runner *LarryRunner) doStrategy(
 	balances []*types.Balance,
+	positions map[string]float64,
 	availableCoins []string,
 	candleMap map[string][]*types.DayCandle,
 	orderMap map[string][]*types.Order,
@@ -410,16 +449,24 @@ func (runner *LarryRunner) doStrategy(
 	// 주문 가능 잔고 체크
 	availableKrwBalance := getAvailableKrwBalance(balances)
 
-	gLogger.Printf("주문 가능 잔고 : %f\n", availableKrwBalance )
+	runner.logger.Printf("주문 가능 잔고 : %f\n", availableKrwBalance )
+
+	// 전략 예산 : 다른 전략이 쓰는 자금을 빼고 이 전략의 몫만 주문 가능 잔고로 본다.
+	allocation := runner.getAllocation(balances, candleMap)
+	if allocation != nil {
+		availableKrwBalance = math.Min(availableKrwBalance, allocation.Available)
+		runner.logger.Printf("[자금 배분] 예산 적용 주문 가능 잔고 : %f\n", availableKrwBalance)
+	}
 
-	if availableKrwBalance < float64(gConfig.LarryStrategy.OrderAmount) {
-		gLogger.Printf("주문 가능 잔고가 최소 주문 금액보다 적음 : %f\n", availableKrwBalance)
+	if availableKrwBalance < float64(runner.config.LarryStrategy.OrderAmount) {
+		runner.logger.Printf("주문 가능 잔고가 최소 주문 금액보다 적음 : %f\n", availableKrwBalance)
 		return
 	}
 
-	if len(balances) - 1 >= gConfig.LarryStrategy.MaxCoin {
-		gLogger.Printf("기존 보유 코인이 설정값 초과 : 보유코인 %d, 설정값 %d\n",
-			len(balances) - 1, gConfig.LarryStrategy.MaxCoin)
+	// 이 전략이 보유한 코인만 센다.
+	if len(positions) >= runner.config.LarryStrategy.MaxCoin {
+		runner.logger.Printf("기존 보유 코인이 설정값 초과 : 보유코인 %d, 설정값 %d\n",
+			len(positions), runner.config.LarryStrategy.MaxCoin)
 		return
 	}
 
@@ -434,7 +481,7 @@ func (runner *LarryRunner) doStrategy(
 
 			// 고가 - 저가 = 범위값
 			rangeValue := candleInfo[1].HighPrice - candleInfo[1].LowPrice
-			kValue := gConfig.LarryStrategy.KValue
+			kValue := runner.config.LarryStrategy.KValue
 
 			if coinKValue, valueExist := kMap[coinName]; valueExist {
 				kValue = coinKValue
@@ -442,62 +489,71 @@ func (runner *LarryRunner) doStrategy(
 
 			kAppliedValue := rangeValue * kValue
 
-			gLogger.Printf("==== 전략 수행 코인 : %s ====\n", coinName)
-			gLogger.Printf("전일 고가 : %f, 전일 저가 : %f , Range : %f, Range-K value : %f\n",
+			runner.logger.Printf("==== 전략 수행 코인 : %s ====\n", coinName)
+			runner.logger.Printf("전일 고가 : %f, 전일 저가 : %f , Range : %f, Range-K value : %f\n",
 				candleInfo[1].HighPrice, candleInfo[1].LowPrice, rangeValue, kValue)
 
 			// 변동성 조건에 해당함.
 			bidValue := candleInfo[0].OpeningPrice + kAppliedValue
 
-			gLogger.Printf("당일 시가 %f\n", candleInfo[0].OpeningPrice)
-			gLogger.Printf("이동편균 Score %f, 변동성 적용 가격 %f\n", malScoreMap[coinName], kAppliedValue)
-			gLogger.Printf("매수 조건 가격 %f\n", bidValue)
-			gLogger.Printf("현재 가격 %f\n", candleInfo[0].TradePrice)
+			runner.logger.Printf("당일 시가 %f\n", candleInfo[0].OpeningPrice)
+			runner.logger.Printf("이동편균 Score %f, 변동성 적용 가격 %f\n", malScoreMap[coinName], kAppliedValue)
+			runner.logger.Printf("매수 조건 가격 %f\n", bidValue)
+			runner.logger.Printf("현재 가격 %f\n", candleInfo[0].TradePrice)
 
 			if candleInfo[0].TradePrice >= bidValue {
 				// 매수 주문 실행.
-				// priceStr :=  fmt.Sprintf("%.8f", bidValue)
-				priceStr := upbitTool.GetPriceCanOrder(bidValue)
-
-				orderAmount := getOrderAmount(gConfig.LarryStrategy.OrderAmount, malScoreMap, coinName)
-				// orderAmount := gConfig.LarryStrategy.OrderAmount * malScoreMap[coinName]
-				// volumeStr := fmt.Sprintf("%.8f", gConfig.LarryStrategy.OrderAmount/bidValue)
+				orderAmount := getOrderAmount(runner.config.LarryStrategy.OrderAmount, malScoreMap, coinName)
+				// orderAmount := runner.config.LarryStrategy.OrderAmount * malScoreMap[coinName]
+				// volumeStr := fmt.Sprintf("%.8f", runner.config.LarryStrategy.OrderAmount/bidValue)
 
 				if orderAmount <= 0 {
-					gLogger.Printf("**** 매수 신호 발생  , 매수 주문 Coin : %s , Price : %s, Volume : 0.0\n",
-						coinName, priceStr)
+					runner.logger.Printf("**** 매수 신호 발생  , 매수 주문 Coin : %s , Price : %f, Volume : 0.0\n",
+						coinName, bidValue)
 					continue
 				}
 
-				volumeStr := fmt.Sprintf("%.8f", orderAmount/bidValue)
+				if allocation != nil {
+					if allocationErr := allocation.Check(orderAmount); allocationErr != nil {
+						runner.logger.Printf("**** 매수 신호 발생, 주문 거절 : %s\n", allocationErr.Error())
+						continue
+					}
+				}
 
-				gLogger.Printf("**** 매수 신호 발생  , 매수 주문 Coin : %s , Price : %s, Volume : %s\n",
-					coinName, priceStr, volumeStr)
+				// 호가 단위로 내린 가격 기준으로 수수료를 포함해 주문 금액을 넘지 않는 수량을 구한다.
+				orderRules := rules.For(runner.exchange.Name(), coinName)
+				bidPrice := orderRules.Price(types.ORDERSIDE_BID, money.NewFromFloat(bidValue))
+				bidVolume := runner.fees.For(coinName).BidVolume(money.NewFromFloat(orderAmount), bidPrice)
 
-				bidOrder := types.OrderInfo{
-					Identifier: strconv.Itoa(int(upbitUtil.TimeStamp())),
-					Side:       types.ORDERSIDE_BID,
-					Market:     coinName,
-					Price:      priceStr,
-					Volume:     volumeStr,
-					OrdType:    types.ORDERTYPE_LIMIT}
+				bidOrder, err := runner.limitOrder(orderRules, types.ORDERSIDE_BID, bidPrice, bidVolume)
+				if err != nil {
+					runner.logger.Printf("**** 매수 신호 발생, 주문 불가 : %s\n", err.Error())
+					continue
+				}
 
-				order, err := runner.client.OrderByInfo(bidOrder)
+				runner.logger.Printf("**** 매수 신호 발생  , 매수 주문 Coin : %s , Price : %s, Volume : %s\n",
+					coinName, bidOrder.Price, bidOrder.Volume)
+
+				order, err := runner.placeOrder(bidOrder)
 
 				if err != nil {
-					gLogger.Println("주문 에러 ")
+					runner.logger.Println("주문 에러 ")
 				} else {
 					if len(order.Uuid) > 0 {
-						gLogger.Println("매수 성공 ")
-						gLogger.Printf("코인 %s, 주문가격 : %s, 주문수량 :%s", order.Market, order.Price, order.Volume)
+						runner.logger.Println("매수 성공 ")
+						runner.logger.Printf("코인 %s, 주문가격 : %s, 주문수량 :%s", order.Market, order.Price, order.Volume)
+
+						if allocation != nil {
+							allocation.Use(orderAmount)
+						}
 					}
 				}
 			} else {
-				gLogger.Printf("==== 매수 신호 대기 ====\n")
+				runner.logger.Printf("==== 매수 신호 대기 ====\n")
 			}
 		}
 
-		gLogger.Printf("\n")
+		runner.logger.Printf("\n")
 	}
 
 	return
@@ -512,21 +568,40 @@ func getOrderAmount(defaultAmount float64, malScoreMap map[string]float64, coinN
 
 	return
 }
+
+/*
+ * 거래소 주문 규칙에 맞춘 지정가 주문을 만든다.
+ * 규칙에 맞지 않으면(최소 주문 금액 미만 등) Identifier 를 발급하지 않고 에러를 돌려준다.
+ */
+func (runner *LarryRunner) limitOrder(orderRules *rules.Rules,
+	side string,
+	price money.Decimal,
+	volume money.Decimal) (orderInfo types.OrderInfo, err error) {
+
+	orderInfo, err = orderRules.LimitOrder(side, price, volume)
+	if err != nil {
+		return
+	}
+
+	orderInfo.Identifier = runner.idGenerator.Next(orderRules.Market)
+
+	return
+}
+
 /*
  * 전략 수행에 적용할 코인 목록 가져오기
  * 이미 잔고에 없고, 미체결 주문이 들어가지 않은 코인을 가져온다.
  */
 func getAvailableCoins(config *model.Config,
-	balances []*types.Balance,
+	positions map[string]float64,
 	orderMap map[string][]*types.Order) (checkCoins []string ) {
 
-	balanceMap := upbitTool.GetBalanceMap(balances)
 	checkCoins = make([]string, 0)
 
 	for i := 0; i < len(config.LarryStrategy.Targets); i++ {
 		coin := config.LarryStrategy.Targets[i]
 
-		if _, exist := balanceMap[coin]; !exist {
+		if _, exist := positions[coin]; !exist {
 			if _, exist := upbitTool.ExistOrder(coin, orderMap, types.ORDERSIDE_BID); !exist {
 				checkCoins = append(checkCoins, coin)
 			}
@@ -536,30 +611,27 @@ func getAvailableCoins(config *model.Config,
 	return
 }
 
-func (runner *LarryRunner) askOrder(coins []string, balances []*types.Balance, candleMap map[string][]*types.DayCandle) {
-	balanceMap := upbitTool.GetBalanceMap(balances)
-
+func (runner *LarryRunner) askOrder(coins []string, sellableVolumes map[string]float64, candleMap map[string][]*types.DayCandle) {
 	for _, value := range coins {
-		priceStr :=  fmt.Sprintf("%.8f", candleMap[value][0].TradePrice)
-		volumeStr :=  balanceMap[value].Balance
+		askOrder, err := runner.limitOrder(rules.For(runner.exchange.Name(), value),
+			types.ORDERSIDE_ASK,
+			money.NewFromFloat(candleMap[value][0].TradePrice),
+			money.NewFromFloat(sellableVolumes[value]))
 
-		gLogger.Printf("매도 주문 수행 %s : 가격 , %s , 수량 , %s", value, priceStr, volumeStr)
+		if err != nil {
+			runner.logger.Printf("매도 주문 불가 : %s\n", err.Error())
+			continue
+		}
 
-		askOrder := types.OrderInfo{
-			Identifier: strconv.Itoa(int(upbitUtil.TimeStamp())),
-			Side:       types.ORDERSIDE_ASK,
-			Market:     value,
-			Price:      priceStr,
-			Volume:     volumeStr,
-			OrdType:    types.ORDERTYPE_LIMIT}
+		runner.logger.Printf("매도 주문 수행 %s : 가격 , %s , 수량 , %s", value, askOrder.Price, askOrder.Volume)
 
-		_, err := runner.client.OrderByInfo(askOrder)
+		_, err = runner.placeOrder(askOrder)
 
 		if err != nil {
 			// fmt.Println("주문 에러")
-			gLogger.Println("주문 에러")
+			runner.logger.Println("주문 에러")
 		} else {
-			gLogger.Println("주문 성공")
+			runner.logger.Println("주문 성공")
 		}
 	}
 }
@@ -568,9 +640,9 @@ func (runner *LarryRunner) askOrder(coins []string, balances []*types.Balance, c
 /*
  * 매도 미체결이 계속 남아있으면 가격을 낮춰서라도 강제 매도
  */
-func (runner *LarryRunner) forceAskOrder(config *model.Config, orders[]*types.Order, targetCoins []string, candleMap map[string][]*types.DayCandle) {
+func (runner *LarryRunner) forceAskOrder(config *model.Config, orders[]*types.Order, targetCoins []string, candleMap map[string][]*types.DayCandle, positions map[string]float64) {
 
-	gLogger.Println("강제 매도 수행")
+	runner.logger.Println("강제 매도 수행")
 	for _, value := range orders {
 
 		if upbitTool.IsExist(value.Market, targetCoins) {
@@ -581,19 +653,32 @@ func (runner *LarryRunner) forceAskOrder(config *model.Config, orders[]*types.Or
 			elapsedSeconds := int(timeGap.Seconds())
 
 			if elapsedSeconds > config.LarryStrategy.AskOrderGap  {
+				// 부분 체결된 주문은 남은 수량만 다시 낸다.
+				// 취소 후 다시 낼 수 없는 주문(최소 주문 금액 미만 등)은 취소하지 않는다.
+				askOrder, orderErr := runner.limitOrder(rules.For(runner.exchange.Name(), value.Market),
+					types.ORDERSIDE_ASK,
+					money.NewFromFloat(candleMap[value.Market][0].TradePrice),
+					remainingAskVolume(value, positions))
+
+				if orderErr != nil {
+					runner.logger.Printf("매도 가격 조정 불가 : %s\n", orderErr.Error())
+					continue
+				}
+
 				//	client.CancelOrder(value.Uuid)
 				fmt.Println("주문 진행 시간 : " + strconv.Itoa(elapsedSeconds) + ", 주문취소 : " + value.Uuid)
-				gLogger.Println(value.Market + ", 주문 진행 시간 : " + strconv.Itoa(elapsedSeconds) + ", 주문취소 : " + value.Uuid)
+				runner.logger.Println(value.Market + ", 주문 진행 시간 : " + strconv.Itoa(elapsedSeconds) + ", 주문취소 : " + value.Uuid)
 
-				_, err := runner.client.CancelOrder(value.Uuid)
+				_, err := runner.exchange.CancelOrder(value.Uuid)
 
 				if err != nil {
-					gLogger.Printf("주문 취소 실패 : %s\n" + err.Error())
+					runner.logger.Printf("주문 취소 실패 : %s\n" + err.Error())
 				} else {
 
-					gLogger.Println("매도 주문 실행 ")
-					gLogger.Printf("코인 : %s, 주문수량 : %s, 주문가격 : %f\n", value.Market, value.Volume, candleMap[value.Market][0].TradePrice)
-					upbitTool.AskOrder(runner.client, value.Market, value.Volume, candleMap[value.Market][0],  types.ORDERTYPE_LIMIT)
+					runner.logger.Println("매도 주문 실행 ")
+					runner.logger.Printf("코인 : %s, 주문수량 : %s, 주문가격 : %s\n", value.Market, askOrder.Volume, askOrder.Price)
+
+					runner.placeOrder(askOrder)
 				}
 			}
 		}
@@ -605,27 +690,89 @@ func (runner *LarryRunner) forceAskOrder(config *model.Config, orders[]*types.Or
 
 
 
+/*
+ * 미체결 매도 주문의 남은 수량 (거래소가 알려주지 않으면 봇 보유 수량)
+ */
+func remainingAskVolume(order *types.Order, positions map[string]float64) money.Decimal {
+	if volume, known := exchange.RemainingVolume(order); known {
+		return volume
+	}
+
+	return money.NewFromFloat(positions[order.Market])
+}
+
+/*
+ * 주문 실행
+ * 일시적인 오류면 같은 Identifier 로 재시도하고, 중복으로 거절되면 Identifier 로 접수된 주문을 찾는다.
+ * 성공한 주문은 상태 파일에 기록한다.
+ */
+func (runner *LarryRunner) placeOrder(orderInfo types.OrderInfo) (order *types.Order, err error) {
+	for attempt := 1; attempt <= orderRetryCount; attempt++ {
+		order, err = runner.exchange.OrderByInfo(orderInfo)
+		if err == nil {
+			break
+		}
+
+		runner.logger.Printf("주문 실패 (%d/%d) : %s, %s\n", attempt, orderRetryCount, orderInfo.Identifier, err.Error())
+
+		// 이전 시도가 응답만 받지 못하고 들어간 주문이면 Identifier 로 찾아 기록한다.
+		if exchange.IsDuplicateIdentifier(err) {
+			if order, err = exchange.OrderByIdentifier(runner.exchange, orderInfo.Identifier); err == nil {
+				runner.logger.Printf("이미 접수된 주문 : %s, %s\n", orderInfo.Identifier, order.Uuid)
+			}
+			break
+		}
+
+		// 잔고 부족 등 요청 자체가 거절된 경우는 다시 보내지 않는다.
+		if !exchange.IsRetryable(err) {
+			break
+		}
+
+		if attempt < orderRetryCount {
+			time.Sleep(orderRetryDelay)
+		}
+	}
+
+	runner.recordOrder(orderInfo.Identifier, order, err)
+
+	return
+}
+
+/*
+ * 성공한 주문을 상태 파일에 기록한다.
+ */
+func (runner *LarryRunner) recordOrder(id string, order *types.Order, err error) {
+	if err != nil {
+		runner.logger.Printf("주문 에러 : %s, %s\n", id, err.Error())
+		return
+	}
+
+	if storeErr := runner.store.RecordOrder(id, order); storeErr != nil {
+		runner.logger.Printf("주문 기록 실패 : %s, %s\n", id, storeErr.Error())
+	}
+}
+
 /*
  * 밸런스 로깅
  */
-func logBalance(balances []*types.Balance) {
-	gLogger.Println("[잔고 현황] ")
+func (runner *LarryRunner) logBalance(balances []*types.Balance) {
+	runner.logger.Println("[잔고 현황] ")
 	for _, value := range balances {
-		gLogger.Printf("코인 : %s, 잔고 : %s\n", value.Currency, value.Balance)
+		runner.logger.Printf("코인 : %s, 잔고 : %s\n", value.Currency, value.Balance)
 	}
 }
 
 /*
  * 미체결 오더 잔고 로깅
  */
-func logWaitOrders(orders []*types.Order) {
-	gLogger.Println("[미체결 주문 현황] ")
+func (runner *LarryRunner) logWaitOrders(orders []*types.Order) {
+	runner.logger.Println("[미체결 주문 현황] ")
 	if len(orders) >  0 {
 		for _, value := range orders {
-			gLogger.Printf("코인 : %s, 주문량 %s, 주문가격 %s\n", value.Market, value.Volume, value.Price)
+			runner.logger.Printf("코인 : %s, 주문량 %s, 주문가격 %s\n", value.Market, value.Volume, value.Price)
 		}
 	} else {
-		gLogger.Println("미체결 주문 없음")
+		runner.logger.Println("미체결 주문 없음")
 	}
 }
 
@@ -653,11 +800,13 @@ func (runner *LarryRunner) processProfit(stopLossRate float64, balances []*types
 
 		if stopLossRate > profitRate {
 			if order, exist := upbitTool.ExistOrder(coinStr, ordersMap, types.ORDERSIDE_ASK); exist {
-				runner.client.CancelOrder(order.Uuid)
+				runner.exchange.CancelOrder(order.Uuid)
 			}
 
 			// Ask Order
-			upbitTool.AskMarketOrder(runner.client, coinStr, balance.Balance)
+			identifier := runner.idGenerator.Next(coinStr)
+			askOrder, err := runner.exchange.AskMarketOrder(identifier, coinStr, balance.Balance)
+			runner.recordOrder(identifier, askOrder, err)
 
 
 		}
@@ -0,0 +1,17 @@
+package lw_basic
+
+import (
+	"raindrop/main/allocator"
+)
+
+/*
+ * 자금 배분기로 이번 주기의 전략 예산을 구한다. 자금 배분을 쓰지 않으면 nil
+ * rates : 기준 통화의 원화 환산 비율 (사용 중인 자금을 원화로 바꿀 때 사용)
+ */
+func (runner *LarryRunner) getAllocation(equity float64, rates map[string]float64) (allocation *allocator.Allocation) {
+	if runner.allocator == nil {
+		return
+	}
+
+	return runner.allocator.Allocate(strategyName, runner.store, equity, rates, runner.fees.For)
+}
@@ -0,0 +1,192 @@
+package lw_basic
+
+import (
+	"fmt"
+	"github.com/jekeun/upbit-go/types"
+	"raindrop/main/model"
+	"raindrop/main/risk"
+	"sort"
+	"strings"
+)
+
+/*
+ * 상관계수 진입 제한
+ * 변동성이 큰 날은 원화 알트코인이 함께 돌파해 max_coin 을 거의 같은 방향의 포지션으로 채우게 된다.
+ * 최근 일간 수익률로 매수 후보와 보유 코인의 상관계수를 구해 max_correlation 이상이면 매수하지 않거나 주문 금액을 줄인다.
+ */
+
+const (
+	CorrelationActionRefuse = "refuse"
+	CorrelationActionShrink = "shrink"
+
+	defaultMaxCorrelation          = 0.8
+	defaultCorrelationScaleRate    = 50.0
+	defaultCorrelationLookbackDays = 18
+
+	// 상관계수를 믿을 수 있는 최소 공통 수익률 수, 모자라면 그 코인과는 비교하지 않는다.
+	minCorrelationSamples = 5
+)
+
+/*
+ * 매수 후보와 상관계수가 높아 진입을 막은 보유 코인
+ */
+type CorrelationBlocker struct {
+	Market      string
+	Correlation float64
+}
+
+/*
+ * 설정 기본값을 채운다.
+ */
+func correlationConfigWithDefaults(config model.CorrelationConfig) model.CorrelationConfig {
+	if config.MaxCorrelation <= 0 {
+		config.MaxCorrelation = defaultMaxCorrelation
+	}
+
+	if config.Action != CorrelationActionShrink {
+		config.Action = CorrelationActionRefuse
+	}
+
+	if config.ScaleRate <= 0 {
+		config.ScaleRate = defaultCorrelationScaleRate
+	}
+
+	if config.LookbackDays <= 0 {
+		config.LookbackDays = defaultCorrelationLookbackDays
+	}
+
+	return config
+}
+
+/*
+ * 보유 코인(held) 중 market 과 상관계수가 max_correlation 이상인 코인 (상관계수 높은 순)
+ * amountRate : 주문 금액에 곱할 비율 (1 : 제한 없음, 0 : 매수 안함)
+ * returns : 마켓별 일간 수익률 (최신순)
+ */
+func EvaluateCorrelation(config model.CorrelationConfig,
+	market string,
+	held []string,
+	returns map[string][]float64) (blockers []CorrelationBlocker, amountRate float64) {
+
+	config = correlationConfigWithDefaults(config)
+	amountRate = 1.0
+
+	candidate := returns[market]
+	for _, holding := range held {
+		if holding == market {
+			continue
+		}
+
+		other := returns[holding]
+		if len(candidate) < minCorrelationSamples || len(other) < minCorrelationSamples {
+			continue
+		}
+
+		if correlation := risk.Correlation(candidate, other); correlation >= config.MaxCorrelation {
+			blockers = append(blockers, CorrelationBlocker{Market: holding, Correlation: correlation})
+		}
+	}
+
+	if len(blockers) == 0 {
+		return
+	}
+
+	sort.Slice(blockers, func(i, j int) bool {
+		return blockers[i].Correlation > blockers[j].Correlation
+	})
+
+	if config.Action == CorrelationActionShrink {
+		amountRate = config.ScaleRate / 100
+	} else {
+		amountRate = 0
+	}
+
+	return
+}
+
+/*
+ * 상관계수 계산에 쓰는 수익률 기간 (일)
+ */
+func CorrelationLookbackDays(config model.CorrelationConfig) int {
+	return correlationConfigWithDefaults(config).LookbackDays
+}
+
+func formatBlockers(blockers []CorrelationBlocker) string {
+	values := make([]string, 0, len(blockers))
+	for _, blocker := range blockers {
+		values = append(values, fmt.Sprintf("%s %.2f", blocker.Market, blocker.Correlation))
+	}
+
+	return strings.Join(values, ", ")
+}
+
+/*
+ * 이번 주기의 보유 코인(봇 보유 + 봇 미체결 매수 주문)과 수익률
+ */
+type correlationFilter struct {
+	config  model.CorrelationConfig
+	held    []string
+	returns map[string][]float64
+}
+
+/*
+ * 상관계수 제한을 쓰지 않으면 nil
+ */
+func (runner *LarryRunner) getCorrelationFilter(positions map[string]float64,
+	orderMap map[string][]*types.Order,
+	candleMap map[string][]*types.DayCandle) (filter *correlationFilter) {
+
+	config := correlationConfigWithDefaults(runner.config.LarryStrategy.Correlation)
+	if config.Enable != 1 {
+		return
+	}
+
+	filter = &correlationFilter{
+		config:  config,
+		returns: make(map[string][]float64)}
+
+	for market := range positions {
+		filter.add(market)
+	}
+	for _, order := range runner.store.FilterOwnOrders(orderMap[types.ORDERSIDE_BID]) {
+		filter.add(order.Market)
+	}
+	sort.Strings(filter.held)
+
+	markets := append(append([]string{}, runner.targets...), filter.held...)
+	for market, candles := range runner.getReturnCandles(markets, config.LookbackDays+2, candleMap) {
+		filter.returns[market] = risk.Returns(candles, config.LookbackDays)
+	}
+
+	return
+}
+
+func (filter *correlationFilter) add(market string) {
+	for _, holding := range filter.held {
+		if holding == market {
+			return
+		}
+	}
+
+	filter.held = append(filter.held, market)
+}
+
+/*
+ * 보유 코인과 상관계수가 높으면 주문 금액을 줄이거나 0 으로 만들고, 막은 보유 코인을 로그로 남긴다.
+ */
+func (runner *LarryRunner) limitCorrelation(filter *correlationFilter, market string, amount float64) float64 {
+	blockers, amountRate := EvaluateCorrelation(filter.config, market, filter.held, filter.returns)
+	if len(blockers) == 0 {
+		return amount
+	}
+
+	if amountRate <= 0 {
+		runner.logger.Printf("[상관계수] %s 매수 안함 : 상관계수 %.2f 이상 보유 코인 %s\n",
+			market, filter.config.MaxCorrelation, formatBlockers(blockers))
+	} else {
+		runner.logger.Printf("[상관계수] %s 주문 금액 %.0f%% 적용 : 상관계수 %.2f 이상 보유 코인 %s\n",
+			market, amountRate*100, filter.config.MaxCorrelation, formatBlockers(blockers))
+	}
+
+	return amount * amountRate
+}
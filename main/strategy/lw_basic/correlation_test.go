@@ -0,0 +1,111 @@
+package lw_basic
+
+import (
+	"raindrop/main/model"
+	"testing"
+)
+
+func TestCorrelationConfigWithDefaults(t *testing.T) {
+	tests := []struct {
+		name   string
+		config model.CorrelationConfig
+		want   model.CorrelationConfig
+	}{
+		{"empty", model.CorrelationConfig{},
+			model.CorrelationConfig{MaxCorrelation: 0.8, Action: CorrelationActionRefuse, ScaleRate: 50, LookbackDays: 18}},
+		{"shrink", model.CorrelationConfig{Enable: 1, MaxCorrelation: 0.7, Action: CorrelationActionShrink, ScaleRate: 30, LookbackDays: 30},
+			model.CorrelationConfig{Enable: 1, MaxCorrelation: 0.7, Action: CorrelationActionShrink, ScaleRate: 30, LookbackDays: 30}},
+		// 모르는 동작은 매수 안함으로 본다.
+		{"unknown action", model.CorrelationConfig{Action: "skip", ScaleRate: -1, LookbackDays: -5},
+			model.CorrelationConfig{MaxCorrelation: 0.8, Action: CorrelationActionRefuse, ScaleRate: 50, LookbackDays: 18}},
+	}
+
+	for _, test := range tests {
+		if config := correlationConfigWithDefaults(test.config); config != test.want {
+			t.Errorf("%s : config = %+v, want %+v", test.name, config, test.want)
+		}
+	}
+}
+
+func TestEvaluateCorrelation(t *testing.T) {
+	base := []float64{0.01, 0.02, -0.01, 0.03, -0.02}
+	returns := map[string][]float64{
+		"KRW-XRP":  base,
+		"KRW-ETH":  {0.02, 0.04, -0.02, 0.06, -0.04},  // 1.00
+		"KRW-ADA":  {0.02, 0.01, -0.01, 0.03, -0.02},  // 0.94
+		"KRW-DOGE": {0.01, -0.02, 0.01, 0.03, -0.02},  // 0.47
+		"KRW-SOL":  {-0.01, -0.02, 0.01, -0.03, 0.02}, // -1.00
+		"KRW-NEW":  {0.02, 0.04, -0.02, 0.06},
+	}
+
+	refuse := model.CorrelationConfig{Enable: 1}
+	shrink := model.CorrelationConfig{Enable: 1, Action: CorrelationActionShrink, ScaleRate: 40}
+
+	tests := []struct {
+		name       string
+		config     model.CorrelationConfig
+		held       []string
+		blockers   []string
+		amountRate float64
+	}{
+		{"no holdings", refuse, nil, nil, 1},
+		{"refuse", refuse, []string{"KRW-ETH"}, []string{"KRW-ETH"}, 0},
+		{"shrink", shrink, []string{"KRW-ETH"}, []string{"KRW-ETH"}, 0.4},
+		{"below max", refuse, []string{"KRW-DOGE", "KRW-SOL"}, nil, 1},
+		{"lower max", model.CorrelationConfig{Enable: 1, MaxCorrelation: 0.4}, []string{"KRW-DOGE", "KRW-SOL"}, []string{"KRW-DOGE"}, 0},
+		// 상관계수 높은 순
+		{"sorted", refuse, []string{"KRW-ADA", "KRW-DOGE", "KRW-ETH"}, []string{"KRW-ETH", "KRW-ADA"}, 0},
+		// 이미 보유한 코인 자신과는 비교하지 않는다.
+		{"self", refuse, []string{"KRW-XRP"}, nil, 1},
+		// 표본이 모자라거나 없는 코인과는 비교하지 않는다.
+		{"too few samples", refuse, []string{"KRW-NEW", "KRW-BTC"}, nil, 1},
+	}
+
+	for _, test := range tests {
+		blockers, amountRate := EvaluateCorrelation(test.config, "KRW-XRP", test.held, returns)
+
+		markets := make([]string, 0, len(blockers))
+		for _, blocker := range blockers {
+			markets = append(markets, blocker.Market)
+		}
+
+		if amountRate != test.amountRate || !equalStrings(markets, test.blockers) {
+			t.Errorf("%s : blockers = %v, amountRate = %v, want %v, %v", test.name, markets, amountRate, test.blockers, test.amountRate)
+		}
+	}
+
+	// 후보 코인의 표본이 모자라면 제한하지 않는다.
+	if blockers, amountRate := EvaluateCorrelation(refuse, "KRW-NEW", []string{"KRW-ETH"}, returns); len(blockers) != 0 || amountRate != 1 {
+		t.Errorf("short candidate : blockers = %v, amountRate = %v", blockers, amountRate)
+	}
+}
+
+func TestFormatBlockers(t *testing.T) {
+	tests := []struct {
+		blockers []CorrelationBlocker
+		want     string
+	}{
+		{nil, ""},
+		{[]CorrelationBlocker{{"KRW-ETH", 0.987}}, "KRW-ETH 0.99"},
+		{[]CorrelationBlocker{{"KRW-ETH", 1}, {"KRW-ADA", 0.9418}}, "KRW-ETH 1.00, KRW-ADA 0.94"},
+	}
+
+	for _, test := range tests {
+		if text := formatBlockers(test.blockers); text != test.want {
+			t.Errorf("formatBlockers = %q, want %q", text, test.want)
+		}
+	}
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
+	}
+
+	return true
+}
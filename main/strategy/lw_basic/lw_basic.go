@@ -2,13 +2,20 @@ package lw_basic
 
 import (
 	"fmt"
-	"github.com/jekeun/upbit-go"
 	upbitTool "github.com/jekeun/upbit-go/tool"
 	"github.com/jekeun/upbit-go/types"
-	upbitUtil "github.com/jekeun/upbit-go/util"
 	"log"
 	"math"
+	"raindrop/main/allocator"
+	"raindrop/main/exchange"
+	"raindrop/main/fee"
 	"raindrop/main/model"
+	"raindrop/main/money"
+	"raindrop/main/quote"
+	"raindrop/main/rules"
+	"raindrop/main/state"
+	"raindrop/main/utils/identifier"
+	"raindrop/main/universe"
 	"strconv"
 	"time"
 )
@@ -17,25 +24,59 @@ import (
  * Larry Williams Basic 변동성 전략 수행
  */
 
-var gLogger *log.Logger
-var gConfig *model.Config
-var gCurrentMode = BID_MODE
-
 const (
 	BID_MODE = 1 + iota
 	ASK_MODE
 )
 
 type LarryRunner struct {
-	client *upbit.Client
+	exchange    exchange.Exchange
+	idGenerator *identifier.Generator
+	store       *state.Store
+	// 이 전략의 주문과 보유 수량 (계정에서 여러 전략을 실행하면 다른 전략의 것은 빠진다)
+	own         *state.StrategyView
+	fees        *fee.Provider
+	config      *model.Config
+	logger      *log.Logger
+	currentMode int
+
+	// 유니버스 선정 (설정하지 않으면 nil), staticTargets 는 설정 파일의 targets
+	universe      *universe.Selector
+	staticTargets []string
+	// 이번 주기의 타겟 코인 (유니버스를 쓰면 선정 결과 + 봇 보유, 미체결 코인), 공유 설정은 바꾸지 않는다.
+	targets []string
+
+	// 전략별 자금 배분 (설정하지 않으면 nil)
+	allocator *allocator.Allocator
 }
 
-const healthCheckCoin = "KRW-ETC"
+const strategyName = "lw_basic"
+
+// 네트워크, 서버 오류로 주문이 실패하면 같은 Identifier 로 재시도한다. 이미 접수된 주문이면 거래소가 중복으로 거절한다.
+const (
+	orderRetryCount = 3
+	orderRetryDelay = 500 * time.Millisecond
+)
 
-func (runner *LarryRunner) Init(config *model.Config, logger *log.Logger) {
-	runner.client = upbit.NewClient(config.Account.Accesskey, config.Account.SecretKey)
-	gConfig = config
-	gLogger = logger
+func (runner *LarryRunner) Init(config *model.Config, logger *log.Logger, store *state.Store, ex exchange.Exchange) {
+	runner.exchange = ex
+	runner.idGenerator = identifier.NewGenerator(strategyName)
+	runner.store = store
+	runner.own = store.View(strategyName, config.StrategyNames())
+	runner.fees = fee.NewProvider(ex, config.Fee)
+	runner.config = config
+	runner.logger = logger
+	runner.currentMode = BID_MODE
+	runner.staticTargets = append([]string{}, config.LarryStrategy.Targets...)
+	runner.targets = runner.staticTargets
+
+	if config.LarryStrategy.Universe.Enable == 1 {
+		runner.universe = universe.NewSelector(ex, config.LarryStrategy.Universe, logger)
+	}
+
+	if config.Allocator.Enable == 1 {
+		runner.allocator = allocator.New(config.Allocator, logger)
+	}
 }
 
 func (runner *LarryRunner) RunLWBasicStrategy() {
@@ -45,109 +86,95 @@ func (runner *LarryRunner) RunLWBasicStrategy() {
 
 	balances, ordersMap, _ := runner.getBalanceAndWaitOrders()
 
-	runner.healthCheck(ordersMap)
+	// 봇 보유 수량 계산을 위해 봇 주문의 체결 정보를 갱신한다.
+	if _, err := runner.store.SyncFills(runner.exchange.OrderFill); err != nil {
+		runner.logger.Printf("체결 내역 동기화 실패 : %s\n", err.Error())
+	}
+	positions := runner.getOwnPositions()
+
+	if runner.universe != nil {
+		runner.targets = runner.getUniverseTargets(positions, ordersMap)
+	}
 
-	candleMap := upbitTool.GetDayCandlesByCoins(runner.client, gConfig.LarryStrategy.Targets, 20)
+	candleMap := exchange.GetDayCandlesByCoins(runner.exchange, runner.targets, 20)
 
 	if len(candleMap) == 0 {
-		gLogger.Println("캔들 정보 얻어오기에 실패했음.")
+		runner.logger.Println("캔들 정보 얻어오기에 실패했음.")
 		return
 	}
 
-	//orderAmount := getOrderAmount(gConfig.LarryStrategy.OrderAmount,
-	//	gConfig.LarryStrategy.MoneyPlan,
+	//orderAmount := getOrderAmount(runner.config.LarryStrategy.OrderAmount,
+	//	runner.config.LarryStrategy.MoneyPlan,
 	//	candleMap["KRW-XRP"],
 	//	map[string]float64{"KRW-XRP":0.6}, "KRW-XRP")
 
 	//fmt.Println(orderAmount)
 	// 스탑로스 or 익절 체크
 	// 기본 로직은 StopLoss 및 StopProfit 을 적용하지 않는다.
-	// runner.processStop(gConfig.LarryStrategy.StopLoss, balances, ordersMap, candleMap)
+	// runner.processStop(runner.config.LarryStrategy.StopLoss, balances, ordersMap, candleMap)
 
 	kMap := getKNoiseValueByDay(candleMap)
 	malMap := getMovingAverageLineByDay(candleMap)
 	malScoreMap := getMalScore(malMap, candleMap)
 
 	// 주어진 시각, 최대 5분간 잔고 매도만 수행한다.
-	if now.Hour() == gConfig.LarryStrategy.StartTime &&
-		now.Minute() <= gConfig.LarryStrategy.AskPeriodMinute {
-		runner.runLarryAskStrategy(balances, ordersMap, candleMap)
-		gCurrentMode = ASK_MODE
+	if now.Hour() == runner.config.LarryStrategy.StartTime &&
+		now.Minute() <= runner.config.LarryStrategy.AskPeriodMinute {
+		runner.runLarryAskStrategy(balances, positions, ordersMap, candleMap)
+		runner.currentMode = ASK_MODE
 	} else {
-		if gCurrentMode == ASK_MODE {
-			runner.forceAskMarketOrder(ordersMap)
+		if runner.currentMode == ASK_MODE {
+			runner.forceAskMarketOrder(ordersMap, positions)
 		}
 
-		gCurrentMode = BID_MODE
+		runner.currentMode = BID_MODE
 
-		runner.runLarryBidStrategy(balances, ordersMap, candleMap, kMap, malScoreMap)
+		runner.runLarryBidStrategy(balances, positions, ordersMap, candleMap, kMap, malScoreMap)
 	}
 }
 
 /*
- * 현재 매도 미체결 내역에 대해 강제 청산을 수행한다.
+ * 현재 매도 미체결 내역에 대해 강제 청산을 수행한다. (부분 체결된 주문은 남은 수량만 매도)
  */
-func (runner *LarryRunner) forceAskMarketOrder(ordersMap map[string][]*types.Order) {
+func (runner *LarryRunner) forceAskMarketOrder(ordersMap map[string][]*types.Order, positions map[string]float64) {
 	if askOrders, exist := ordersMap[types.ORDERSIDE_ASK]; exist {
-		for _, order := range askOrders {
+		for _, order := range runner.own.FilterOrders(askOrders) {
+			order, _ = remainingAskOrder(order, positions)
 
-			upbitTool.CancelOrderAndAskMarketOrder(runner.client, order)
+			identifier := runner.idGenerator.Next(order.Market)
+			askOrder, err := runner.exchange.CancelOrderAndAskMarketOrder(identifier, order)
+			runner.recordOrder(identifier, askOrder, err)
 		}
 	}
 }
 
 /*
- * 실제 주문으로 HealthCheck
- * 안전하게 ETC 100원으로 주문
+ * 매도 주문을 다시 낼 때 쓸 주문 : 남은 수량을 모르면 봇 보유 수량을 남은 수량으로 넣는다.
+ * 부분 체결된 주문의 원래 수량으로 다시 내면 남은 것보다 많이 팔게 된다.
  */
-func (runner *LarryRunner) healthCheck(ordersMap map[string][]*types.Order) {
-
-	var etcOrder *types.Order = nil
-
-	bidOrders := ordersMap[types.ORDERSIDE_BID]
-
-	for _, order := range bidOrders {
-		if order.Market == healthCheckCoin {
-			etcOrder = order
-			break
-		}
+func remainingAskOrder(order *types.Order, positions map[string]float64) (*types.Order, money.Decimal) {
+	volume, known := exchange.RemainingVolume(order)
+	if known {
+		return order, volume
 	}
 
-	//매수 주문이 있다면 취소
-	if etcOrder != nil {
-		runner.client.CancelOrder(etcOrder.Uuid)
-	} else {
-		// 매수
-		bidOrder := types.OrderInfo{
-			Identifier: strconv.Itoa(int(upbitUtil.TimeStamp())),
-			Side:       types.ORDERSIDE_BID,
-			Market:     healthCheckCoin,
-			Price:      "100",
-			Volume:     "100",
-			OrdType:    types.ORDERTYPE_LIMIT}
-
-		order, err := runner.client.OrderByInfo(bidOrder)
-
-		if err != nil {
-			gLogger.Println("주문 에러 ")
-		} else {
-			if len(order.Uuid) > 0 {
-				gLogger.Println("헬스체크 매수 성공 ")
-			}
-		}
+	volume = money.NewFromFloat(positions[order.Market])
+	remaining := *order
+	remaining.RemainingVolume = volume.String()
 
-	}
+	return &remaining, volume
 }
 
 // 매도 전략
 func (runner *LarryRunner)runLarryAskStrategy(balances []*types.Balance,
+	positions map[string]float64,
 	ordersMap map[string][]*types.Order,
 	candleMap map[string][]*types.DayCandle) {
 
-	gLogger.Println("[매도 전략 수행중]")
+	runner.logger.Println("[매도 전략 수행중]")
 
-	logBalance(balances)
-	logWaitOrders(ordersMap[types.ORDERSIDE_ASK])
+	runner.logBalance(balances)
+	runner.logWaitOrders(ordersMap[types.ORDERSIDE_ASK])
 
 	//util.PrintBalance(balances)
 	//util.PrintOrdersMap(ordersMap)
@@ -156,77 +183,91 @@ func (runner *LarryRunner)runLarryAskStrategy(balances []*types.Balance,
 	// askCoins := getCanAskCoins(balances, ordersMap)
 
 	// 이 시간대 매수 오더는 전일 오더이므로 모두 취소시킨다.
-	bidOrders := ordersMap[types.ORDERSIDE_BID]
+	// 수동으로 낸 주문은 건드리지 않는다.
+	bidOrders := runner.own.FilterOrders(ordersMap[types.ORDERSIDE_BID])
 	if len(bidOrders) > 0 {
 		runner.cancelAllOrder(bidOrders)
 	}
 
-	// 매도 가능 잔고를 구함 ,
-	askCoins := upbitTool.GetBalanceCoinsCanAsk(balances)
+	// 매도 가능 잔고를 구함 , 봇이 매수한 수량만 매도한다. (수동 매수 코인 제외)
+	sellableVolumes := getSellableVolumes(positions, balances)
+	askCoins := make([]string, 0, len(sellableVolumes))
+	for market := range sellableVolumes {
+		askCoins = append(askCoins, market)
+	}
 
 	if len(askCoins) > 0 {
-		gLogger.Printf("매도 가능 잔고 : %v\n", askCoins)
+		runner.logger.Printf("매도 가능 잔고 : %v\n", askCoins)
 	} else {
-		gLogger.Println("매도 가능 잔고 없음")
+		runner.logger.Println("매도 가능 잔고 없음")
 	}
 
 	// 매도가능 잔고에서  Target으로 잡은 코인만 추출한다.
-	targetAskCoins := checkTargetCoins(gConfig, askCoins)
+	targetAskCoins := checkTargetCoins(runner.targets, askCoins)
 
 	if len(targetAskCoins) <= 0 {
-		gLogger.Println("매도 가능 타겟 잔고 없음")
+		runner.logger.Println("매도 가능 타겟 잔고 없음")
 	}
 
 	if len(targetAskCoins) > 0 {
 		// Candle 정보 및 현재가 정보를 가져온다.
-		gLogger.Printf("잔고 매도 전략 수행 %v\n", targetAskCoins)
+		runner.logger.Printf("잔고 매도 전략 수행 %v\n", targetAskCoins)
 
 		// candleMap := runner.getDayCandlesByCoins(targetAskCoins)
 
-		runner.askOrder(targetAskCoins, balances, candleMap)
+		runner.askOrder(targetAskCoins, sellableVolumes, candleMap)
 
 	} else if len(ordersMap[types.ORDERSIDE_ASK]) > 0 {
 
 		waitOrderCoins := upbitTool.GetCoinsFromOrders(ordersMap[types.ORDERSIDE_ASK])
-		waitTargetCoins := checkTargetCoins(gConfig, waitOrderCoins)
+		waitTargetCoins := checkTargetCoins(runner.targets, waitOrderCoins)
 
-		gLogger.Println("미체결 오더 확인 ")
+		runner.logger.Println("미체결 오더 확인 ")
 		if len(waitTargetCoins) > 0 {
 			// candleMap := runner.getDayCandlesByCoins(waitTargetCoins)
 
-			runner.forceAskOrder(gConfig, ordersMap[types.ORDERSIDE_ASK], waitTargetCoins, candleMap)
+			runner.forceAskOrder(runner.config, runner.own.FilterOrders(ordersMap[types.ORDERSIDE_ASK]), waitTargetCoins, candleMap, positions)
 		}
 
 	} else {
 
 	}
 
-	gLogger.Println("[매도 전략 수행 종료]")
+	runner.logger.Println("[매도 전략 수행 종료]")
 
 }
 
 // 매수 전략
 func (runner *LarryRunner) runLarryBidStrategy(balances []*types.Balance,
+	positions map[string]float64,
 	ordersMap map[string][]*types.Order,
 	candleMap map[string][]*types.DayCandle,
 	kMap map[string]float64,
 	malScoreMap  map[string]float64) {
 
-	gLogger.Println()
-	gLogger.Println("[매수 전략 수행중]")
+	runner.logger.Println()
+	runner.logger.Println("[매수 전략 수행중]")
 
-	logBalance(balances)
-	logWaitOrders(ordersMap[types.ORDERSIDE_BID])
+	runner.logBalance(balances)
+	runner.logWaitOrders(ordersMap[types.ORDERSIDE_BID])
 
 	// 잔고에 없고 WaitingOrder가 없는 코인으로 탐색 대상을 잡는다.
-	availableCoins := getAvailableCoins(gConfig, balances, ordersMap)
+	availableCoins := getAvailableCoins(runner.config, runner.targets, positions, runner.own.FilterOrdersMap(ordersMap))
 
 	// targetBidCoins := checkTargetCoins(config, availableCoins)
 
+	// 시장 국면 필터 : 약세면 매수를 막거나 주문 금액을 줄인다.
+	amountRate := 1.0
+	if regime := runner.getRegime(candleMap); regime != nil {
+		amountRate = regime.AmountRate
+	}
+
 	// 전략 수행
-	runner.doStrategy(balances, availableCoins, candleMap, ordersMap, kMap, malScoreMap)
+	if amountRate > 0 {
+		runner.doStrategy(balances, positions, availableCoins, candleMap, ordersMap, kMap, malScoreMap, amountRate)
+	}
 
-	gLogger.Println("[매수 전략 수행 종료] ")
+	runner.logger.Println("[매수 전략 수행 종료] ")
 }
 
 /*
@@ -235,16 +276,20 @@ func (runner *LarryRunner) runLarryBidStrategy(balances []*types.Balance,
 func (runner *LarryRunner) cancelAllOrder(orders []*types.Order) {
 
 	for _, value := range orders {
-		order, _ := runner.client.CancelOrder(value.Uuid)
-		gLogger.Printf("주문 취소 : %s, %s", order.Uuid, order.Side)
+		order, err := runner.exchange.CancelOrder(value.Uuid)
+		if err != nil {
+			runner.logger.Printf("주문 취소 실패 : %s, %s\n", value.Uuid, err.Error())
+			continue
+		}
+		runner.logger.Printf("주문 취소 : %s, %s\n", order.Uuid, order.Side)
 	}
 }
 
-func checkTargetCoins(config *model.Config, coins []string) (targetCoins []string) {
+func checkTargetCoins(targets []string, coins []string) (targetCoins []string) {
 	targetCoins = make([]string, 0)
 
 	for _, value := range coins {
-		for _, targetCoin := range config.LarryStrategy.Targets {
+		for _, targetCoin := range targets {
 			if value == targetCoin {
 				targetCoins = append(targetCoins, targetCoin)
 				continue
@@ -353,16 +398,10 @@ func getKNoiseValueByDay(candleMap map[string][]*types.DayCandle) (
  * 매도 가능한 코인을 얻어온다.
  * 잔고가 있으며, 현재 미체결 Order가 없는 경우
  */
-func getCanAskCoins(balances []*types.Balance, ordersMap map[string][]*types.Order) (coins []string) {
+func getCanAskCoins(targets []string, balances []*types.Balance, ordersMap map[string][]*types.Order) (coins []string) {
 	coins = make([]string, 0)
 
-	for _, value := range balances {
-		// 원하는 매도 가능 잔고가 아님.
-		if value.Currency == "KRW" {
-			continue
-		}
-		currency := upbitUtil.GetMarketFromCurrency(value.Currency, "KRW")
-
+	for currency := range quote.HoldingMap(balances, targets) {
 		askOrders := ordersMap[types.ORDERSIDE_ASK]
 
 		bFound := false
@@ -389,7 +428,7 @@ func (runner *LarryRunner) getBalanceAndWaitOrders()(
 	ordersMap map[string][]*types.Order,
 	err error ) {
 	// check balance
-	balances, err = runner.client.Accounts()
+	balances, err = runner.exchange.Accounts()
 
 	if err != nil {
 		log.Println(err)
@@ -397,7 +436,7 @@ func (runner *LarryRunner) getBalanceAndWaitOrders()(
 	}
 
 	// 미체결 Order 확인
-	ordersMap, err = runner.client.OrdersMap("", types.ORDERSTATE_WAIT, 1, types.ORDERBY_DESC)
+	ordersMap, err = runner.exchange.OrdersMap("", types.ORDERSTATE_WAIT, 1, types.ORDERBY_DESC)
 	if err != nil {
 		log.Println(err)
 		return
@@ -407,21 +446,116 @@ func (runner *LarryRunner) getBalanceAndWaitOrders()(
 }
 
 /*
- * 주문 가능 원화 잔고를 가져온다.
+ * 유니버스에서 고른 타겟에 봇 보유 코인, 봇 미체결 주문 코인을 더한다. (청산을 위해 유지)
  */
-func getAvailableKrwBalance(balances []*types.Balance) (krwBalance float64) {
-	for _, value := range balances {
-		if value.Currency == "KRW" {
-			f, err := strconv.ParseFloat(value.Balance, 64)
+func (runner *LarryRunner) getUniverseTargets(positions map[string]float64,
+	ordersMap map[string][]*types.Order) (targets []string) {
 
-			if err != nil {
-				krwBalance = 0.0
-			} else {
-				krwBalance = f
-				return
-			}
+	targets = append([]string{}, runner.universe.Targets(runner.staticTargets)...)
+
+	keep := make([]string, 0)
+	for market := range positions {
+		keep = append(keep, market)
+	}
+	for _, orders := range runner.own.FilterOrdersMap(ordersMap) {
+		keep = append(keep, upbitTool.GetCoinsFromOrders(orders)...)
+	}
+
+	for _, market := range keep {
+		if !upbitTool.IsExist(market, targets) {
+			targets = append(targets, market)
+		}
+	}
+
+	return
+}
+
+/*
+ * 이 전략이 보유한 코인 수량 (봇 주문 체결량 + 대사 편입 수량, 제외 코인은 빼고)
+ */
+func (runner *LarryRunner) getOwnPositions() (positions map[string]float64) {
+	return runner.withoutExcluded(runner.own.Positions())
+}
+
+/*
+ * 계정의 봇 보유 코인 수량 (다른 전략 포함, 포트폴리오 변동성과 상관계수 계산에 사용)
+ */
+func (runner *LarryRunner) getBotPositions() (positions map[string]float64) {
+	return runner.withoutExcluded(runner.store.Positions())
+}
+
+func (runner *LarryRunner) withoutExcluded(botPositions map[string]float64) (positions map[string]float64) {
+	positions = make(map[string]float64)
+
+	for market, volume := range botPositions {
+		if volume <= 0 || isExcluded(runner.config, market) {
+			continue
 		}
+		positions[market] = volume
 	}
+
+	return
+}
+
+/*
+ * 봇 보유 코인의 매도 가능 수량 : 봇 수량과 거래소 주문 가능 잔고 중 작은 값
+ */
+func getSellableVolumes(positions map[string]float64, balances []*types.Balance) (volumes map[string]float64) {
+	volumes = make(map[string]float64)
+
+	for market, volume := range positions {
+		sellable := math.Min(volume, quote.Available(balances, quote.Base(market)))
+		if sellable > 0 {
+			volumes[market] = sellable
+		}
+	}
+
+	return
+}
+
+func isExcluded(config *model.Config, market string) bool {
+	return upbitTool.IsExist(quote.Base(market), config.LarryStrategy.ExcludedCurrencies)
+}
+
+/*
+ * 기준 통화별 최대 주문 금액을 구한다.
+ * order_amounts 에 없는 기준 통화는 원화 최대 주문 금액을 현재 시세로 환산한다.
+ * 평가 금액 기준 주문 금액(sizing)이면 order_amounts 를 쓰지 않고 모든 기준 통화를 평가 금액 기준으로 환산한다.
+ */
+func (runner *LarryRunner) getMaxOrderAmounts(quoteCurrencies []string,
+	candleMap map[string][]*types.DayCandle,
+	equity float64) (amountMap map[string]float64) {
+
+	strategy := runner.config.LarryStrategy
+	amountMap = make(map[string]float64)
+
+	baseAmount := BaseOrderAmount(&strategy, equity)
+	if IsEquitySizing(strategy.Sizing) {
+		runner.logger.Printf("[주문 금액] %s : 평가 금액 %.0f, 최대 주문 금액 %.0f\n", strategy.Sizing.Mode, equity, baseAmount)
+	}
+
+	convertCurrencies := make([]string, 0)
+	for _, currency := range quoteCurrencies {
+		if amount, exist := strategy.OrderAmounts[currency]; exist && !IsEquitySizing(strategy.Sizing) {
+			amountMap[currency] = amount
+		} else if currency == quote.KRW {
+			amountMap[currency] = baseAmount
+		} else {
+			convertCurrencies = append(convertCurrencies, currency)
+		}
+	}
+
+	if len(convertCurrencies) == 0 {
+		return
+	}
+
+	rates := quote.Rates(runner.exchange, convertCurrencies, candleMap)
+	for _, currency := range convertCurrencies {
+		if rate, exist := rates[currency]; exist && rate > 0 {
+			amountMap[currency] = baseAmount / rate
+		}
+	}
+
 	return
 }
 
@@ -429,122 +563,216 @@ func getAvailableKrwBalance(balances []*types.Balance) (krwBalance float64) {
  * 전략 실행
  * param
  * balances : 잔고 목록
+ * positions : 봇 보유 수량
  * availableCoins : 매매 가능 코인
  * candleMap : 코인별 일봉 캔들 목록
  * orderMap : 현재 걸려있는 미체결 주문 목록
  * kMap : 코인별 K-Value
  * malScoreMap : 코인별 이동평균선 스코어
+ * amountRate : 시장 국면 필터가 정한 주문 금액 비율
  */
 func (runner *LarryRunner) doStrategy(
 	balances []*types.Balance,
+	positions map[string]float64,
 	availableCoins []string,
 	candleMap map[string][]*types.DayCandle,
 	orderMap map[string][]*types.Order,
 	kMap map[string]float64,
-	malScoreMap map[string]float64) (err error) {
+	malScoreMap map[string]float64,
+	amountRate float64) (err error) {
 
-	// 주문 가능 잔고 체크
-	availableKrwBalance := getAvailableKrwBalance(balances)
+	// 기준 통화별 주문 가능 잔고 체크
+	quoteCurrencies := quote.Currencies(availableCoins)
 
-	gLogger.Printf("주문 가능 잔고 : %f\n", availableKrwBalance )
+	for _, currency := range quoteCurrencies {
+		runner.logger.Printf("주문 가능 잔고 : %f %s\n", quote.Available(balances, currency), currency)
+	}
 
-	if availableKrwBalance < float64(gConfig.LarryStrategy.OrderAmount) {
-		gLogger.Printf("주문 가능 잔고가 최소 주문 금액보다 적음 : %f\n", availableKrwBalance)
+	// 봇이 보유한 코인만 센다. (수동 보유 코인, 기준 통화 제외)
+	holdingCount := len(positions)
+	if holdingCount >= runner.config.LarryStrategy.MaxCoin {
+		runner.logger.Printf("기존 보유 코인이 설정값 초과 : 보유코인 %d, 설정값 %d\n",
+			holdingCount, runner.config.LarryStrategy.MaxCoin)
 		return
 	}
 
-	if len(balances) - 1 >= gConfig.LarryStrategy.MaxCoin {
-		gLogger.Printf("기존 보유 코인이 설정값 초과 : 보유코인 %d, 설정값 %d\n",
-			len(balances) - 1, gConfig.LarryStrategy.MaxCoin)
+	// 평가 금액 : 자금 배분, 평가 금액 기준 주문 금액, 포트폴리오 변동성에 사용한다. (모두 쓰지 않으면 구하지 않음)
+	equity := 0.0
+	if runner.allocator != nil || IsEquitySizing(runner.config.LarryStrategy.Sizing) ||
+		runner.config.LarryStrategy.PortfolioRisk.Enable == 1 {
+		equity = runner.getEquity(balances, candleMap)
+	}
+	rates := quote.Rates(runner.exchange, quote.Currencies(runner.targets), candleMap)
+
+	// 전략 예산 : 다른 전략이 쓰는 자금을 빼고 이 전략의 몫 안에서만 주문한다.
+	allocation := runner.getAllocation(equity, rates)
+	if allocation != nil && allocation.Available <= 0 {
+		runner.logger.Printf("[자금 배분] %s 남은 예산 없음 : 매수 안함\n", strategyName)
 		return
 	}
 
+	// 자금 배분을 쓰면 전략 예산을 이 전략의 평가 금액으로 본다.
+	sizingEquity := equity
+	if allocation != nil {
+		sizingEquity = allocation.Budget
+	}
+	maxOrderAmountMap := runner.getMaxOrderAmounts(quoteCurrencies, candleMap, sizingEquity)
+
+	// 보유 코인 + 이번 주기 매수 주문으로 포트폴리오 변동성을 추정한다.
+	botPositions := runner.getBotPositions()
+	portfolio := runner.getPortfolio(equity, botPositions, orderMap, candleMap, rates)
+
+	// 보유 코인 + 이번 주기 매수 주문과 상관계수가 높은 코인은 매수를 막거나 줄인다.
+	correlation := runner.getCorrelationFilter(botPositions, orderMap, candleMap)
+
 	// 잔고에 없는 코인을 기준으로 탐색
 	for _, coinName := range availableCoins {
 		//candleInfo := candleMap[value]
 
+		quoteCurrency := quote.Of(coinName)
+		maxOrderAmount, exist := maxOrderAmountMap[quoteCurrency]
+		if !exist {
+			runner.logger.Printf("%s : 기준 통화 %s 의 주문 금액을 정할 수 없음\n", coinName, quoteCurrency)
+			continue
+		}
+
+		availableBalance := quote.Available(balances, quoteCurrency)
+		if availableBalance < RequiredBalance(&runner.config.LarryStrategy, maxOrderAmount) {
+			runner.logger.Printf("%s : 주문 가능 잔고가 최소 주문 금액보다 적음 : %f %s\n",
+				coinName, availableBalance, quoteCurrency)
+			continue
+		}
+
 		if candleInfo, exist := candleMap[coinName]; exist {
-			if len(candleInfo) < 2 {	// 최소한 봉이 2개 이상 있어야 판단 가능.
+			// 최소한 봉이 2개 이상 있어야 판단 가능.
+			signal := evaluateSignal(&runner.config.LarryStrategy, coinName, candleInfo, kMap, malScoreMap)
+			if signal == nil {
 				continue
 			}
 
-			// 고가 - 저가 = 범위값
-			rangeValue := candleInfo[1].HighPrice - candleInfo[1].LowPrice
-			kValue := gConfig.LarryStrategy.KValue
+			runner.logger.Printf("==== 전략 수행 코인 : %s ====\n", coinName)
+			runner.logger.Printf("전일 고가 : %f, 전일 저가 : %f , Range : %f, Range-K value : %f\n",
+				candleInfo[1].HighPrice, candleInfo[1].LowPrice, signal.Range, signal.KValue)
 
-			if coinKValue, valueExist := kMap[coinName]; valueExist {
-				kValue = coinKValue
+			// 변동성 조건에 해당함.
+			bidValue := signal.TriggerPrice
+
+			runner.logger.Printf("당일 시가 %f\n", signal.OpeningPrice)
+			runner.logger.Printf("이동평균 Score %f, 변동성 적용 가격 %f\n", signal.MalScore, signal.Range*signal.KValue)
+			runner.logger.Printf("매수 조건 가격 %f\n", bidValue)
+			runner.logger.Printf("현재 가격 %f\n", signal.CurrentPrice)
+
+			// 자금관리 비율 계산을 위해 값을 구한다. (기준 통화 단위)
+			orderAmount := 0.0
+			if len(candleInfo) >= minCandleCountForAmount {
+				orderAmount = scaleOrderAmount(maxOrderAmount,
+					runner.config.LarryStrategy.MinOrderAmountRate,
+					signal.MalScore,
+					signal.MoneyPlanRate) * amountRate
 			}
 
-			kAppliedValue := rangeValue * kValue
+			runner.logger.Printf("평균 변동성 : %.2f, 자금관리 비율 : %.2f, 이평스코어 : %.2f\n",
+				signal.AverageVariability,
+				signal.MoneyPlanRate,
+				signal.MalScore)
 
-			gLogger.Printf("==== 전략 수행 코인 : %s ====\n", coinName)
-			gLogger.Printf("전일 고가 : %f, 전일 저가 : %f , Range : %f, Range-K value : %f\n",
-				candleInfo[1].HighPrice, candleInfo[1].LowPrice, rangeValue, kValue)
+			runner.logger.Printf("최대주문 금액 : %f %s, 주문요청 금액 : %f %s",
+				maxOrderAmount, quoteCurrency,
+				orderAmount, quoteCurrency)
 
-			// 변동성 조건에 해당함.
-			bidValue := candleInfo[0].OpeningPrice + kAppliedValue
+			if amountRate < 1 {
+				runner.logger.Printf("시장 국면 약세로 주문 금액 %.0f%% 적용\n", amountRate*100)
+			}
 
-			gLogger.Printf("당일 시가 %f\n", candleInfo[0].OpeningPrice)
-			gLogger.Printf("이동평균 Score %f, 변동성 적용 가격 %f\n", malScoreMap[coinName], kAppliedValue)
-			gLogger.Printf("매수 조건 가격 %f\n", bidValue)
-			gLogger.Printf("현재 가격 %f\n", candleInfo[0].TradePrice)
+			if signal.Triggered {
+				// 매수 주문 실행.
+				// orderAmount := runner.config.LarryStrategy.OrderAmount * malScoreMap[coinName]
+				// volumeStr := fmt.Sprintf("%.8f", runner.config.LarryStrategy.OrderAmount/bidValue)
 
+				if orderAmount <= 0 {
+					runner.logger.Printf("**** 매수 신호 발생  , 매수 주문 Coin : %s , Price : %f, Volume : 0.0\n",
+						coinName, bidValue)
+					continue
+				}
 
-			// 자금관리 비율 계산을 위해 값을 구한다.
-			orderAmount, _ := getOrderAmount(gConfig.LarryStrategy.OrderAmount,
-				gConfig.LarryStrategy.MinOrderAmountRate,
-				gConfig.LarryStrategy.MoneyPlan,
-				candleInfo,
-				malScoreMap, coinName)
+				if correlation != nil {
+					if orderAmount = runner.limitCorrelation(correlation, coinName, orderAmount); orderAmount <= 0 {
+						continue
+					}
+				}
 
-			gLogger.Printf("최대주문 금액 : %f, 주문요청 금액 : %f",
-				gConfig.LarryStrategy.OrderAmount,
-				orderAmount)
+				bidAmount := math.Min(orderAmount, availableBalance)
 
-			if candleInfo[0].TradePrice >= bidValue {
-				// 매수 주문 실행.
-				// priceStr :=  fmt.Sprintf("%.8f", bidValue)
-				priceStr := upbitTool.GetPriceCanOrder(bidValue)
+				// 주문 금액을 원화로 바꿔 포트폴리오 변동성, 전략의 남은 예산과 비교한다.
+				bidAmountKRW, converted := quote.ToKRW(bidAmount, quoteCurrency, rates)
+				if (allocation != nil || portfolio != nil) && !converted {
+					runner.logger.Printf("%s : 기준 통화 %s 의 원화 환산 비율이 없어 주문 안함\n", coinName, quoteCurrency)
+					continue
+				}
 
-				// orderAmount := gConfig.LarryStrategy.OrderAmount * malScoreMap[coinName]
-				// volumeStr := fmt.Sprintf("%.8f", gConfig.LarryStrategy.OrderAmount/bidValue)
+				if portfolio != nil {
+					limitedKRW := runner.limitPortfolioRisk(portfolio, coinName, bidAmountKRW)
+					if limitedKRW <= 0 {
+						runner.logger.Printf("**** 매수 신호 발생, 주문 거절 : 포트폴리오 변동성이 목표 이상\n")
+						continue
+					}
+					if limitedKRW < bidAmountKRW {
+						bidAmount *= limitedKRW / bidAmountKRW
+						bidAmountKRW = limitedKRW
+					}
+				}
 
-				if orderAmount <= 0 {
-					gLogger.Printf("**** 매수 신호 발생  , 매수 주문 Coin : %s , Price : %s, Volume : 0.0\n",
-						coinName, priceStr)
-					continue
+				if allocation != nil {
+					if allocationErr := allocation.Check(bidAmountKRW); allocationErr != nil {
+						runner.logger.Printf("**** 매수 신호 발생, 주문 거절 : %s\n", allocationErr.Error())
+						continue
+					}
 				}
 
-				volumeStr := fmt.Sprintf("%.8f", orderAmount/bidValue)
+				// 호가 단위로 내린 가격 기준으로 수수료를 포함해 주문 금액(주문 가능 잔고 이내)을 넘지 않는 수량을 구한다.
+				orderRules := rules.For(runner.exchange.Name(), coinName)
+				bidPrice := orderRules.Price(types.ORDERSIDE_BID, money.NewFromFloat(bidValue))
+				bidVolume := runner.fees.For(coinName).BidVolume(money.NewFromFloat(bidAmount), bidPrice)
 
-				gLogger.Printf("**** 매수 신호 발생  , 매수 주문 Coin : %s , Price : %s, Volume : %s\n",
-					coinName, priceStr, volumeStr)
+				bidOrder, err := runner.limitOrder(orderRules, types.ORDERSIDE_BID, bidPrice, bidVolume)
+				if err != nil {
+					runner.logger.Printf("**** 매수 신호 발생, 주문 불가 : %s\n", err.Error())
+					continue
+				}
 
-				bidOrder := types.OrderInfo{
-					Identifier: strconv.Itoa(int(upbitUtil.TimeStamp())),
-					Side:       types.ORDERSIDE_BID,
-					Market:     coinName,
-					Price:      priceStr,
-					Volume:     volumeStr,
-					OrdType:    types.ORDERTYPE_LIMIT}
+				runner.logger.Printf("**** 매수 신호 발생  , 매수 주문 Coin : %s , Price : %s, Volume : %s\n",
+					coinName, bidOrder.Price, bidOrder.Volume)
 
-				order, err := runner.client.OrderByInfo(bidOrder)
+				order, err := runner.placeOrder(bidOrder)
 
 				if err != nil {
-					gLogger.Println("주문 에러 ")
+					runner.logger.Println("주문 에러 ")
 				} else {
 					if len(order.Uuid) > 0 {
-						gLogger.Println("매수 성공 ")
-						gLogger.Printf("코인 %s, 주문가격 : %s, 주문수량 :%s", order.Market, order.Price, order.Volume)
+						runner.logger.Println("매수 성공 ")
+						runner.logger.Printf("코인 %s, 주문가격 : %s, 주문수량 :%s", order.Market, order.Price, order.Volume)
+
+						if storeErr := runner.store.SetSignal(order.Uuid, signal.KValue, signal.MalScore); storeErr != nil {
+							runner.logger.Printf("신호 정보 기록 실패 : %s\n", storeErr.Error())
+						}
+
+						if allocation != nil {
+							allocation.Use(bidAmountKRW)
+						}
+						if portfolio != nil {
+							portfolio.Add(coinName, bidAmountKRW)
+						}
+						if correlation != nil {
+							correlation.add(coinName)
+						}
 					}
 				}
 			} else {
-				gLogger.Printf("==== 매수 신호 대기 ====\n")
+				runner.logger.Printf("==== 매수 신호 대기 ====\n")
 			}
 		}
 
-		gLogger.Printf("\n")
+		runner.logger.Printf("\n")
 	}
 
 	return
@@ -557,14 +785,14 @@ func getOrderAmount(maxOrderAmount float64,
 	malScoreMap map[string]float64,
 	coinName string) (
 	orderAmount float64,
-	moneyPlanRate float64) {
+	moneyPlanRate float64,
+	avrVar float64) {
 
 	const MAX_INDEX = 3
 
 	orderAmount = 0.0
 	moneyPlanRate = 0.0
-
-	minOrderAmount := maxOrderAmount * (minOrderAmountRate/100.0)
+	avrVar = 0.0
 
 	if malScore, exist := malScoreMap[coinName]; exist {
 
@@ -581,44 +809,78 @@ func getOrderAmount(maxOrderAmount float64,
 		}
 
 		// prevVar := (candles[1].HighPrice - candles[1].LowPrice)/candles[0].TradePrice
-		avrVar := float64( avrVarSum / MAX_INDEX)
+		avrVar = float64( avrVarSum / MAX_INDEX)
 
 		moneyPlanRate = (moneyPlan/100)/avrVar
 
-		orderAmount = maxOrderAmount * malScore * moneyPlanRate
+		orderAmount = scaleOrderAmount(maxOrderAmount, minOrderAmountRate, malScore, moneyPlanRate)
+	}
 
-		gLogger.Printf("평균 변동성 : %.2f, 자금관리 비율 : %.2f, 이평스코어 : %.2f\n",
-			avrVar,
-			moneyPlanRate,
-			malScore)
+	return
+}
 
-		if orderAmount > maxOrderAmount {
-			orderAmount = maxOrderAmount
-		}
+/*
+ * 최대 주문 금액에 이평 스코어와 자금관리 비율을 적용한다.
+ * 최소 주문 금액(최대 주문 금액의 minOrderAmountRate %) ~ 최대 주문 금액 사이로 제한한다.
+ */
+func scaleOrderAmount(maxOrderAmount float64,
+	minOrderAmountRate float64,
+	malScore float64,
+	moneyPlanRate float64) (orderAmount float64) {
 
-		if orderAmount < minOrderAmount {
-			orderAmount = minOrderAmount
-		}
+	minOrderAmount := maxOrderAmount * (minOrderAmountRate/100.0)
+
+	orderAmount = maxOrderAmount * malScore * moneyPlanRate
+
+	if orderAmount > maxOrderAmount {
+		orderAmount = maxOrderAmount
+	}
+
+	if orderAmount < minOrderAmount {
+		orderAmount = minOrderAmount
 	}
 
 	return
 }
+
+/*
+ * 거래소 주문 규칙에 맞춘 지정가 주문을 만든다.
+ * 규칙에 맞지 않으면(최소 주문 금액 미만 등) Identifier 를 발급하지 않고 에러를 돌려준다.
+ */
+func (runner *LarryRunner) limitOrder(orderRules *rules.Rules,
+	side string,
+	price money.Decimal,
+	volume money.Decimal) (orderInfo types.OrderInfo, err error) {
+
+	orderInfo, err = orderRules.LimitOrder(side, price, volume)
+	if err != nil {
+		return
+	}
+
+	orderInfo.Identifier = runner.idGenerator.Next(orderRules.Market)
+
+	return
+}
 /*
  * 전략 수행에 적용할 코인 목록 가져오기
- * 이미 잔고에 없고, 미체결 주문이 들어가지 않은 코인을 가져온다.
+ * 봇 보유 수량이 없고, 봇의 미체결 매수 주문이 없는 코인을 가져온다. (제외 코인은 빼고)
  */
 func getAvailableCoins(config *model.Config,
-	balances []*types.Balance,
+	targets []string,
+	positions map[string]float64,
 	orderMap map[string][]*types.Order) (
 	checkCoins []string ) {
 
-	balanceMap := upbitTool.GetBalanceMap(balances)
 	checkCoins = make([]string, 0)
 
-	for i := 0; i < len(config.LarryStrategy.Targets); i++ {
-		coin := config.LarryStrategy.Targets[i]
+	for i := 0; i < len(targets); i++ {
+		coin := targets[i]
+
+		if isExcluded(config, coin) {
+			continue
+		}
 
-		if _, exist := balanceMap[coin]; !exist {
+		if _, exist := positions[coin]; !exist {
 			if _, exist := upbitTool.ExistOrder(coin, orderMap, types.ORDERSIDE_BID); !exist {
 				checkCoins = append(checkCoins, coin)
 			}
@@ -628,35 +890,32 @@ func getAvailableCoins(config *model.Config,
 }
 
 /*
- * coins 의 코인들이 balances 에 있으면 현재가로 매도 수행
+ * coins 의 코인들을 봇 매도 가능 수량만큼 현재가로 매도 수행
  */
 func (runner *LarryRunner) askOrder(coins []string,
-	balances []*types.Balance,
+	sellableVolumes map[string]float64,
 	candleMap map[string][]*types.DayCandle) {
 
-	balanceMap := upbitTool.GetBalanceMap(balances)
-
 	for _, value := range coins {
-		priceStr :=  fmt.Sprintf("%.8f", candleMap[value][0].TradePrice)
-		volumeStr :=  balanceMap[value].Balance
+		askOrder, err := runner.limitOrder(rules.For(runner.exchange.Name(), value),
+			types.ORDERSIDE_ASK,
+			money.NewFromFloat(candleMap[value][0].TradePrice),
+			money.NewFromFloat(sellableVolumes[value]))
 
-		gLogger.Printf("매도 주문 수행 %s : 가격 , %s , 수량 , %s", value, priceStr, volumeStr)
+		if err != nil {
+			runner.logger.Printf("매도 주문 불가 : %s\n", err.Error())
+			continue
+		}
 
-		askOrder := types.OrderInfo{
-			Identifier: strconv.Itoa(int(upbitUtil.TimeStamp())),
-			Side:       types.ORDERSIDE_ASK,
-			Market:     value,
-			Price:      priceStr,
-			Volume:     volumeStr,
-			OrdType:    types.ORDERTYPE_LIMIT}
+		runner.logger.Printf("매도 주문 수행 %s : 가격 , %s , 수량 , %s", value, askOrder.Price, askOrder.Volume)
 
-		_, err := runner.client.OrderByInfo(askOrder)
+		_, err = runner.placeOrder(askOrder)
 
 		if err != nil {
 			// fmt.Println("주문 에러")
-			gLogger.Println("주문 에러")
+			runner.logger.Println("주문 에러")
 		} else {
-			gLogger.Println("주문 성공")
+			runner.logger.Println("주문 성공")
 		}
 	}
 }
@@ -665,9 +924,9 @@ func (runner *LarryRunner) askOrder(coins []string,
 /*
  * 매도 미체결이 계속 남아있으면 가격을 낮춰서라도 강제 매도
  */
-func (runner *LarryRunner) forceAskOrder(config *model.Config, orders[]*types.Order, targetCoins []string, candleMap map[string][]*types.DayCandle) {
+func (runner *LarryRunner) forceAskOrder(config *model.Config, orders[]*types.Order, targetCoins []string, candleMap map[string][]*types.DayCandle, positions map[string]float64) {
 
-	gLogger.Println("매도 가격 조정 수행")
+	runner.logger.Println("매도 가격 조정 수행")
 
 	for _, value := range orders {
 
@@ -679,46 +938,111 @@ func (runner *LarryRunner) forceAskOrder(config *model.Config, orders[]*types.Or
 			elapsedSeconds := int(timeGap.Seconds())
 
 			if elapsedSeconds > config.LarryStrategy.AskOrderGap  {
+				// 부분 체결된 주문은 남은 수량만 다시 낸다.
+				// 취소 후 다시 낼 수 없는 주문(최소 주문 금액 미만 등)은 취소하지 않는다.
+				_, remaining := remainingAskOrder(value, positions)
+				askOrder, orderErr := runner.limitOrder(rules.For(runner.exchange.Name(), value.Market),
+					types.ORDERSIDE_ASK,
+					money.NewFromFloat(candleMap[value.Market][0].TradePrice),
+					remaining)
+
+				if orderErr != nil {
+					runner.logger.Printf("매도 가격 조정 불가 : %s\n", orderErr.Error())
+					continue
+				}
+
 				//	client.CancelOrder(value.Uuid)
 				fmt.Println("주문 진행 시간 : " + strconv.Itoa(elapsedSeconds) + ", 주문취소 : " + value.Uuid)
-				gLogger.Println(value.Market + ", 주문 진행 시간 : " + strconv.Itoa(elapsedSeconds) + ", 주문취소 : " + value.Uuid)
+				runner.logger.Println(value.Market + ", 주문 진행 시간 : " + strconv.Itoa(elapsedSeconds) + ", 주문취소 : " + value.Uuid)
 
-				_, err := runner.client.CancelOrder(value.Uuid)
+				_, err := runner.exchange.CancelOrder(value.Uuid)
 
 				if err != nil {
-					gLogger.Printf("주문 취소 실패 : %s\n" + err.Error())
+					runner.logger.Printf("주문 취소 실패 : %s\n" + err.Error())
 				} else {
 
-					gLogger.Println("매도 주문 실행 ")
-					gLogger.Printf("코인 : %s, 주문수량 : %s, 주문가격 : %f\n", value.Market, value.Volume, candleMap[value.Market][0].TradePrice)
-					upbitTool.AskOrder(runner.client, value.Market, value.Volume, candleMap[value.Market][0],  types.ORDERTYPE_LIMIT)
+					runner.logger.Println("매도 주문 실행 ")
+					runner.logger.Printf("코인 : %s, 주문수량 : %s, 주문가격 : %s\n", value.Market, askOrder.Volume, askOrder.Price)
+
+					runner.placeOrder(askOrder)
 				}
 			}
 		}
 	}
 }
 
+/*
+ * 주문 실행
+ * 일시적인 오류면 같은 Identifier 로 재시도하고, 중복으로 거절되면 Identifier 로 접수된 주문을 찾는다.
+ * 성공한 주문은 상태 파일에 기록한다.
+ */
+func (runner *LarryRunner) placeOrder(orderInfo types.OrderInfo) (order *types.Order, err error) {
+	for attempt := 1; attempt <= orderRetryCount; attempt++ {
+		order, err = runner.exchange.OrderByInfo(orderInfo)
+		if err == nil {
+			break
+		}
+
+		runner.logger.Printf("주문 실패 (%d/%d) : %s, %s\n", attempt, orderRetryCount, orderInfo.Identifier, err.Error())
+
+		// 이전 시도가 응답만 받지 못하고 들어간 주문이면 Identifier 로 찾아 기록한다.
+		if exchange.IsDuplicateIdentifier(err) {
+			if order, err = exchange.OrderByIdentifier(runner.exchange, orderInfo.Identifier); err == nil {
+				runner.logger.Printf("이미 접수된 주문 : %s, %s\n", orderInfo.Identifier, order.Uuid)
+			}
+			break
+		}
+
+		// 잔고 부족 등 요청 자체가 거절된 경우는 다시 보내지 않는다.
+		if !exchange.IsRetryable(err) {
+			break
+		}
+
+		if attempt < orderRetryCount {
+			time.Sleep(orderRetryDelay)
+		}
+	}
+
+	runner.recordOrder(orderInfo.Identifier, order, err)
+
+	return
+}
+
+/*
+ * 성공한 주문을 상태 파일에 기록한다.
+ */
+func (runner *LarryRunner) recordOrder(id string, order *types.Order, err error) {
+	if err != nil {
+		runner.logger.Printf("주문 에러 : %s, %s\n", id, err.Error())
+		return
+	}
+
+	if storeErr := runner.store.RecordOrder(id, order); storeErr != nil {
+		runner.logger.Printf("주문 기록 실패 : %s, %s\n", id, storeErr.Error())
+	}
+}
+
 /*
  * 밸런스 로깅
  */
-func logBalance(balances []*types.Balance) {
-	gLogger.Println("[잔고 현황] ")
+func (runner *LarryRunner) logBalance(balances []*types.Balance) {
+	runner.logger.Println("[잔고 현황] ")
 	for _, value := range balances {
-		gLogger.Printf("코인 : %s, 잔고 : %s\n", value.Currency, value.Balance)
+		runner.logger.Printf("코인 : %s, 잔고 : %s\n", value.Currency, value.Balance)
 	}
 }
 
 /*
  * 미체결 오더 잔고 로깅
  */
-func logWaitOrders(orders []*types.Order) {
-	gLogger.Println("[미체결 주문 현황] ")
+func (runner *LarryRunner) logWaitOrders(orders []*types.Order) {
+	runner.logger.Println("[미체결 주문 현황] ")
 	if len(orders) >  0 {
 		for _, value := range orders {
-			gLogger.Printf("코인 : %s, 주문량 %s, 주문가격 %s\n", value.Market, value.Volume, value.Price)
+			runner.logger.Printf("코인 : %s, 주문량 %s, 주문가격 %s\n", value.Market, value.Volume, value.Price)
 		}
 	} else {
-		gLogger.Println("미체결 주문 없음")
+		runner.logger.Println("미체결 주문 없음")
 	}
 }
 
@@ -735,26 +1059,36 @@ func (runner *LarryRunner) processStop(stopLossRate float64,
 		return
 	}
 
-	for _, balance := range balances {
-		if balance.Currency == "KRW" {
+	balanceMap := quote.HoldingMap(balances, runner.targets)
+
+	// 봇이 보유한 수량만 손절한다.
+	for coinStr, volume := range getSellableVolumes(runner.getOwnPositions(), balances) {
+		balance, exist := balanceMap[coinStr]
+		if !exist {
 			continue
 		}
 
-		coinStr := upbitUtil.GetMarketFromCurrency(balance.Currency, "KRW")
-
 		currentPrice := upbitTool.GetCurrentPriceFromDayCandle(candleMap[coinStr])
 
-		profitRate := upbitTool.GetProfitRate(balance, currentPrice)
+		// 수수료와 시장가 매도 슬리피지를 반영한 수익률
+		avgBuyPrice, _ := strconv.ParseFloat(balance.AvgBuyPrice, 64)
+		// 평균 매수가를 알려주지 않는 거래소는 봇 주문 체결로 구한다.
+		if len(balance.AvgBuyPrice) == 0 {
+			avgBuyPrice = runner.store.AvgPrice(coinStr)
+		}
+		profitRate := runner.fees.For(coinStr).ProfitRate(avgBuyPrice, currentPrice)
 
 		//profitRate = -10
 
 		if stopLossRate > profitRate {
-			if order, exist := upbitTool.ExistOrder(coinStr, ordersMap, types.ORDERSIDE_ASK); exist {
-				runner.client.CancelOrder(order.Uuid)
+			if order, exist := upbitTool.ExistOrder(coinStr, runner.own.FilterOrdersMap(ordersMap), types.ORDERSIDE_ASK); exist {
+				runner.exchange.CancelOrder(order.Uuid)
 			}
 
 			// Ask Order
-			upbitTool.AskMarketOrder(runner.client, coinStr, balance.Balance)
+			identifier := runner.idGenerator.Next(coinStr)
+			askOrder, err := runner.exchange.AskMarketOrder(identifier, coinStr, money.NewFromFloat(volume).String())
+			runner.recordOrder(identifier, askOrder, err)
 
 
 		}
@@ -0,0 +1,41 @@
+package lw_basic
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"raindrop/main/money"
+	"testing"
+)
+
+func TestRemainingAskOrder(t *testing.T) {
+	positions := map[string]float64{"KRW-XRP": 6}
+
+	tests := []struct {
+		name   string
+		order  *types.Order
+		volume string
+	}{
+		{"unfilled", &types.Order{Market: "KRW-XRP", Volume: "10", RemainingVolume: "10"}, "10"},
+		// 부분 체결된 주문은 원래 수량이 아니라 남은 수량
+		{"partially filled", &types.Order{Market: "KRW-XRP", Volume: "10", RemainingVolume: "4"}, "4"},
+		{"executed only", &types.Order{Market: "KRW-XRP", Volume: "10", ExecutedVolume: "7"}, "3"},
+		// 남은 수량을 모르면 봇 보유 수량
+		{"unknown remaining", &types.Order{Market: "KRW-XRP", Volume: "10"}, "6"},
+		{"unknown position", &types.Order{Market: "KRW-ETH", Volume: "10"}, "0"},
+	}
+
+	for _, test := range tests {
+		original := *test.order
+		order, volume := remainingAskOrder(test.order, positions)
+		if volume.Cmp(money.MustParse(test.volume)) != 0 {
+			t.Errorf("%s : volume = %s, want %s", test.name, volume, test.volume)
+		}
+
+		// 거래소에 넘기는 주문도 같은 남은 수량을 가진다.
+		if remaining := money.MustParse(order.RemainingVolume); len(order.RemainingVolume) > 0 && remaining.Cmp(volume) != 0 {
+			t.Errorf("%s : order remaining = %s, want %s", test.name, order.RemainingVolume, volume)
+		}
+		if *test.order != original {
+			t.Errorf("%s : 원래 주문이 바뀜 %+v", test.name, test.order)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package lw_basic
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"math"
+	"raindrop/main/exchange"
+	"raindrop/main/quote"
+	"raindrop/main/risk"
+	"strconv"
+)
+
+/*
+ * 포트폴리오 변동성 목표
+ * 코인별 자금관리 비율(money_plan)은 그 코인의 변동폭만 보므로, 비슷하게 움직이는 코인을 여러 개 보유하면
+ * 계정 전체의 변동성이 커진다. 보유 코인과 매수 후보의 일간 수익률 공분산으로 매수 후 포트폴리오 변동성을 추정해
+ * 목표(target_volatility %)를 넘지 않도록 신규 주문 금액을 줄인다.
+ */
+
+const (
+	// 전략이 조회하는 일봉 20개(당일 포함)로 계산할 수 있는 최대 기간
+	defaultPortfolioLookbackDays     = 18
+	defaultPortfolioTargetVolatility = 2.0
+)
+
+/*
+ * 이번 주기의 포트폴리오 : 봇 보유 코인 + 봇 미체결 매수 주문 (원화 환산)
+ * 포트폴리오 변동성 목표를 쓰지 않거나 평가 금액을 모르면 nil
+ */
+func (runner *LarryRunner) getPortfolio(equity float64,
+	positions map[string]float64,
+	orderMap map[string][]*types.Order,
+	candleMap map[string][]*types.DayCandle,
+	rates map[string]float64) (portfolio *risk.Portfolio) {
+
+	if runner.config.LarryStrategy.PortfolioRisk.Enable != 1 {
+		return
+	}
+
+	if equity <= 0 {
+		runner.logger.Println("[포트폴리오 변동성] 평가 금액을 구하지 못해 적용 안함")
+		return
+	}
+
+	portfolio = risk.NewPortfolio(equity)
+
+	ownBids := runner.store.FilterOwnOrders(orderMap[types.ORDERSIDE_BID])
+
+	// 매수 후보(타겟), 보유 코인, 미체결 매수 코인의 일봉 : 타겟에서 빠진 보유 코인은 일봉을 따로 조회한다.
+	markets := append([]string{}, runner.targets...)
+	for market := range positions {
+		markets = append(markets, market)
+	}
+	for _, order := range ownBids {
+		markets = append(markets, order.Market)
+	}
+
+	lookback := portfolioLookbackDays(runner.config.LarryStrategy.PortfolioRisk.LookbackDays)
+	returnCandles := runner.getReturnCandles(markets, lookback+2, candleMap)
+
+	for market, volume := range positions {
+		candles := returnCandles[market]
+		if len(candles) == 0 {
+			runner.logger.Printf("[포트폴리오 변동성] %s : 현재가를 조회하지 못해 보유 금액에서 뺌 (수량 %f)\n", market, volume)
+			continue
+		}
+
+		runner.addPortfolioValue(portfolio, market, volume*candles[0].TradePrice, rates)
+	}
+
+	for _, order := range ownBids {
+		price, _ := strconv.ParseFloat(order.Price, 64)
+		remaining, _ := strconv.ParseFloat(order.RemainingVolume, 64)
+		runner.addPortfolioValue(portfolio, order.Market, price*remaining, rates)
+	}
+
+	for market, candles := range returnCandles {
+		portfolio.Returns[market] = risk.Returns(candles, lookback)
+	}
+
+	return
+}
+
+func (runner *LarryRunner) addPortfolioValue(portfolio *risk.Portfolio, market string, value float64, rates map[string]float64) {
+	if value <= 0 {
+		return
+	}
+
+	if krwValue, ok := quote.ToKRW(value, quote.Of(market), rates); ok {
+		portfolio.Add(market, krwValue)
+	} else {
+		runner.logger.Printf("[포트폴리오 변동성] %s : 원화 환산 비율이 없어 보유 금액에서 뺌\n", market)
+	}
+}
+
+func portfolioLookbackDays(lookbackDays int) int {
+	if lookbackDays <= 0 {
+		return defaultPortfolioLookbackDays
+	}
+
+	return lookbackDays
+}
+
+/*
+ * 수익률 계산용 일봉 : 이번 주기에 조회한 일봉이 모자란 마켓만 count 개를 다시 조회한다.
+ */
+func (runner *LarryRunner) getReturnCandles(markets []string,
+	count int,
+	candleMap map[string][]*types.DayCandle) (returnCandles map[string][]*types.DayCandle) {
+
+	returnCandles = make(map[string][]*types.DayCandle)
+
+	fetch := make([]string, 0)
+	for _, market := range markets {
+		if _, exist := returnCandles[market]; exist {
+			continue
+		}
+
+		returnCandles[market] = candleMap[market]
+		if len(returnCandles[market]) < count {
+			fetch = append(fetch, market)
+		}
+	}
+
+	if len(fetch) > 0 {
+		for market, candles := range exchange.GetDayCandlesByCoins(runner.exchange, fetch, count) {
+			if len(candles) > len(returnCandles[market]) {
+				returnCandles[market] = candles
+			}
+		}
+	}
+
+	return
+}
+
+/*
+ * 매수 후 포트폴리오 일간 변동성이 목표를 넘지 않도록 주문 금액(원화)을 줄인다.
+ * 매수 후보의 수익률을 추정할 수 없으면 줄이지 않는다.
+ */
+func (runner *LarryRunner) limitPortfolioRisk(portfolio *risk.Portfolio, market string, amount float64) (limited float64) {
+	target := runner.config.LarryStrategy.PortfolioRisk.TargetVolatility / 100
+	if target <= 0 {
+		target = defaultPortfolioTargetVolatility / 100
+	}
+
+	if len(portfolio.Returns[market]) < 2 {
+		runner.logger.Printf("[포트폴리오 변동성] %s : 수익률 기록이 모자라 적용 안함\n", market)
+		return amount
+	}
+
+	before := portfolio.Volatility()
+	limited = math.Min(amount, portfolio.MaxAmount(market, target))
+
+	runner.logger.Printf("[포트폴리오 변동성] %s : 현재 %.2f%%, 매수 후 %.2f%% (목표 %.2f%%), 주문 금액 %.0f -> %.0f, 보유 %d 코인\n",
+		market, before*100, portfolio.VolatilityWith(market, limited)*100, target*100, amount, limited, len(portfolio.Values))
+
+	return
+}
@@ -0,0 +1,111 @@
+package lw_basic
+
+import (
+	"bytes"
+	"github.com/jekeun/upbit-go/types"
+	"log"
+	"math"
+	"path/filepath"
+	"raindrop/main/exchange"
+	"raindrop/main/model"
+	"raindrop/main/state"
+	"strings"
+	"testing"
+)
+
+// 마켓별 일봉만 돌려주는 거래소
+type candleExchange struct {
+	exchange.Exchange
+	candles map[string][]*types.DayCandle
+}
+
+func (ex *candleExchange) Name() string {
+	return "candles"
+}
+
+func (ex *candleExchange) DayCandles(market string, count int) ([]*types.DayCandle, error) {
+	candles := ex.candles[market]
+	if len(candles) > count {
+		candles = candles[:count]
+	}
+
+	return candles, nil
+}
+
+func almostEqual(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9*math.Max(1, math.Abs(b))
+}
+
+/*
+ * price 에서 시작해 하루씩 rate 만큼 번갈아 오르내리는 일봉 (최신순)
+ */
+func swingCandles(price float64, rate float64, count int) (candles []*types.DayCandle) {
+	for index := 0; index < count; index++ {
+		candles = append(candles, &types.DayCandle{TradePrice: price})
+		if index%2 == 0 {
+			price *= 1 + rate
+		} else {
+			price /= 1 + rate
+		}
+	}
+
+	return
+}
+
+func TestGetPortfolio(t *testing.T) {
+	// 이번 주기 일봉은 타겟(KRW-BTC)만 조회했다.
+	candleMap := map[string][]*types.DayCandle{"KRW-BTC": swingCandles(100000000, 0.02, 20)}
+	ex := &candleExchange{candles: map[string][]*types.DayCandle{
+		"KRW-BTC": candleMap["KRW-BTC"],
+		"KRW-ETH": swingCandles(4000000, 0.03, 20)}}
+
+	tests := []struct {
+		name      string
+		positions map[string]float64
+		values    map[string]float64
+		logged    string
+	}{
+		{"target", map[string]float64{"KRW-BTC": 0.01}, map[string]float64{"KRW-BTC": 1000000}, ""},
+		// 타겟에서 빠진 보유 코인도 일봉을 조회해 평가한다.
+		{"held outside targets", map[string]float64{"KRW-BTC": 0.01, "KRW-ETH": 0.5},
+			map[string]float64{"KRW-BTC": 1000000, "KRW-ETH": 2000000}, ""},
+		// 시세를 조회하지 못한 보유 코인은 로그로 남긴다.
+		{"unknown market", map[string]float64{"KRW-XYZ": 10}, map[string]float64{}, "KRW-XYZ"},
+	}
+
+	for _, test := range tests {
+		store, err := state.Load(filepath.Join(t.TempDir(), "state.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var output bytes.Buffer
+		config := new(model.Config)
+		config.LarryStrategy.PortfolioRisk.Enable = 1
+
+		runner := &LarryRunner{
+			exchange: ex,
+			store:    store,
+			config:   config,
+			logger:   log.New(&output, "", 0),
+			targets:  []string{"KRW-BTC"}}
+
+		portfolio := runner.getPortfolio(10000000, test.positions, nil, candleMap, map[string]float64{"KRW": 1})
+
+		if len(portfolio.Values) != len(test.values) {
+			t.Errorf("%s : values = %v, want %v", test.name, portfolio.Values, test.values)
+		}
+		for market, value := range test.values {
+			if !almostEqual(portfolio.Values[market], value) {
+				t.Errorf("%s : %s value = %v, want %v", test.name, market, portfolio.Values[market], value)
+			}
+			if len(portfolio.Returns[market]) != defaultPortfolioLookbackDays {
+				t.Errorf("%s : %s returns = %d, want %d", test.name, market, len(portfolio.Returns[market]), defaultPortfolioLookbackDays)
+			}
+		}
+
+		if len(test.logged) > 0 && !strings.Contains(output.String(), test.logged) {
+			t.Errorf("%s : log = %q, want %s", test.name, output.String(), test.logged)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package lw_basic
+
+import (
+	"fmt"
+	"github.com/jekeun/upbit-go/types"
+	"raindrop/main/exchange"
+	"raindrop/main/model"
+	"strings"
+)
+
+/*
+ * 시장 국면 필터
+ * 기준 마켓(기본 KRW-BTC)이 이동평균 아래에 있거나 이평 스코어가 낮으면
+ * 알트코인 돌파 매수가 손실로 끝나는 경우가 많아 매수를 막거나 주문 금액을 줄인다.
+ */
+
+const (
+	RegimeActionSuppress = "suppress"
+	RegimeActionScale    = "scale"
+
+	defaultRegimeMarket    = "KRW-BTC"
+	defaultRegimeScaleRate = 50.0
+)
+
+/*
+ * 틱마다 판단한 시장 국면
+ * AmountRate : 주문 금액에 곱할 비율 (1 : 정상, 0 : 매수 중지)
+ */
+type Regime struct {
+	Market        string
+	CurrentPrice  float64
+	MovingAverage float64
+	MalScore      float64
+	Bearish       bool
+	Reasons       []string
+	AmountRate    float64
+}
+
+/*
+ * 설정 기본값을 채운다.
+ */
+func regimeConfigWithDefaults(config model.RegimeConfig) model.RegimeConfig {
+	if len(config.Market) == 0 {
+		config.Market = defaultRegimeMarket
+	}
+
+	if config.Action != RegimeActionScale {
+		config.Action = RegimeActionSuppress
+	}
+
+	if config.ScaleRate <= 0 {
+		config.ScaleRate = defaultRegimeScaleRate
+	}
+
+	return config
+}
+
+/*
+ * 기준 마켓 일봉(최신순)으로 시장 국면을 판단한다.
+ * 이동평균 기간보다 봉이 적으면 이동평균 조건은 판단하지 않는다.
+ */
+func EvaluateRegime(config model.RegimeConfig, candles []*types.DayCandle) (regime *Regime) {
+	config = regimeConfigWithDefaults(config)
+
+	regime = &Regime{
+		Market:     config.Market,
+		Reasons:    make([]string, 0),
+		AmountRate: 1.0}
+
+	if len(candles) == 0 {
+		return
+	}
+
+	regime.CurrentPrice = candles[0].TradePrice
+	regime.MalScore = MalScore(candles)
+
+	if config.MovingAverage > 0 && len(candles) >= config.MovingAverage {
+		sum := 0.0
+		for _, candle := range candles[:config.MovingAverage] {
+			sum += candle.TradePrice
+		}
+		regime.MovingAverage = sum / float64(config.MovingAverage)
+
+		if regime.CurrentPrice < regime.MovingAverage {
+			regime.Reasons = append(regime.Reasons,
+				fmt.Sprintf("현재가 %f < %d일 이평 %f", regime.CurrentPrice, config.MovingAverage, regime.MovingAverage))
+		}
+	}
+
+	if config.MinMalScore > 0 && regime.MalScore < config.MinMalScore {
+		regime.Reasons = append(regime.Reasons,
+			fmt.Sprintf("이평 스코어 %.2f < %.2f", regime.MalScore, config.MinMalScore))
+	}
+
+	if len(regime.Reasons) > 0 {
+		regime.Bearish = true
+
+		if config.Action == RegimeActionScale {
+			regime.AmountRate = config.ScaleRate / 100.0
+		} else {
+			regime.AmountRate = 0
+		}
+	}
+
+	return
+}
+
+/*
+ * 이번 틱의 시장 국면을 판단하고 결과를 로그로 남긴다.
+ * 필터를 쓰지 않으면 nil, 캔들을 얻지 못하면 매수를 막지 않는다.
+ */
+func (runner *LarryRunner) getRegime(candleMap map[string][]*types.DayCandle) (regime *Regime) {
+	config := regimeConfigWithDefaults(runner.config.LarryStrategy.Regime)
+	if config.Enable != 1 {
+		return
+	}
+
+	candles, exist := candleMap[config.Market]
+	if !exist || len(candles) < config.MovingAverage {
+		count := 20
+		if config.MovingAverage > count {
+			count = config.MovingAverage
+		}
+		candles = exchange.GetDayCandlesByCoins(runner.exchange, []string{config.Market}, count)[config.Market]
+	}
+
+	if len(candles) == 0 {
+		runner.logger.Printf("[시장 국면] %s 캔들 조회 실패 : 필터 적용 안함\n", config.Market)
+		return
+	}
+
+	regime = EvaluateRegime(config, candles)
+
+	if !regime.Bearish {
+		runner.logger.Printf("[시장 국면] %s 정상 : 현재가 %f, 이평 %f, 이평 스코어 %.2f\n",
+			regime.Market, regime.CurrentPrice, regime.MovingAverage, regime.MalScore)
+	} else if regime.AmountRate <= 0 {
+		runner.logger.Printf("[시장 국면] %s 약세, 매수 중지 : %s\n",
+			regime.Market, strings.Join(regime.Reasons, ", "))
+	} else {
+		runner.logger.Printf("[시장 국면] %s 약세, 주문 금액 %.0f%% 적용 : %s\n",
+			regime.Market, regime.AmountRate*100, strings.Join(regime.Reasons, ", "))
+	}
+
+	return
+}
@@ -0,0 +1,149 @@
+package lw_basic
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"raindrop/main/exchange"
+	"raindrop/main/model"
+)
+
+/*
+ * 코인별 매수 신호 계산 결과
+ * 실매매(doStrategy), signals 명령, 백테스트가 같은 계산을 사용한다.
+ */
+type Signal struct {
+	Market             string
+	OpeningPrice       float64
+	Range              float64
+	KValue             float64
+	TriggerPrice       float64
+	CurrentPrice       float64
+	MalScore           float64
+	AverageVariability float64
+	MoneyPlanRate      float64
+	OrderAmount        float64
+	Triggered          bool
+}
+
+// 자금관리 금액 계산에 필요한 최소 봉 개수 (당일 + 최근 3일)
+const minCandleCountForAmount = 4
+
+/*
+ * 일봉 목록(최신순)으로 매수 신호를 계산한다.
+ * k 는 노이즈 비율 평균, 이평 스코어는 3일~N일 이평선 기준으로 구한다.
+ */
+func EvaluateSignal(strategy *model.LarryStrategyConfig, market string, candles []*types.DayCandle) *Signal {
+	return NewSignal(strategy, market, candles, KNoiseValue(candles), MalScore(candles))
+}
+
+/*
+ * 주어진 k 와 이평 스코어로 매수 신호를 계산한다.
+ * candles[0] 은 당일 봉, candles[1] 은 전일 봉
+ */
+func NewSignal(strategy *model.LarryStrategyConfig,
+	market string,
+	candles []*types.DayCandle,
+	kValue float64,
+	malScore float64) (signal *Signal) {
+
+	if len(candles) < 2 {
+		return
+	}
+
+	rangeValue := candles[1].HighPrice - candles[1].LowPrice
+
+	signal = &Signal{
+		Market:       market,
+		OpeningPrice: candles[0].OpeningPrice,
+		Range:        rangeValue,
+		KValue:       kValue,
+		TriggerPrice: candles[0].OpeningPrice + rangeValue*kValue,
+		CurrentPrice: candles[0].TradePrice,
+		MalScore:     malScore}
+
+	signal.Triggered = signal.CurrentPrice >= signal.TriggerPrice
+
+	if len(candles) >= minCandleCountForAmount {
+		signal.OrderAmount, signal.MoneyPlanRate, signal.AverageVariability = getOrderAmount(strategy.OrderAmount,
+			strategy.MinOrderAmountRate,
+			strategy.MoneyPlan,
+			candles,
+			map[string]float64{market: malScore}, market)
+	}
+
+	return
+}
+
+/*
+ * 노이즈 비율 평균으로 k 를 구한다.
+ */
+func KNoiseValue(candles []*types.DayCandle) float64 {
+	return getKNoiseValueByDay(map[string][]*types.DayCandle{"": candles})[""]
+}
+
+/*
+ * 이동평균선 스코어를 구한다.
+ */
+func MalScore(candles []*types.DayCandle) float64 {
+	candleMap := map[string][]*types.DayCandle{"": candles}
+
+	return getMalScore(getMovingAverageLineByDay(candleMap), candleMap)[""]
+}
+
+/*
+ * doStrategy 에서 사용하는 신호 계산
+ * 코인별 k 가 없으면 설정값을 사용한다.
+ */
+func evaluateSignal(strategy *model.LarryStrategyConfig,
+	market string,
+	candles []*types.DayCandle,
+	kMap map[string]float64,
+	malScoreMap map[string]float64) *Signal {
+
+	kValue := strategy.KValue
+	if coinKValue, exist := kMap[market]; exist {
+		kValue = coinKValue
+	}
+
+	return NewSignal(strategy, market, candles, kValue, malScoreMap[market])
+}
+
+/*
+ * 타겟 코인 전체의 현재 매수 신호를 계산한다. (주문은 하지 않음)
+ */
+func (runner *LarryRunner) Signals() (signals []*Signal) {
+	signals = make([]*Signal, 0)
+
+	candleMap := exchange.GetDayCandlesByCoins(runner.exchange, runner.targets, 20)
+
+	// 평가 금액 기준 주문 금액이면 현재 잔고로 최대 주문 금액을 정한다.
+	strategy := runner.config.LarryStrategy
+	if IsEquitySizing(strategy.Sizing) {
+		if balances, err := runner.exchange.Accounts(); err == nil {
+			strategy.OrderAmount = BaseOrderAmount(&strategy, runner.getEquity(balances, candleMap))
+		}
+	}
+
+	for _, market := range strategy.Targets {
+		if signal := EvaluateSignal(&strategy, market, candleMap[market]); signal != nil {
+			signals = append(signals, signal)
+		}
+	}
+
+	return
+}
+
+/*
+ * 기준 마켓의 현재 시장 국면을 판단한다. (필터 사용 여부와 관계없이 계산)
+ */
+func (runner *LarryRunner) Regime() *Regime {
+	config := regimeConfigWithDefaults(runner.config.LarryStrategy.Regime)
+
+	count := 20
+	if config.MovingAverage > count {
+		count = config.MovingAverage
+	}
+
+	candleMap := exchange.GetDayCandlesByCoins(runner.exchange, []string{config.Market}, count)
+
+	return EvaluateRegime(config, candleMap[config.Market])
+}
@@ -0,0 +1,76 @@
+package lw_basic
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"raindrop/main/model"
+	"raindrop/main/quote"
+)
+
+/*
+ * 주문 금액 기준
+ * fixed 는 order_amount 를 그대로 쓰고, 평가 금액 기준이면 계정(자금 배분을 쓰면 전략 예산)이 커지고 줄어드는 만큼
+ * 최대 주문 금액도 따라 바뀐다. 자금관리 비율과 이평 스코어는 이 금액에 곱해진다. (scaleOrderAmount)
+ */
+
+const (
+	SizingModeFixed         = "fixed"
+	SizingModeEquityPercent = "equity_percent"
+	SizingModeEquityPerCoin = "equity_per_coin"
+)
+
+func IsEquitySizing(config model.SizingConfig) bool {
+	return config.Mode == SizingModeEquityPercent || config.Mode == SizingModeEquityPerCoin
+}
+
+/*
+ * 평가 금액(원화)으로 원화 최대 주문 금액을 구한다.
+ * fixed 이거나 평가 금액을 모르면(0 이하) order_amount 를 사용한다.
+ */
+func BaseOrderAmount(strategy *model.LarryStrategyConfig, equity float64) (amount float64) {
+	sizing := strategy.Sizing
+	if !IsEquitySizing(sizing) || equity <= 0 {
+		return strategy.OrderAmount
+	}
+
+	switch sizing.Mode {
+	case SizingModeEquityPercent:
+		amount = equity * sizing.EquityRate / 100
+	case SizingModeEquityPerCoin:
+		maxCoin := strategy.MaxCoin
+		if maxCoin < 1 {
+			maxCoin = 1
+		}
+		amount = equity / float64(maxCoin)
+	}
+
+	if sizing.MaxOrderAmount > 0 && amount > sizing.MaxOrderAmount {
+		amount = sizing.MaxOrderAmount
+	}
+
+	return
+}
+
+/*
+ * 계정 평가 금액 (원화 + 보유 코인 원화 환산, 제외 코인은 빼고)
+ */
+func (runner *LarryRunner) getEquity(balances []*types.Balance, candleMap map[string][]*types.DayCandle) (equity float64) {
+	equity, missing := quote.Equity(runner.exchange, balances, candleMap, runner.config.LarryStrategy.ExcludedCurrencies)
+	if len(missing) > 0 {
+		runner.logger.Printf("원화 환산 실패로 평가 금액에서 빠진 코인 : %v\n", missing)
+	}
+
+	return
+}
+
+/*
+ * 매수를 시도할 최소 주문 가능 잔고
+ * fixed 는 최대 주문 금액이 있어야 주문한다. 평가 금액 기준이면 최대 주문 금액의 합이 평가 금액과 같아
+ * 수수료만큼 잔고가 모자라므로 최소 주문 금액(min_order_amount_rate %)만 있으면 남은 잔고로 주문한다.
+ */
+func RequiredBalance(strategy *model.LarryStrategyConfig, maxOrderAmount float64) float64 {
+	if !IsEquitySizing(strategy.Sizing) {
+		return maxOrderAmount
+	}
+
+	return maxOrderAmount * strategy.MinOrderAmountRate / 100
+}
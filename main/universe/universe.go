@@ -0,0 +1,277 @@
+package universe
+
+import (
+	"fmt"
+	"github.com/jekeun/upbit-go/types"
+	"log"
+	"math"
+	"raindrop/main/exchange"
+	"raindrop/main/model"
+	"raindrop/main/quote"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+ * 전략 대상 코인(유니버스) 선정
+ * 기준 통화의 전체 마켓을 24시간 거래대금, 평균 변동폭, 노이즈 비율로 순위를 매겨 상위 N 개를 고른다.
+ * 유의 종목, 거래대금이 적은 코인은 제외하고 include / exclude 목록은 항상 반영한다.
+ * 전체 마켓 조회(exchange.MarketLister)를 지원하는 거래소(Upbit, Bithumb)에서 사용할 수 있다.
+ */
+
+const (
+	defaultTopN          = 10
+	defaultRefreshMinute = 60
+	defaultRangeDays     = 20
+
+	// 후보 일봉 조회 간격 (거래소 시세 API 요청 제한)
+	candleRequestInterval = 100 * time.Millisecond
+)
+
+type Candidate struct {
+	Market       string  `json:"market"`
+	TradeValue   float64 `json:"trade_value"`
+	AverageRange float64 `json:"average_range"`
+	Noise        float64 `json:"noise"`
+	Score        float64 `json:"score"`
+	Warning      bool    `json:"warning"`
+	// 제외된 이유 (선정되면 빈 값)
+	Excluded string `json:"excluded,omitempty"`
+}
+
+type Selector struct {
+	exchange exchange.Exchange
+	config   model.UniverseConfig
+	logger   *log.Logger
+
+	mutex       sync.Mutex
+	targets     []string
+	lastRefresh time.Time
+}
+
+func NewSelector(ex exchange.Exchange, config model.UniverseConfig, logger *log.Logger) *Selector {
+	if len(config.Quote) == 0 {
+		config.Quote = quote.KRW
+	}
+	if config.TopN <= 0 {
+		config.TopN = defaultTopN
+	}
+	if config.RefreshMinute <= 0 {
+		config.RefreshMinute = defaultRefreshMinute
+	}
+	if config.CandidateCount < config.TopN {
+		config.CandidateCount = config.TopN * 3
+	}
+	if config.RangeDays <= 0 {
+		config.RangeDays = defaultRangeDays
+	}
+
+	return &Selector{
+		exchange: ex,
+		config:   config,
+		logger:   logger}
+}
+
+/*
+ * 선정된 타겟 목록, 갱신 주기가 지났으면 다시 고른다.
+ * 선정에 실패하면 직전 목록을, 직전 목록도 없으면 fallback 을 돌려준다.
+ */
+func (selector *Selector) Targets(fallback []string) []string {
+	selector.mutex.Lock()
+	defer selector.mutex.Unlock()
+
+	if time.Since(selector.lastRefresh) >= time.Duration(selector.config.RefreshMinute)*time.Minute {
+		selector.lastRefresh = time.Now()
+
+		targets, _, err := selector.Select()
+		if err != nil {
+			selector.logger.Printf("타겟 코인 선정 실패 : %s\n", err.Error())
+		} else {
+			selector.logger.Printf("타겟 코인 선정 : %v\n", targets)
+			selector.targets = targets
+		}
+	}
+
+	if len(selector.targets) == 0 {
+		return fallback
+	}
+
+	return selector.targets
+}
+
+/*
+ * 전체 마켓 순위를 매겨 타겟 목록을 만든다.
+ * candidates 는 일봉까지 조회한 후보 (점수순, 제외 사유 포함)
+ */
+func (selector *Selector) Select() (targets []string, candidates []*Candidate, err error) {
+	candidates, err = selector.Rank()
+	if err != nil {
+		return
+	}
+
+	targets = make([]string, 0, selector.config.TopN+len(selector.config.Include))
+	selected := make(map[string]bool)
+
+	for _, market := range selector.config.Include {
+		if !contains(selector.config.Exclude, market) && !selected[market] {
+			targets = append(targets, market)
+			selected[market] = true
+		}
+	}
+
+	count := 0
+	for _, candidate := range candidates {
+		if count >= selector.config.TopN {
+			break
+		}
+		if len(candidate.Excluded) > 0 || selected[candidate.Market] {
+			continue
+		}
+
+		targets = append(targets, candidate.Market)
+		selected[candidate.Market] = true
+		count++
+	}
+
+	return
+}
+
+/*
+ * 거래대금 상위 후보의 일봉으로 평균 변동폭, 노이즈를 구해 순위를 매긴다.
+ * 점수 = 거래대금 순위 + 변동폭 순위(클수록 좋음) + 노이즈 순위(작을수록 좋음), 작을수록 상위
+ */
+func (selector *Selector) Rank() (candidates []*Candidate, err error) {
+	lister, ok := exchange.MarketListerOf(selector.exchange)
+	if !ok {
+		return nil, fmt.Errorf("전체 마켓을 조회할 수 없는 거래소 : %s", selector.exchange.Name())
+	}
+
+	summaries, err := lister.MarketSummaries(selector.config.Quote)
+	if err != nil {
+		return
+	}
+
+	if len(summaries) == 0 {
+		return nil, fmt.Errorf("%s 마켓이 없음", selector.config.Quote)
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].TradeValue > summaries[j].TradeValue
+	})
+
+	candidates = make([]*Candidate, 0, selector.config.CandidateCount)
+	ranked := make([]*Candidate, 0, selector.config.CandidateCount)
+	fetched := 0
+
+	for _, summary := range summaries {
+		if len(ranked) >= selector.config.CandidateCount {
+			break
+		}
+
+		candidate := &Candidate{
+			Market:     summary.Market,
+			TradeValue: summary.TradeValue,
+			Warning:    summary.Warning}
+		candidates = append(candidates, candidate)
+
+		switch {
+		case contains(selector.config.Exclude, summary.Market):
+			candidate.Excluded = "exclude"
+		case candidate.Warning && selector.config.AllowWarning != 1:
+			candidate.Excluded = "warning"
+		case summary.TradeValue < selector.config.MinTradeValue:
+			candidate.Excluded = "trade_value"
+		}
+		if len(candidate.Excluded) > 0 {
+			continue
+		}
+
+		if fetched > 0 {
+			time.Sleep(candleRequestInterval)
+		}
+		fetched++
+
+		candles, candleErr := selector.exchange.DayCandles(summary.Market, selector.config.RangeDays+1)
+		if candleErr != nil || len(candles) < 2 {
+			candidate.Excluded = "candles"
+			continue
+		}
+
+		// 진행 중인 당일 봉은 빼고 계산한다.
+		candidate.AverageRange, candidate.Noise = rangeAndNoise(candles[1:])
+
+		switch {
+		case candidate.AverageRange*100 < selector.config.MinAverageRange:
+			candidate.Excluded = "range"
+		case selector.config.MaxNoise > 0 && candidate.Noise > selector.config.MaxNoise:
+			candidate.Excluded = "noise"
+		}
+		if len(candidate.Excluded) > 0 {
+			continue
+		}
+
+		ranked = append(ranked, candidate)
+	}
+
+	score(ranked)
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		left, right := candidates[i], candidates[j]
+		if (len(left.Excluded) == 0) != (len(right.Excluded) == 0) {
+			return len(left.Excluded) == 0
+		}
+		if len(left.Excluded) == 0 {
+			return left.Score < right.Score
+		}
+		return left.TradeValue > right.TradeValue
+	})
+
+	return
+}
+
+/*
+ * 평균 변동폭 ((고가-저가)/시가) 과 평균 노이즈 비율 (1 - |시가-종가|/(고가-저가))
+ */
+func rangeAndNoise(candles []*types.DayCandle) (averageRange float64, noise float64) {
+	count := 0.0
+	for _, candle := range candles {
+		if candle.OpeningPrice <= 0 || candle.HighPrice <= candle.LowPrice {
+			continue
+		}
+
+		averageRange += (candle.HighPrice - candle.LowPrice) / candle.OpeningPrice
+		noise += 1 - math.Abs(candle.OpeningPrice-candle.TradePrice)/(candle.HighPrice-candle.LowPrice)
+		count++
+	}
+
+	if count > 0 {
+		averageRange /= count
+		noise /= count
+	}
+
+	return
+}
+
+func score(candidates []*Candidate) {
+	addRanks(candidates, func(i, j int) bool { return candidates[i].TradeValue > candidates[j].TradeValue })
+	addRanks(candidates, func(i, j int) bool { return candidates[i].AverageRange > candidates[j].AverageRange })
+	addRanks(candidates, func(i, j int) bool { return candidates[i].Noise < candidates[j].Noise })
+}
+
+func addRanks(candidates []*Candidate, less func(i, j int) bool) {
+	sort.SliceStable(candidates, less)
+	for rank, candidate := range candidates {
+		candidate.Score += float64(rank + 1)
+	}
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
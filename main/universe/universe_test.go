@@ -0,0 +1,110 @@
+package universe
+
+import (
+	"github.com/jekeun/upbit-go/types"
+	"io/ioutil"
+	"log"
+	"raindrop/main/exchange"
+	"raindrop/main/model"
+	"reflect"
+	"testing"
+)
+
+// 전체 마켓 조회를 지원하는 거래소
+type listerExchange struct {
+	exchange.Exchange
+	summaries []*exchange.MarketSummary
+	candles   map[string][]*types.DayCandle
+}
+
+func (ex *listerExchange) Name() string {
+	return "fake"
+}
+
+func (ex *listerExchange) MarketSummaries(quoteCurrency string) ([]*exchange.MarketSummary, error) {
+	return ex.summaries, nil
+}
+
+func (ex *listerExchange) DayCandles(market string, count int) ([]*types.DayCandle, error) {
+	return ex.candles[market], nil
+}
+
+// 전체 마켓 조회를 지원하지 않는 거래소
+type plainExchange struct {
+	exchange.Exchange
+}
+
+func (ex *plainExchange) Name() string {
+	return "plain"
+}
+
+/*
+ * 당일 봉 + 변동폭 rangeRate, 노이즈 noise 인 일봉 days 개 (최신순)
+ */
+func candles(days int, rangeRate float64, noise float64) []*types.DayCandle {
+	result := []*types.DayCandle{{OpeningPrice: 100, TradePrice: 100, HighPrice: 100, LowPrice: 100}}
+	for day := 0; day < days; day++ {
+		high := 100 * (1 + rangeRate)
+		result = append(result, &types.DayCandle{
+			OpeningPrice: 100,
+			TradePrice:   100 + (high-100)*(1-noise),
+			HighPrice:    high,
+			LowPrice:     100})
+	}
+
+	return result
+}
+
+func newFakeExchange() *listerExchange {
+	return &listerExchange{
+		summaries: []*exchange.MarketSummary{
+			{Market: "KRW-AAA", TradeValue: 300},
+			{Market: "KRW-BBB", TradeValue: 500},
+			{Market: "KRW-CCC", TradeValue: 400, Warning: true},
+			{Market: "KRW-DDD", TradeValue: 50},
+			{Market: "KRW-EEE", TradeValue: 200},
+		},
+		candles: map[string][]*types.DayCandle{
+			"KRW-AAA": candles(3, 0.10, 0.2),
+			"KRW-BBB": candles(3, 0.05, 0.5),
+			"KRW-CCC": candles(3, 0.10, 0.1),
+			"KRW-DDD": candles(3, 0.10, 0.1),
+			"KRW-EEE": candles(3, 0.01, 0.9)}}
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name   string
+		config model.UniverseConfig
+		want   []string
+	}{
+		{"top 2", model.UniverseConfig{TopN: 2, MinTradeValue: 100}, []string{"KRW-AAA", "KRW-BBB"}},
+		{"allow warning", model.UniverseConfig{TopN: 1, MinTradeValue: 100, AllowWarning: 1}, []string{"KRW-CCC"}},
+		{"include and exclude", model.UniverseConfig{TopN: 1, MinTradeValue: 100,
+			Include: []string{"KRW-DDD"}, Exclude: []string{"KRW-AAA"}}, []string{"KRW-DDD", "KRW-BBB"}},
+		{"min average range", model.UniverseConfig{TopN: 3, MinTradeValue: 100, MinAverageRange: 5},
+			[]string{"KRW-AAA", "KRW-BBB"}},
+	}
+
+	for _, test := range tests {
+		selector := NewSelector(newFakeExchange(), test.config, log.New(ioutil.Discard, "", 0))
+
+		targets, _, err := selector.Select()
+		if err != nil {
+			t.Errorf("%s : %s", test.name, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(targets, test.want) {
+			t.Errorf("%s : targets = %v, want %v", test.name, targets, test.want)
+		}
+	}
+}
+
+func TestTargetsFallback(t *testing.T) {
+	fallback := []string{"KRW-BTC"}
+
+	selector := NewSelector(&plainExchange{}, model.UniverseConfig{}, log.New(ioutil.Discard, "", 0))
+	if targets := selector.Targets(fallback); !reflect.DeepEqual(targets, fallback) {
+		t.Errorf("targets = %v, want %v", targets, fallback)
+	}
+}
@@ -0,0 +1,102 @@
+package identifier
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * 주문 Identifier 생성 및 해석
+ * 형식 : rd:{전략명}:{마켓}:{세션}:{순번}
+ * 세션은 프로세스 시작 시각 + 난수로 만들어 재시작 후에도 충돌하지 않는다.
+ */
+
+const (
+	Prefix    = "rd"
+	separator = ":"
+)
+
+type Tag struct {
+	Strategy string
+	Market   string
+	Session  string
+	Sequence uint64
+}
+
+type Generator struct {
+	strategy string
+	session  string
+	sequence uint64
+}
+
+func NewGenerator(strategy string) *Generator {
+	return &Generator{
+		strategy: strings.Replace(strategy, separator, "_", -1),
+		session:  newSession(),
+	}
+}
+
+/*
+ * 마켓별 주문 Identifier 를 새로 발급한다.
+ * 같은 Generator 에서 발급된 값은 순번이 증가하므로 같은 시각에도 중복되지 않는다.
+ */
+func (g *Generator) Next(market string) string {
+	sequence := atomic.AddUint64(&g.sequence, 1)
+
+	return strings.Join([]string{
+		Prefix,
+		g.strategy,
+		market,
+		g.session,
+		strconv.FormatUint(sequence, 10)}, separator)
+}
+
+func (g *Generator) Strategy() string {
+	return g.strategy
+}
+
+/*
+ * Identifier 를 해석한다. 봇이 만든 형식이 아니면 ok 는 false
+ */
+func Parse(id string) (tag Tag, ok bool) {
+	fields := strings.Split(id, separator)
+	if len(fields) != 5 || fields[0] != Prefix {
+		return
+	}
+
+	sequence, err := strconv.ParseUint(fields[4], 10, 64)
+	if err != nil {
+		return
+	}
+
+	tag = Tag{
+		Strategy: fields[1],
+		Market:   fields[2],
+		Session:  fields[3],
+		Sequence: sequence}
+	ok = true
+
+	return
+}
+
+/*
+ * 봇이 발급한 Identifier 인지 확인한다.
+ */
+func IsOwn(id string) bool {
+	_, ok := Parse(id)
+	return ok
+}
+
+func newSession() string {
+	random := make([]byte, 3)
+	if _, err := rand.Read(random); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+
+	return fmt.Sprintf("%s%s", strconv.FormatInt(time.Now().Unix(), 36), hex.EncodeToString(random))
+}
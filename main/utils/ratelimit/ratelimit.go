@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+ * 요청 간격 제한
+ * 마지막 요청 후 interval 이 지나기 전에는 Wait 에서 대기한다.
+ * 여러 goroutine(계정, recorder) 이 같은 Limiter 를 공유해도 된다.
+ */
+type Limiter struct {
+	interval time.Duration
+
+	mutex sync.Mutex
+	next  time.Time
+}
+
+/*
+ * 초당 perSecond 회로 제한
+ */
+func New(perSecond int) *Limiter {
+	if perSecond <= 0 {
+		perSecond = 1
+	}
+
+	return &Limiter{interval: time.Second / time.Duration(perSecond)}
+}
+
+func (limiter *Limiter) Wait() {
+	limiter.mutex.Lock()
+
+	now := time.Now()
+	wait := limiter.next.Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+	limiter.next = now.Add(wait + limiter.interval)
+
+	limiter.mutex.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
@@ -1,61 +1,137 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"github.com/natefinch/lumberjack"
-	"log"
 	"os"
+	"path/filepath"
 	"raindrop/main/model"
-	"raindrop/main/strategy/lw_basic"
-	printUtil "raindrop/main/utils/print"
-	"time"
+	"sort"
 )
 
+const (
+	defaultConfigPath = "./config.json"
+	defaultLogDir     = "./log"
+)
+
+// 전체 설정과 계정별 설정
+// config 는 계정 하나를 대상으로 하는 명령(balance, orders 등)에서 사용한다.
+var rootConfig *model.Config
+var accountConfigs []*model.Config
 var config *model.Config
-var lwBasicRunner *lw_basic.LarryRunner
+
+// 전역 옵션
+var configPath string
+var logDir string
+var dryRun bool
+var accountName string
+
+type command struct {
+	description string
+	run         func(args []string) error
+}
+
+var commands = map[string]command{
+	"run":           {"전략 실행 (10초 주기)", runCommand},
+	"balance":       {"잔고 및 수익률 조회", balanceCommand},
+	"orders":        {"미체결 주문 조회", ordersCommand},
+	"cancel-all":    {"미체결 주문 전체 취소", cancelAllCommand},
+	"flatten":       {"보유 코인 시장가 전량 매도", flattenCommand},
+	"signals":       {"타겟 코인 매수 신호 계산 (주문 없음)", signalsCommand},
+	"backtest":      {"일봉 백테스트", backtestCommand},
+	"optimize":      {"백테스트 파라미터 탐색", optimizeCommand},
+	"walk-forward":  {"학습/검증 구간 반복으로 과최적화 점검", walkForwardCommand},
+	"montecarlo":    {"거래 재표본으로 낙폭, 최종 자금, 연속 손실 분포 계산", montecarloCommand},
+	"report":        {"실거래 성과 리포트", reportCommand},
+	"html-report":   {"자금 곡선, 낙폭, 월별 수익률, 단순 보유 비교 HTML 리포트", htmlReportCommand},
+	"reconcile":     {"거래소 잔고와 봇 보유 수량 대사", reconcileCommand},
+	"universe":      {"거래대금, 변동폭, 노이즈로 타겟 코인 선정 결과 조회", universeCommand},
+	"record":        {"타겟 코인 캔들, 현재가, 호가 기록", recordCommand},
+	"fetch-history": {"과거 캔들 다운로드 (중단시 이어받기)", fetchHistoryCommand},
+}
 
 func main() {
-	fmt.Println("Start RainDrop")
+	flag.StringVar(&configPath, "config", defaultConfigPath, "설정 파일 경로")
+	flag.StringVar(&logDir, "log-dir", defaultLogDir, "로그 디렉토리")
+	flag.BoolVar(&dryRun, "dry-run", false, "주문을 보내지 않고 decision log 에 기록")
+	flag.StringVar(&accountName, "account", "", "대상 계정 이름 (기본 : run 은 전체, 그 외 명령은 첫 번째 계정)")
+	flag.Usage = usage
+	flag.Parse()
 
-	config = new(model.Config)
+	// 명령이 없으면 기존과 같이 전략을 실행한다.
+	name := "run"
+	args := flag.Args()
+	if len(args) > 0 {
+		name = args[0]
+		args = args[1:]
+	}
 
-	config.LoadConfiguration("./config.json")
+	cmd, exist := commands[name]
+	if !exist {
+		fmt.Fprintf(os.Stderr, "알 수 없는 명령 : %s\n", name)
+		usage()
+		os.Exit(2)
+	}
 
-	initRaindrop()
+	if err := loadAccountConfigs(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
-	// 10초 단위로 수행
-	for {
-		runStrategy()
-		time.Sleep(10 * time.Second)
+	if err := cmd.run(args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 }
 
-func initRaindrop() {
-	// 처음 실행시키는 경우,
-	fmt.Println("Init : Config information")
-	fmt.Println(printUtil.PrettyPrint(config))
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage : %s [options] <command> [command options]\n\n", filepath.Base(os.Args[0]))
+	fmt.Fprintln(os.Stderr, "Options :")
+	flag.PrintDefaults()
 
-	f, err := os.OpenFile("./log/raindrop.log",
-		os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
-	if err != nil {
-		log.Println(err)
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	logger := log.New(f, "RainDrop : ", log.LstdFlags)
-	logger.SetOutput(&lumberjack.Logger{
-		Filename:   "./log/raindrop.log",
-		MaxSize:    20, // megabytes after which new file is created
-		MaxBackups: 5, // number of backups
-		MaxAge:     31, //days
-	})
-	logger.Println("Start raindrop")
-
-	lwBasicRunner = new(lw_basic.LarryRunner)
-	lwBasicRunner.Init(config, logger)
+	fmt.Fprintln(os.Stderr, "\nCommands :")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, commands[name].description)
+	}
 }
 
-func runStrategy() {
-	lwBasicRunner.RunLWBasicStrategy()
-}
+/*
+ * 설정 파일을 읽어 계정별 설정을 만든다.
+ * -account 옵션이 있으면 해당 계정만 남긴다.
+ */
+func loadAccountConfigs() (err error) {
+	rootConfig = new(model.Config)
+	if err = rootConfig.LoadConfiguration(configPath); err != nil {
+		return fmt.Errorf("설정 파일 읽기 실패 : %s", err.Error())
+	}
+
+	if dryRun {
+		rootConfig.DryRun = 1
+	}
+
+	configs, err := rootConfig.AccountConfigs(logDir)
+	if err != nil {
+		return
+	}
+
+	accountConfigs = make([]*model.Config, 0, len(configs))
+	for _, accountConfig := range configs {
+		if len(accountName) == 0 || accountConfig.Name == accountName {
+			accountConfigs = append(accountConfigs, accountConfig)
+		}
+	}
 
+	if len(accountConfigs) == 0 {
+		return fmt.Errorf("계정을 찾을 수 없음 : %s", accountName)
+	}
+
+	config = accountConfigs[0]
 
+	return
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"github.com/natefinch/lumberjack"
+	"log"
+	"os"
+	"path/filepath"
+	"raindrop/main/exchange"
+	"raindrop/main/exchange/bithumb"
+	"raindrop/main/health"
+	"raindrop/main/model"
+	"raindrop/main/reconcile"
+	"raindrop/main/state"
+	"raindrop/main/strategy/lw_basic"
+	printUtil "raindrop/main/utils/print"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+/*
+ * 계정 하나의 전략 실행 단위
+ * 계정마다 거래소 클라이언트, 로그, 상태 파일, 전략 Runner 를 따로 가진다.
+ * 계정의 전략(strategies)은 같은 상태 파일과 자금 배분을 공유하고 설정 순서대로 수행한다.
+ */
+type accountRunner struct {
+	config        *model.Config
+	logger        *log.Logger
+	strategies    []*liveStrategy
+	store         *state.Store
+	healthChecker *health.Checker
+	reconciler    *reconcile.Reconciler
+	// 실매매 전략과 shadow 전략이 같이 보는 주기별 일봉
+	candleCache *exchange.CandleCache
+	shadows     []*shadowRunner
+}
+
+// 계정에서 실행하는 실매매 전략
+type liveStrategy struct {
+	name string
+	run  func()
+}
+
+// 전략 수행 주기
+const tickInterval = 10 * time.Second
+
+func runCommand(args []string) (err error) {
+	fmt.Println("Start RainDrop")
+
+	// 처음 실행시키는 경우,
+	fmt.Println("Init : Config information")
+	fmt.Println(printUtil.PrettyPrint(rootConfig.Redacted()))
+
+	runners := make([]*accountRunner, 0, len(accountConfigs))
+	for _, accountConfig := range accountConfigs {
+		runner, initErr := newAccountRunner(accountConfig)
+		if initErr != nil {
+			return fmt.Errorf("계정 %s 초기화 실패 : %s", accountConfig.Name, initErr.Error())
+		}
+		runners = append(runners, runner)
+	}
+
+	// 계정별로 따로 10초 단위로 수행
+	var waitGroup sync.WaitGroup
+	for _, runner := range runners {
+		waitGroup.Add(1)
+		go func(runner *accountRunner) {
+			defer waitGroup.Done()
+			runner.run()
+		}(runner)
+	}
+	waitGroup.Wait()
+
+	return
+}
+
+func newAccountRunner(accountConfig *model.Config) (runner *accountRunner, err error) {
+	logger := newLogger(accountConfig.LogFile)
+	logger.Printf("Start raindrop : %s\n", accountConfig.Name)
+
+	// 봇이 낸 주문 기록
+	store, err := loadStore(accountConfig)
+	if err != nil {
+		logger.Printf("상태 파일 읽기 실패 : %s\n", err.Error())
+		return
+	}
+
+	ex, err := newExchange(accountConfig, store)
+	if err != nil {
+		logger.Printf("거래소 초기화 실패 : %s\n", err.Error())
+		return
+	}
+
+	if accountConfig.DryRun == 1 {
+		logger.Printf("Dry-run 모드 : 주문은 %s 에 기록됨\n", accountConfig.DecisionLog)
+	}
+
+	candleCache := exchange.NewCandleCache(ex)
+
+	shadows, err := newShadowRunners(accountConfig, candleCache, logger)
+	if err != nil {
+		logger.Printf("shadow 전략 초기화 실패 : %s\n", err.Error())
+		return
+	}
+
+	runner = &accountRunner{
+		config:     accountConfig,
+		logger:     logger,
+		strategies: newLiveStrategies(accountConfig, logger, store, candleCache),
+		store:      store,
+		// 주문 없이 인증 API 로 HealthCheck
+		healthChecker: health.NewChecker(accountConfig, logger),
+		// 거래소 잔고와 봇 수량 대사
+		reconciler:  reconcile.NewReconciler(accountConfig, logger, store, ex),
+		candleCache: candleCache,
+		shadows:     shadows}
+
+	return
+}
+
+/*
+ * 계정 설정의 전략 목록으로 실매매 전략을 만든다.
+ */
+func newLiveStrategies(accountConfig *model.Config,
+	logger *log.Logger,
+	store *state.Store,
+	ex exchange.Exchange) (strategies []*liveStrategy) {
+
+	for _, name := range accountConfig.StrategyNames() {
+		// 설정 검증에서 실매매 전략은 lw_basic 만 허용한다. (lw_advance 는 shadow 전용)
+		strategyRunner := new(lw_basic.LarryRunner)
+		strategyRunner.Init(accountConfig, logger, store, ex)
+
+		strategy := &liveStrategy{name: name, run: strategyRunner.RunLWBasicStrategy}
+
+		strategies = append(strategies, strategy)
+	}
+
+	logger.Printf("실매매 전략 : %v\n", accountConfig.StrategyNames())
+
+	return
+}
+
+func (runner *accountRunner) run() {
+	for {
+		runner.runStrategy()
+
+		// shadow 전략은 실매매 전략이 조회한 일봉을 그대로 사용한다.
+		for _, shadow := range runner.shadows {
+			shadow.runStrategy()
+		}
+
+		time.Sleep(tickInterval)
+	}
+}
+
+/*
+ * 한 계정에서 패닉이 나도 다른 계정은 계속 수행되도록 복구한다.
+ */
+func (runner *accountRunner) runStrategy() {
+	defer func() {
+		if r := recover(); r != nil {
+			runner.logger.Printf("전략 수행 중 패닉 : %v\n%s\n", r, debug.Stack())
+		}
+	}()
+
+	runner.candleCache.Reset()
+
+	// 다른 명령(flatten, cancel-all 등)이 상태 파일에 남긴 주문을 반영한다.
+	if err := runner.store.Refresh(); err != nil {
+		runner.logger.Printf("상태 파일 다시 읽기 실패 : %s\n", err.Error())
+	}
+
+	runner.healthChecker.Check()
+	runner.reconciler.Check()
+
+	for _, strategy := range runner.strategies {
+		runner.runLiveStrategy(strategy)
+	}
+}
+
+/*
+ * 전략 하나에서 패닉이 나도 같은 계정의 다른 전략은 계속 수행되도록 복구한다.
+ */
+func (runner *accountRunner) runLiveStrategy(strategy *liveStrategy) {
+	defer func() {
+		if r := recover(); r != nil {
+			runner.logger.Printf("%s 전략 수행 중 패닉 : %v\n%s\n", strategy.name, r, debug.Stack())
+		}
+	}()
+
+	strategy.run()
+}
+
+/*
+ * 설정에 맞는 거래소를 만든다.
+ * dry-run 이면 주문 요청을 decision log 로 돌린다.
+ */
+func newExchange(accountConfig *model.Config, store *state.Store) (ex exchange.Exchange, err error) {
+	switch accountConfig.Exchange {
+	case "", model.ExchangeUpbit:
+		ex = exchange.NewUpbit(accountConfig.Account.Accesskey, accountConfig.Account.SecretKey)
+	case model.ExchangeBithumb:
+		// Bithumb 은 전체 미체결 주문 조회가 없어 타겟 마켓별로 조회한다.
+		bithumbExchange := bithumb.New(accountConfig.Account.Accesskey, accountConfig.Account.SecretKey, accountConfig.LarryStrategy.Targets)
+		// 재시작 전에 낸 주문의 마켓, 매수/매도 구분은 상태 파일에서 찾는다.
+		if store != nil {
+			bithumbExchange.SetOrderSource(store)
+		}
+		ex = bithumbExchange
+	default:
+		return nil, fmt.Errorf("지원하지 않는 거래소 : %s", accountConfig.Exchange)
+	}
+
+	if accountConfig.DryRun == 1 {
+		ex, err = exchange.NewDryRun(ex, accountConfig.DecisionLog)
+	}
+
+	return
+}
+
+/*
+ * 상태 파일을 읽는다. dry-run 이면 가상 주문이 기록되지 않도록 저장하지 않는다.
+ */
+func loadStore(accountConfig *model.Config) (store *state.Store, err error) {
+	store, err = state.Load(accountConfig.StateFile)
+
+	if accountConfig.DryRun == 1 {
+		store.SetReadOnly()
+	}
+
+	return
+}
+
+func newLogger(logPath string) *log.Logger {
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		log.Println(err)
+	}
+
+	f, err := os.OpenFile(logPath,
+		os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		log.Println(err)
+	}
+
+	logger := log.New(f, "RainDrop : ", log.LstdFlags)
+	logger.SetOutput(&lumberjack.Logger{
+		Filename:   logPath,
+		MaxSize:    20, // megabytes after which new file is created
+		MaxBackups: 5,  // number of backups
+		MaxAge:     31, //days
+	})
+
+	return logger
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"raindrop/main/exchange"
+	"raindrop/main/model"
+	"raindrop/main/quote"
+	"raindrop/main/state"
+	"raindrop/main/strategy/lw_advance"
+	"raindrop/main/strategy/lw_basic"
+	"runtime/debug"
+)
+
+/*
+ * shadow 전략 실행 단위
+ * 실매매 전략과 같은 주기에 같은 시세(CandleCache)를 보고 가상 지갑(exchange.Paper)에서 거래한다.
+ * 주문 기록은 shadow 상태 파일에 남아 report 에서 실매매 전략과 나란히 비교한다.
+ */
+type shadowRunner struct {
+	name   string
+	logger *log.Logger
+	paper  *exchange.Paper
+	store  *state.Store
+	run    func()
+}
+
+func newShadowRunners(accountConfig *model.Config, market exchange.Exchange, logger *log.Logger) (runners []*shadowRunner, err error) {
+	names := make(map[string]bool)
+
+	for _, shadow := range accountConfig.Shadows {
+		shadowConfig, configErr := accountConfig.ShadowConfig(shadow)
+		if configErr != nil {
+			return nil, configErr
+		}
+
+		if names[shadow.Name] {
+			return nil, fmt.Errorf("중복된 shadow 이름 : %s", shadow.Name)
+		}
+		names[shadow.Name] = true
+
+		store, loadErr := state.Load(shadowConfig.StateFile)
+		if loadErr != nil {
+			return nil, loadErr
+		}
+
+		paper, paperErr := exchange.NewPaper(market, shadow.WalletFile, shadow.InitialCash, shadowConfig.Fee)
+		if paperErr != nil {
+			return nil, paperErr
+		}
+
+		runner := &shadowRunner{
+			name:   shadow.Name,
+			logger: newLogger(shadowConfig.LogFile),
+			paper:  paper,
+			store:  store}
+		runner.logger.Printf("Start shadow : %s (%s), 초기 자금 %.0f\n", shadowConfig.Name, shadow.Strategy, paper.InitialCash())
+
+		switch shadow.Strategy {
+		case model.ShadowStrategyAdvanced:
+			strategyRunner := new(lw_advance.LarryRunner)
+			strategyRunner.Init(shadowConfig, runner.logger, store, paper)
+			runner.run = strategyRunner.RunLWAdvancedStrategy
+		default:
+			strategyRunner := new(lw_basic.LarryRunner)
+			strategyRunner.Init(shadowConfig, runner.logger, store, paper)
+			runner.run = strategyRunner.RunLWBasicStrategy
+		}
+
+		logger.Printf("Shadow 전략 : %s (%s), 로그 %s\n", shadow.Name, shadow.Strategy, shadowConfig.LogFile)
+
+		runners = append(runners, runner)
+	}
+
+	return
+}
+
+/*
+ * shadow 전략 한 주기 수행, 패닉이 나도 실매매와 다른 shadow 는 계속 수행된다.
+ */
+func (runner *shadowRunner) runStrategy() {
+	defer func() {
+		if r := recover(); r != nil {
+			runner.logger.Printf("shadow 전략 수행 중 패닉 : %v\n%s\n", r, debug.Stack())
+		}
+	}()
+
+	runner.run()
+
+	// 상태 파일에 최종 상태가 기록된 주문은 가상 지갑에서 지운다.
+	if _, err := runner.paper.PruneOrders(runner.store.IsSynced); err != nil {
+		runner.logger.Printf("가상 지갑 주문 정리 실패 : %s\n", err.Error())
+	}
+
+	rates := quote.Rates(runner.paper, runner.paper.QuoteCurrencies(), nil)
+	equity, missing, err := runner.paper.Equity(rates)
+	if err != nil {
+		runner.logger.Printf("가상 지갑 평가 실패 : %s\n", err.Error())
+		return
+	}
+	if len(missing) > 0 {
+		runner.logger.Printf("[가상 지갑] 원화 환산 비율이 없어 평가 금액에서 뺌 : %v\n", missing)
+	}
+
+	initialCash := runner.paper.InitialCash()
+	runner.logger.Printf("[가상 지갑] 평가 금액 %.0f, 수익률 %.2f%%\n", equity, (equity/initialCash-1)*100)
+}